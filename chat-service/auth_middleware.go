@@ -1,76 +1,182 @@
 package main
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+)
+
+// Identity headers set by the API gateway. They are only honored together with a valid signature.
+const (
+	headerUserID            = "X-User-ID"
+	headerPlanID            = "X-Plan-ID"
+	headerUserRole          = "X-User-Role"
+	headerIdentityTimestamp = "X-Identity-Timestamp"
+	headerIdentitySignature = "X-Identity-Signature"
 )
 
-func AuthMiddleware(jwtHandler JWT) gin.HandlerFunc {
+// AuthMiddleware resolves the caller's domain.Principal from a verified access token or, when a
+// gateway secret is configured, from identity headers signed by the gateway. Requests carrying
+// neither are visitors; requests carrying an invalid token or signature are rejected.
+func AuthMiddleware(jwtHandler JWT, gatewaySecret string, maxClockSkew time.Duration) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.Set("userID", "")
-			c.Set("plan_id", "free")
-			c.Set("userRole", "user")
-			c.Next()
-			return
+		principal := domain.VisitorPrincipal()
+
+		if c.GetHeader(headerIdentitySignature) != "" {
+			if gatewaySecret == "" {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "signed identity headers are not accepted"})
+				return
+			}
+			p, err := verifyGatewayIdentity(c.Request.Header, gatewaySecret, maxClockSkew, time.Now())
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+				return
+			}
+			principal = p
+		} else if authHeader := bearerHeader(c); authHeader != "" {
+			token, ok := strings.CutPrefix(authHeader, "Bearer ")
+			if !ok || token == "" {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid authorization header"})
+				return
+			}
+			claims, err := jwtHandler.ValidateAccessToken(token)
+			if err != nil || claims.UserID == "" {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired access token"})
+				return
+			}
+			principal = newPrincipal(claims.UserID, claims.Plan, claims.Role)
+			principal.Age = claims.Age
+			principal.Gender = claims.Gender
 		}
 
-		token := strings.TrimPrefix(authHeader, "Bearer ")
+		c.Set(domain.PrincipalContextKey, principal)
+		c.Next()
+	}
+}
 
-		claims, err := jwtHandler.ValidateAccessToken(token)
-		if err != nil {
-			c.Set("userID", "")
-			c.Set("plan_id", "free")
-			c.Set("userRole", "user")
-			c.Next()
-			return
+// bearerHeader returns the Authorization header. Browsers cannot set headers on WebSocket handshakes,
+// so for those the access token may come in the access_token query parameter instead.
+func bearerHeader(c *gin.Context) string {
+	if header := c.GetHeader("Authorization"); header != "" {
+		return header
+	}
+	if token := c.Query("access_token"); token != "" && strings.EqualFold(c.GetHeader("Upgrade"), "websocket") {
+		return "Bearer " + token
+	}
+	return ""
+}
+
+// requestLogger is gin's default request log with the access_token query parameter redacted, so tokens
+// passed on WebSocket URLs never reach the logs.
+func requestLogger() gin.HandlerFunc {
+	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
+		var statusColor, methodColor, resetColor string
+		if param.IsOutputColor() {
+			statusColor = param.StatusCodeColor()
+			methodColor = param.MethodColor()
+			resetColor = param.ResetColor()
 		}
-		if claims == nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token claims"})
-			return
+		if param.Latency > time.Minute {
+			param.Latency = param.Latency.Truncate(time.Second)
 		}
+		return fmt.Sprintf("[GIN] %v |%s %3d %s| %13v | %15s |%s %-7s %s %#v\n%s",
+			param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+			statusColor, param.StatusCode, resetColor,
+			param.Latency,
+			param.ClientIP,
+			methodColor, param.Method, resetColor,
+			redactAccessToken(param.Path),
+			param.ErrorMessage,
+		)
+	})
+}
 
-		// Set claims in context
-		c.Set("userID", claims.UserID)
-		c.Set("role", claims.Role)
-		c.Set("plan_id", claims.Plan)
-		c.Set("age", claims.Age)
-		c.Set("gender", claims.Gender)
+// redactAccessToken masks the access_token query parameter of a logged path. A query that cannot be
+// parsed is dropped.
+func redactAccessToken(path string) string {
+	i := strings.IndexByte(path, '?')
+	if i < 0 || !strings.Contains(path[i:], "access_token") {
+		return path
+	}
+	query, err := url.ParseQuery(path[i+1:])
+	if err != nil {
+		return path[:i]
+	}
+	if query.Has("access_token") {
+		query.Set("access_token", "REDACTED")
+	}
+	return path[:i+1] + query.Encode()
+}
 
-		c.Next()
+// newPrincipal fills in the defaults for a signed-in user: the free plan and the user role.
+func newPrincipal(userID, planID, role string) domain.Principal {
+	if planID == "" {
+		planID = string(domain.TierFree)
+	}
+	if role == "" {
+		role = domain.RoleUser
 	}
+	return domain.Principal{UserID: userID, PlanID: planID, Role: role}
 }
 
-func RoleMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		role, exists := c.Get("role")
-		if !exists {
-			c.AbortWithStatusJSON(403, gin.H{"error": "role not found"})
-			return
-		}
+// signGatewayIdentity returns the hex HMAC-SHA256 the gateway sends in X-Identity-Signature.
+func signGatewayIdentity(secret, userID, planID, role, timestamp string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strings.Join([]string{userID, planID, role, timestamp}, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
 
-		if role != "admin" {
-			c.AbortWithStatusJSON(403, gin.H{"error": "forbidden: insufficient permissions"})
-			return
-		}
+// verifyGatewayIdentity checks the signature and freshness of the gateway identity headers.
+func verifyGatewayIdentity(header http.Header, secret string, maxClockSkew time.Duration, now time.Time) (domain.Principal, error) {
+	userID := header.Get(headerUserID)
+	planID := header.Get(headerPlanID)
+	role := header.Get(headerUserRole)
+	timestamp := header.Get(headerIdentityTimestamp)
 
-		c.Next()
+	signature, err := hex.DecodeString(header.Get(headerIdentitySignature))
+	if err != nil {
+		return domain.Principal{}, errors.New("malformed identity signature")
+	}
+	expected, _ := hex.DecodeString(signGatewayIdentity(secret, userID, planID, role, timestamp))
+	if !hmac.Equal(signature, expected) {
+		return domain.Principal{}, errors.New("invalid identity signature")
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return domain.Principal{}, errors.New("malformed identity timestamp")
+	}
+	if skew := now.Sub(time.Unix(unix, 0)); skew > maxClockSkew || skew < -maxClockSkew {
+		return domain.Principal{}, errors.New("identity headers have expired")
+	}
+
+	if userID == "" {
+		return domain.VisitorPrincipal(), nil
 	}
+	return newPrincipal(userID, planID, role), nil
 }
 
-func ProPlanMiddleware() gin.HandlerFunc {
+func RoleMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		plan, exists := c.Get("plan_id")
-		if !exists {
-			c.AbortWithStatusJSON(403, gin.H{"error": "plan not found"})
+		principal := domain.PrincipalFrom(c)
+		if principal.IsVisitor() {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authentication required"})
 			return
 		}
 
-		if plan != "pro" {
-			c.AbortWithStatusJSON(403, gin.H{"error": "forbidden: insufficient permissions"})
+		if !principal.IsAdmin() {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden: insufficient permissions"})
 			return
 		}
 
@@ -78,16 +184,18 @@ func ProPlanMiddleware() gin.HandlerFunc {
 	}
 }
 
+func ProPlanMiddleware() gin.HandlerFunc {
+	return planMiddleware(domain.TierPro)
+}
+
 func EnterprisePlanMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		plan, exists := c.Get("plan_id")
-		if !exists {
-			c.AbortWithStatusJSON(403, gin.H{"error": "plan not found"})
-			return
-		}
+	return planMiddleware(domain.TierEnterprise)
+}
 
-		if plan != "enterprise" {
-			c.AbortWithStatusJSON(403, gin.H{"error": "forbidden: insufficient permissions"})
+func planMiddleware(tier domain.SubscriptionTier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if domain.PrincipalFrom(c).PlanID != string(tier) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden: insufficient permissions"})
 			return
 		}
 
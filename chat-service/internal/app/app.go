@@ -1,7 +1,6 @@
 package app
 
 import (
-	"github.com/LAWGEN/lawgen-backend/chat-service/internal/config"
 	"github.com/gin-gonic/gin"
 )
 
@@ -10,10 +9,12 @@ func RegisterQuizRoutes(router *gin.Engine, quizController *QuizController, auth
 	public := router.Group("/api/v1/quizzes")
 	{
 		public.GET("/categories", quizController.GetCategories)
+		public.GET("/attempts", quizController.GetAttempts)
+		public.GET("/progress", quizController.GetProgress)
 		public.GET("/categories/:categoryId", quizController.GetQuizzesByCategory)
 		public.GET("/:quizId", quizController.GetQuiz)
 		public.GET("/:quizId/questions", quizController.GetQuestionsByQuiz)
-		// public.POST("/:quizId/submit", quizController.SubmitQuiz)
+		public.POST("/:quizId/submit", quizController.SubmitQuiz)
 	}
 
 	// Admin routes
@@ -27,7 +28,12 @@ func RegisterQuizRoutes(router *gin.Engine, quizController *QuizController, auth
 
 		// Quiz management
 		admin.POST("/", quizController.CreateQuiz)
+		admin.POST("/generate", quizController.GenerateQuiz)
+		admin.GET("/drafts", quizController.GetDraftQuizzes)
+		admin.POST("/:quizId/publish", quizController.PublishQuiz)
+		admin.GET("/:quizId", quizController.AdminGetQuiz)
 		admin.PUT("/:quizId", quizController.UpdateQuiz)
+		admin.PUT("/:quizId/settings", quizController.UpdateQuizSettings)
 		admin.DELETE("/:quizId", quizController.DeleteQuiz)
 
 		// Question management
@@ -37,12 +43,68 @@ func RegisterQuizRoutes(router *gin.Engine, quizController *QuizController, auth
 	}
 }
 
-func RegisterChatRoutes(router *gin.Engine, chatController *ChatController, cfg *config.Config) {
+func RegisterChatRoutes(router *gin.Engine, chatController *ChatController, voiceController *VoiceChatController, authMiddleware gin.HandlerFunc) {
 	public := router.Group("/api/v1/chats")
 	{
 		public.POST("/query", chatController.postQuery)
+		public.GET("/stream/:messageId", chatController.resumeStream)
+		public.GET("/ws", chatController.chatSocket)
+		public.GET("/usage", chatController.getUsage)
 		public.GET("/sessions", chatController.listSessions)
+		public.PATCH("/sessions/:sessionId", chatController.updateSession)
+		public.DELETE("/sessions/:sessionId", chatController.deleteSession)
 		public.GET("/sessions/:sessionId/messages", chatController.getMessages)
-		public.POST("/voice-query", VoiceChatHandlerWithConfig(chatController.chatService, cfg))
+		public.POST("/sessions/:sessionId/messages/:messageId/feedback", chatController.rateAnswer)
+		public.GET("/sessions/:sessionId/export", chatController.exportSession)
+		public.POST("/sessions/:sessionId/export-link", chatController.createExportLink)
+		public.GET("/exports/download", chatController.downloadExport)
+		public.POST("/sessions/:sessionId/shares", chatController.createShareLink)
+		public.GET("/shares", chatController.listShareLinks)
+		public.DELETE("/shares/:shareId", chatController.revokeShareLink)
+		public.GET("/shared/:token", chatController.getSharedSession)
+		public.GET("/search", chatController.searchHistory)
+		public.POST("/voice-query", voiceController.voiceQuery)
+		public.GET("/voice/ws", voiceController.voiceSocket)
+	}
+
+	// Admin routes
+	admin := router.Group("/api/v1/admin/chats")
+	admin.Use(authMiddleware)
+	{
+		// Response cache management, e.g. after a law document is re-ingested
+		admin.DELETE("/cache", chatController.invalidateCache)
+		admin.GET("/feedback/report", chatController.feedbackReport)
+	}
+}
+
+func RegisterPromptRoutes(router *gin.Engine, promptController *PromptController, authMiddleware gin.HandlerFunc) {
+	admin := router.Group("/api/v1/admin/prompts")
+	admin.Use(authMiddleware)
+	{
+		admin.GET("/", promptController.listPrompts)
+		admin.GET("/:name", promptController.getPrompt)
+		admin.POST("/:name/versions", promptController.createVersion)
+		admin.POST("/:name/versions/:version/publish", promptController.publish)
+		admin.POST("/:name/split", promptController.splitTraffic)
+		admin.POST("/:name/rollback", promptController.rollBack)
+		admin.GET("/:name/feedback", promptController.feedback)
+	}
+}
+
+func RegisterGlossaryRoutes(router *gin.Engine, glossaryController *GlossaryController, authMiddleware gin.HandlerFunc) {
+	// Public routes, for the frontend's term tooltips
+	public := router.Group("/api/v1/glossary")
+	{
+		public.GET("/", glossaryController.search)
+		public.POST("/lookup", glossaryController.lookup)
+	}
+
+	admin := router.Group("/api/v1/admin/glossary")
+	admin.Use(authMiddleware)
+	{
+		admin.GET("/", glossaryController.listTerms)
+		admin.POST("/", glossaryController.createTerm)
+		admin.PUT("/:termId", glossaryController.updateTerm)
+		admin.DELETE("/:termId", glossaryController.deleteTerm)
 	}
 }
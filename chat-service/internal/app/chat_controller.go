@@ -1,25 +1,42 @@
 package app
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/config"
 	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/export"
 	"github.com/LAWGEN/lawgen-backend/chat-service/internal/usecase"
 	"github.com/LAWGEN/lawgen-backend/chat-service/internal/util"
 )
 
+const (
+	// resumeBlock is how long one read of a resumed stream waits for new events. It is kept short so that a
+	// client that went away frees its Redis connection quickly.
+	resumeBlock = time.Second
+	// resumeWait is how long a resumed stream waits for new events before checking that the stream still exists.
+	resumeWait = 10 * time.Second
+)
+
 type ChatController struct {
+	cfg         *config.Config
 	chatService *usecase.ChatService
+	exporter    *export.Exporter
 }
 
-func NewChatController(cs *usecase.ChatService) *ChatController {
-	return &ChatController{chatService: cs}
+func NewChatController(cfg *config.Config, cs *usecase.ChatService, exporter *export.Exporter) *ChatController {
+	return &ChatController{cfg: cfg, chatService: cs, exporter: exporter}
 }
 
 type QueryRequest struct {
@@ -35,21 +52,8 @@ func (c *ChatController) postQuery(ctx *gin.Context) {
 		ChatLatencyHistogram.WithLabelValues("/chat/query").Observe(latency)
 	}()
 
-	// 1. Getting Inputs (request, userID, planID)
-	userID, _ := ctx.Get("userID") // Will be empty string for guests if not set by middleware
-	planID, _ := ctx.Get("planID") // Will be empty string or "visitor" for guests
-
-	userIDStr := ""
-	if userID != nil {
-		userIDStr = userID.(string)
-	}
-	planIDStr := ""
-	if planID != nil {
-		planIDStr = planID.(string)
-	}
-	if planIDStr == "" { // Default to visitor if no plan is provided by middleware
-		planIDStr = string(domain.TierGuest)
-	}
+	// 1. Getting Inputs (request and the authenticated principal)
+	principal := domain.PrincipalFrom(ctx)
 
 	var reqBody QueryRequest
 	if err := ctx.ShouldBindJSON(&reqBody); err != nil {
@@ -75,79 +79,143 @@ func (c *ChatController) postQuery(ctx *gin.Context) {
 	// Create service request
 	svcReq := usecase.QueryRequest{
 		SessionID: reqBody.SessionID,
-		UserID:    userIDStr,
-		PlanID:    planIDStr,
+		UserID:    principal.UserID,
+		PlanID:    principal.PlanID,
+		ClientIP:  ctx.ClientIP(),
 		Message:   reqBody.Query,
 		Language:  reqBody.Language,
 	}
 
-	// Call the service to process the query and get a stream
-	responseStream, err := c.chatService.ProcessQuery(requestContext, svcReq)
+	// Run the query; the stream outlives this request so the client can resume it after a disconnect
+	events, err := c.startQueryStream(requestContext, svcReq)
 	if err != nil {
 		util.SendSSEError(w, "Service processing error: "+err.Error())
 		flusher.Flush()
 		return
 	}
 
-	// Stream the response chunks back to the client
-	for chunk := range responseStream {
-		select {
-		case <-requestContext.Done(): // Check if request context cancelled
-			log.Printf("Request context cancelled during SSE stream for session %s: %v", reqBody.SessionID, requestContext.Err())
+	// Stream the numbered events back to the client
+	for event := range events {
+		if event.Event == "session_id" {
+			// Set cookie for guests, or just send the ID.
+			userParams := domain.GetUserParamsFromPlanID(principal.PlanID)
+			if !userParams.SaveHistory { // It's a guest
+				var session struct {
+					ID string `json:"id"`
+				}
+				if err := json.Unmarshal(event.Data, &session); err == nil {
+					setSessionIDCookie(w, session.ID)
+				}
+			}
+		}
+		util.SendSSEEventWithID(w, event.ID, event.Event, event.Data)
+		flusher.Flush()
+		if event.Terminal() {
+			return
+		}
+	}
+	if requestContext.Err() != nil {
+		log.Printf("Client disconnected from query stream for session %s; it can resume with Last-Event-ID", reqBody.SessionID)
+	}
+}
+
+// resumeStream replays the events of a query stream after the Last-Event-ID header, then follows the
+// stream live until it ends. Visitors pass the stream's session in ?sessionId= or the session cookie.
+func (c *ChatController) resumeStream(ctx *gin.Context) {
+	principal := domain.PrincipalFrom(ctx)
+	requestContext := ctx.Request.Context()
+	sessionID := ctx.Query("sessionId")
+	if sessionID == "" {
+		sessionID, _ = ctx.Cookie("session_id")
+	}
+	var lastID int64
+	if header := ctx.GetHeader("Last-Event-ID"); header != "" {
+		var err error
+		if lastID, err = strconv.ParseInt(header, 10, 64); err != nil || lastID < 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"error": "Last-Event-ID must be an event ID from this stream"})
+			return
+		}
+	}
+	info, err := c.chatService.GetResumableStream(requestContext, principal, ctx.Param("messageId"), sessionID)
+	if errors.Is(err, domain.ErrStreamNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Stream not found or expired"})
+		return
+	}
+	if err != nil {
+		respondSessionError(ctx, err, "Failed to resume stream")
+		return
+	}
+
+	w := ctx.Writer
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		util.SendSSEError(w, "Streaming unsupported")
+		return
+	}
+	flusher.Flush()
+
+	var idle time.Duration
+	for {
+		if requestContext.Err() != nil {
+			return // the client went away
+		}
+		events, err := c.chatService.ReadStreamEvents(requestContext, info, lastID, resumeBlock)
+		if err != nil {
+			if requestContext.Err() == nil {
+				log.Printf("Failed to read stream %s: %v", info.MessageID, err)
+				util.SendSSEError(w, "Failed to resume the answer")
+			}
 			return
-		default:
-			if chunk.Error != nil {
-				util.SendSSEError(w, chunk.Error.Error())
+		}
+		for _, event := range events {
+			util.SendSSEEventWithID(w, event.ID, event.Event, event.Data)
+			lastID = event.ID
+			if event.Terminal() {
 				flusher.Flush()
 				return
 			}
-
-			if chunk.SessionID != "" {
-				// This is the first chunk, containing the new session ID for client to use
-				// Set cookie for guests, or just send the ID.
-				userParams := domain.GetUserParamsFromPlanID(planIDStr)
-				if !userParams.SaveHistory { // It's a guest
-					setSessionIDCookie(w, chunk.SessionID)
-				}
-				util.SendSSEEvent(w, "session_id", map[string]string{"id": chunk.SessionID})
-				flusher.Flush()
-			} else if chunk.IsComplete {
-				// Send the final chunk with suggested questions and completion only (no sources)
-				data := map[string]interface{}{
-					"is_complete": true,
-				}
-				if len(chunk.SuggestedQuestions) > 0 {
-					data["suggested_questions"] = chunk.SuggestedQuestions
-				}
-				if chunk.Text != "" {
-					data["text"] = chunk.Text
-				}
-				util.SendSSEEvent(w, "complete", data)
-				flusher.Flush()
+		}
+		flusher.Flush()
+		if len(events) > 0 {
+			idle = 0
+			continue
+		}
+		if idle += resumeBlock; idle >= resumeWait {
+			// Nothing new for a while; stop once the stream has expired
+			idle = 0
+			if _, err := c.chatService.GetResumableStream(requestContext, principal, info.MessageID, sessionID); err != nil {
+				util.SendSSEError(w, "The answer is no longer available. Please ask again.")
 				return
-			} else if chunk.Text != "" {
-				// Send text chunks, include sources if present
-				msg := map[string]interface{}{"text": chunk.Text}
-				if len(chunk.Sources) > 0 {
-					msg["sources"] = chunk.Sources
-				}
-				util.SendSSEEvent(w, "message", msg)
-				flusher.Flush()
 			}
 		}
 	}
 }
 
+// getUsage reports the caller's usage and remaining quota for their plan.
+func (c *ChatController) getUsage(ctx *gin.Context) {
+	principal := domain.PrincipalFrom(ctx)
+	usage, err := c.chatService.GetUsage(ctx.Request.Context(), principal.UserID, ctx.ClientIP(), principal.PlanID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get usage"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"plan_id": principal.PlanID, "usage": usage})
+}
+
 func (c *ChatController) listSessions(ctx *gin.Context) {
-	userID, exists := ctx.Get("userID")
-	if !exists || userID == "" {
+	principal := domain.PrincipalFrom(ctx)
+	if principal.IsVisitor() {
 		ctx.JSON(http.StatusForbidden, gin.H{"error": "Query history is not available for visitors"})
 		return
 	}
 
 	page, _ := strconv.Atoi(ctx.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
-	sessions, total, err := c.chatService.ListSessions(ctx, userID.(string), page, limit) // Use chatService
+	archived := ctx.Query("archived") == "true"
+	sessions, total, err := c.chatService.ListSessions(ctx, principal.UserID, archived, page, limit) // Use chatService
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list sessions"})
 		return
@@ -156,8 +224,8 @@ func (c *ChatController) listSessions(ctx *gin.Context) {
 }
 
 func (c *ChatController) getMessages(ctx *gin.Context) {
-	userID, exists := ctx.Get("userID")
-	if !exists || userID == "" {
+	principal := domain.PrincipalFrom(ctx)
+	if principal.IsVisitor() {
 		ctx.JSON(http.StatusForbidden, gin.H{"error": "Query history is not available for visitors"})
 		return
 	}
@@ -169,7 +237,7 @@ func (c *ChatController) getMessages(ctx *gin.Context) {
 		return
 	}
 	// Ensure the user owns this session
-	if session.UserID != userID.(string) {
+	if session.UserID != principal.UserID {
 		ctx.JSON(http.StatusForbidden, gin.H{"error": "You do not have access to this session"})
 		return
 	}
@@ -183,6 +251,369 @@ func (c *ChatController) getMessages(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, messages)
 }
 
+type UpdateSessionRequest struct {
+	Title    *string `json:"title"`
+	Pinned   *bool   `json:"pinned"`
+	Archived *bool   `json:"archived"`
+}
+
+// updateSession renames, pins/unpins or archives/unarchives a session; omitted fields are unchanged.
+func (c *ChatController) updateSession(ctx *gin.Context) {
+	principal := domain.PrincipalFrom(ctx)
+	if principal.IsVisitor() {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Query history is not available for visitors"})
+		return
+	}
+
+	var req UpdateSessionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	session, err := c.chatService.UpdateSession(ctx.Request.Context(), principal.UserID, ctx.Param("sessionId"), domain.SessionUpdate{
+		Title:    req.Title,
+		Pinned:   req.Pinned,
+		Archived: req.Archived,
+	})
+	if err != nil {
+		respondSessionError(ctx, err, "Failed to update session")
+		return
+	}
+	ctx.JSON(http.StatusOK, session)
+}
+
+func (c *ChatController) deleteSession(ctx *gin.Context) {
+	principal := domain.PrincipalFrom(ctx)
+	if principal.IsVisitor() {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Query history is not available for visitors"})
+		return
+	}
+
+	if err := c.chatService.DeleteSession(ctx.Request.Context(), principal.UserID, ctx.Param("sessionId")); err != nil {
+		respondSessionError(ctx, err, "Failed to delete session")
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// searchHistory finds the caller's past messages matching ?q=.
+func (c *ChatController) searchHistory(ctx *gin.Context) {
+	principal := domain.PrincipalFrom(ctx)
+	if principal.IsVisitor() {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Query history is not available for visitors"})
+		return
+	}
+
+	query := ctx.Query("q")
+	if strings.TrimSpace(query) == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Missing search query"})
+		return
+	}
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	results, err := c.chatService.SearchHistory(ctx.Request.Context(), principal.UserID, query, limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search chat history"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"results": results, "query": query})
+}
+
+// exportParams reads ?format= (pdf or docx) and ?language= (en or am), defaulting to a PDF in English.
+func exportParams(ctx *gin.Context) (export.Format, string, bool) {
+	format, err := export.ParseFormat(ctx.DefaultQuery("format", string(export.FormatPDF)))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return "", "", false
+	}
+	language := ctx.DefaultQuery("language", "en")
+	if !export.SupportedLanguage(language) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported export language (use en or am)"})
+		return "", "", false
+	}
+	return format, language, true
+}
+
+// exportSession downloads one of the caller's sessions as a PDF or DOCX brief.
+func (c *ChatController) exportSession(ctx *gin.Context) {
+	principal := domain.PrincipalFrom(ctx)
+	if principal.IsVisitor() {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Query history is not available for visitors"})
+		return
+	}
+	format, language, ok := exportParams(ctx)
+	if !ok {
+		return
+	}
+
+	sessionID := ctx.Param("sessionId")
+	brief, err := c.chatService.ExportSession(ctx.Request.Context(), principal.UserID, sessionID, language)
+	if err != nil {
+		respondSessionError(ctx, err, "Failed to export session")
+		return
+	}
+	c.sendBrief(ctx, sessionID, brief, format)
+}
+
+// createExportLink returns a signed, expiring URL from which anyone holding it can download the brief.
+func (c *ChatController) createExportLink(ctx *gin.Context) {
+	principal := domain.PrincipalFrom(ctx)
+	if principal.IsVisitor() {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Query history is not available for visitors"})
+		return
+	}
+	format, language, ok := exportParams(ctx)
+	if !ok {
+		return
+	}
+
+	link, signature, err := c.chatService.CreateExportLink(ctx.Request.Context(), principal.UserID, ctx.Param("sessionId"), format, language)
+	if errors.Is(err, export.ErrLinksDisabled) {
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "Export links are not available"})
+		return
+	}
+	if err != nil {
+		respondSessionError(ctx, err, "Failed to create export link")
+		return
+	}
+	query := url.Values{}
+	query.Set("session", link.SessionID)
+	query.Set("format", string(link.Format))
+	query.Set("language", link.Language)
+	query.Set("expires", strconv.FormatInt(link.Expires.Unix(), 10))
+	query.Set("sig", signature)
+	ctx.JSON(http.StatusCreated, gin.H{
+		"url":        "/api/v1/chats/exports/download?" + query.Encode(),
+		"expires_at": link.Expires,
+	})
+}
+
+// downloadExport serves a brief from a signed link; no authentication is needed.
+func (c *ChatController) downloadExport(ctx *gin.Context) {
+	link, brief, err := c.chatService.ExportLinkedSession(ctx.Request.Context(), ctx.Query("session"), ctx.Query("format"), ctx.Query("language"), ctx.Query("expires"), ctx.Query("sig"))
+	if errors.Is(err, export.ErrInvalidLink) {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		respondSessionError(ctx, err, "Failed to export session")
+		return
+	}
+	c.sendBrief(ctx, link.SessionID, brief, link.Format)
+}
+
+func (c *ChatController) sendBrief(ctx *gin.Context, sessionID string, brief *export.Brief, format export.Format) {
+	document, err := c.exporter.Render(brief, format)
+	if errors.Is(err, export.ErrFontUnavailable) {
+		log.Printf("Failed to export session %s: %v", sessionID, err)
+		ctx.JSON(http.StatusServiceUnavailable, gin.H{"error": "Export is temporarily unavailable"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to export session %s: %v", sessionID, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export session"})
+		return
+	}
+	filename := fmt.Sprintf("lawgen-brief-%s.%s", sessionID, format)
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	ctx.Data(http.StatusOK, format.ContentType(), document)
+}
+
+type CreateShareLinkRequest struct {
+	ExpiresAt *time.Time `json:"expires_at"` // optional; omit for a link that never expires
+}
+
+// createShareLink creates a revocable read-only link to one of the caller's sessions.
+func (c *ChatController) createShareLink(ctx *gin.Context) {
+	principal := domain.PrincipalFrom(ctx)
+	if principal.IsVisitor() {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Query history is not available for visitors"})
+		return
+	}
+
+	var req CreateShareLinkRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	link, err := c.chatService.CreateShareLink(ctx.Request.Context(), principal.UserID, ctx.Param("sessionId"), req.ExpiresAt)
+	if errors.Is(err, domain.ErrInvalidShareExpiry) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		respondSessionError(ctx, err, "Failed to create share link")
+		return
+	}
+	ctx.JSON(http.StatusCreated, gin.H{"link": link, "url": sharedSessionPath(link.Token)})
+}
+
+func sharedSessionPath(token string) string {
+	return "/api/v1/chats/shared/" + token
+}
+
+// listShareLinks returns the caller's active share links.
+func (c *ChatController) listShareLinks(ctx *gin.Context) {
+	principal := domain.PrincipalFrom(ctx)
+	if principal.IsVisitor() {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Query history is not available for visitors"})
+		return
+	}
+
+	links, err := c.chatService.ListShareLinks(ctx.Request.Context(), principal.UserID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list share links"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"links": links})
+}
+
+func (c *ChatController) revokeShareLink(ctx *gin.Context) {
+	principal := domain.PrincipalFrom(ctx)
+	if principal.IsVisitor() {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Query history is not available for visitors"})
+		return
+	}
+
+	err := c.chatService.RevokeShareLink(ctx.Request.Context(), principal.UserID, ctx.Param("shareId"))
+	if errors.Is(err, domain.ErrShareLinkNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Share link not found"})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke share link"})
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// getSharedSession is the public, read-only view of a shared session; no authentication is needed.
+func (c *ChatController) getSharedSession(ctx *gin.Context) {
+	shared, err := c.chatService.GetSharedSession(ctx.Request.Context(), ctx.Param("token"))
+	if errors.Is(err, domain.ErrShareLinkNotFound) {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Share link not found or no longer active"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to get shared session: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get shared session"})
+		return
+	}
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, shared)
+}
+
+type FeedbackRequest struct {
+	Rating domain.FeedbackRating `json:"rating"`
+	Reason domain.FeedbackReason `json:"reason"` // optional, only with a thumbs down
+}
+
+// rateAnswer records a thumbs up or down on one of the answers in the caller's session.
+func (c *ChatController) rateAnswer(ctx *gin.Context) {
+	principal := domain.PrincipalFrom(ctx)
+	if principal.IsVisitor() {
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "Feedback is not available for visitors"})
+		return
+	}
+
+	var req FeedbackRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	feedback, err := c.chatService.RateAnswer(ctx.Request.Context(), principal, ctx.Param("sessionId"), ctx.Param("messageId"), req.Rating, req.Reason)
+	switch {
+	case errors.Is(err, domain.ErrInvalidFeedback):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, domain.ErrChatEntryNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err != nil:
+		respondSessionError(ctx, err, "Failed to save feedback")
+	default:
+		ctx.JSON(http.StatusOK, feedback)
+	}
+}
+
+// feedbackReport lists low-rated answers between ?from= and ?to= (RFC 3339, default the last 30 days),
+// optionally for one ?reason=.
+func (c *ChatController) feedbackReport(ctx *gin.Context) {
+	filter := domain.FeedbackReportFilter{
+		To:     time.Now(),
+		Reason: domain.FeedbackReason(ctx.Query("reason")),
+	}
+	filter.From = filter.To.AddDate(0, 0, -30)
+	for param, target := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := ctx.Query(param); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid %s time, use RFC 3339", param)})
+				return
+			}
+			*target = t
+		}
+	}
+	filter.Page, _ = strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	filter.Limit, _ = strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+
+	report, err := c.chatService.GetFeedbackReport(ctx.Request.Context(), filter)
+	if errors.Is(err, domain.ErrInvalidFeedback) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to build feedback report: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build feedback report"})
+		return
+	}
+	ctx.JSON(http.StatusOK, report)
+}
+
+// respondSessionError maps session ownership errors to 404/403 and anything else to a 400 or 500.
+func respondSessionError(ctx *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, domain.ErrSessionNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+	case errors.Is(err, domain.ErrSessionAccessDenied):
+		ctx.JSON(http.StatusForbidden, gin.H{"error": "You do not have access to this session"})
+	case errors.Is(err, domain.ErrInvalidSessionUpdate):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		log.Printf("%s: %v", fallback, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// invalidateCache drops cached answers citing the law document given in ?source=, or all cached answers if it is omitted.
+func (c *ChatController) invalidateCache(ctx *gin.Context) {
+	source := ctx.Query("source")
+	deleted, err := c.chatService.InvalidateResponseCache(ctx.Request.Context(), source)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to invalidate response cache"})
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"invalidated": deleted, "source": source})
+}
+
+// quotaErrorBody is the error payload sent to clients that exceeded a quota.
+func quotaErrorBody(err *domain.QuotaExceededError) gin.H {
+	return gin.H{
+		"message":  err.Error(),
+		"code":     domain.QuotaExceededCode,
+		"kind":     err.Kind,
+		"period":   err.Period,
+		"limit":    err.Limit,
+		"reset_at": err.ResetAt,
+	}
+}
+
+// citationMap keys citations by their inline marker so the frontend can resolve "[2]" directly.
+func citationMap(citations []domain.Citation) map[string]domain.Citation {
+	m := make(map[string]domain.Citation, len(citations))
+	for _, c := range citations {
+		m[strconv.Itoa(c.Marker)] = c
+	}
+	return m
+}
+
 // setSessionIDCookie sets a session_id cookie for guests.
 func setSessionIDCookie(w http.ResponseWriter, sessionID string) {
 	http.SetCookie(w, &http.Cookie{
@@ -0,0 +1,244 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/usecase"
+)
+
+const (
+	socketPingInterval = 30 * time.Second
+	socketWriteTimeout = 10 * time.Second
+)
+
+// socketRequest is a frame from the client.
+type socketRequest struct {
+	Type      string `json:"type"` // query, cancel, ping or pong
+	ID        string `json:"id"`   // optional; echoed on the frames answering this query
+	SessionID string `json:"sessionId"`
+	Query     string `json:"query"`
+	Language  string `json:"language"`
+}
+
+// socketFrame is a frame to the client. Type is session_id, message, sources, degraded, complete or error,
+// with the same data as the SSE event of that name, or cancelled, ping or pong.
+type socketFrame struct {
+	Type string      `json:"type"`
+	ID   string      `json:"id,omitempty"`
+	Data interface{} `json:"data,omitempty"`
+}
+
+// chatSocket answers queries over a WebSocket with the same semantics as postQuery. The principal is the
+// one resolved for the handshake request, so the socket is authenticated like any HTTP call.
+func (c *ChatController) chatSocket(ctx *gin.Context) {
+	principal := domain.PrincipalFrom(ctx)
+	clientIP := ctx.ClientIP()
+	server := websocket.Server{
+		Handshake: checkOrigin(c.cfg.AllowedOrigins),
+		Handler: func(conn *websocket.Conn) {
+			s := &socketConn{socketWriter: socketWriter{conn: conn}, chatService: c.chatService, principal: principal, clientIP: clientIP}
+			s.serve()
+		},
+	}
+	server.ServeHTTP(ctx.Writer, ctx.Request)
+}
+
+// checkOrigin lets browsers open a socket only from the origins CORS allows, as handshakes are not subject
+// to CORS. Clients that are not browsers send no Origin and may connect.
+func checkOrigin(allowed []string) func(*websocket.Config, *http.Request) error {
+	return func(_ *websocket.Config, req *http.Request) error {
+		origin := req.Header.Get("Origin")
+		if origin == "" {
+			return nil
+		}
+		for _, o := range allowed {
+			if strings.EqualFold(o, origin) {
+				return nil
+			}
+		}
+		return fmt.Errorf("origin %q is not allowed", origin)
+	}
+}
+
+// socketWriter serializes the writes to a WebSocket.
+type socketWriter struct {
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+}
+
+func (w *socketWriter) write(frame socketFrame) error {
+	w.writeMu.Lock()
+	defer w.writeMu.Unlock()
+	w.conn.SetWriteDeadline(time.Now().Add(socketWriteTimeout))
+	return websocket.JSON.Send(w.conn, frame)
+}
+
+func (w *socketWriter) writeBinary(data []byte) error {
+	w.writeMu.Lock()
+	defer w.writeMu.Unlock()
+	w.conn.SetWriteDeadline(time.Now().Add(socketWriteTimeout))
+	return websocket.Message.Send(w.conn, data)
+}
+
+// heartbeat pings the client; a client that stops answering is dropped by the read deadline of its reader.
+func (w *socketWriter) heartbeat(ctx context.Context) {
+	ticker := time.NewTicker(socketPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.write(socketFrame{Type: "ping"}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+type socketConn struct {
+	socketWriter
+	chatService *usecase.ChatService
+	principal   domain.Principal
+	clientIP    string
+	queries     sync.WaitGroup
+
+	mu        sync.Mutex
+	active    *socketQuery // the answer in flight; nil when idle
+	sessionID string       // the session of the last answer, used by follow-ups that don't name one
+}
+
+type socketQuery struct {
+	id     string
+	cancel context.CancelFunc
+}
+
+func (s *socketConn) serve() {
+	ctx, cancel := context.WithCancel(s.conn.Request().Context())
+	defer func() {
+		cancel()
+		s.conn.Close()
+		s.queries.Wait()
+	}()
+	go s.heartbeat(ctx)
+
+	for {
+		// Any frame, pongs included, shows the client is still there
+		s.conn.SetReadDeadline(time.Now().Add(2 * socketPingInterval))
+		var req socketRequest
+		if err := websocket.JSON.Receive(s.conn, &req); err != nil {
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+				s.write(socketFrame{Type: "error", Data: map[string]string{"message": "Frames must be JSON objects"}})
+				continue
+			}
+			return
+		}
+		switch req.Type {
+		case "query":
+			s.startQuery(ctx, req)
+		case "cancel":
+			s.cancelQuery()
+		case "ping":
+			s.write(socketFrame{Type: "pong", ID: req.ID})
+		case "pong":
+		default:
+			s.write(socketFrame{Type: "error", ID: req.ID, Data: map[string]string{"message": "Unknown frame type: " + req.Type}})
+		}
+	}
+}
+
+// startQuery answers one query at a time; a client that wants to ask something else cancels first.
+func (s *socketConn) startQuery(ctx context.Context, req socketRequest) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.active != nil {
+		s.write(socketFrame{Type: "error", ID: req.ID, Data: map[string]string{"message": "An answer is already in progress; cancel it before asking again"}})
+		return
+	}
+	sessionID := req.SessionID
+	if sessionID == "" {
+		sessionID = s.sessionID
+	}
+	queryCtx, cancel := context.WithCancel(ctx)
+	query := &socketQuery{id: req.ID, cancel: cancel}
+	s.active = query
+	s.queries.Add(1)
+	go func() {
+		defer s.queries.Done()
+		defer cancel()
+		defer s.release(query)
+		s.runQuery(queryCtx, query, usecase.QueryRequest{
+			SessionID: sessionID,
+			UserID:    s.principal.UserID,
+			PlanID:    s.principal.PlanID,
+			ClientIP:  s.clientIP,
+			Message:   req.Query,
+			Language:  req.Language,
+		})
+	}()
+}
+
+func (s *socketConn) runQuery(ctx context.Context, query *socketQuery, req usecase.QueryRequest) {
+	id := query.id
+	chunks, err := s.chatService.ProcessQuery(ctx, req)
+	if err != nil {
+		s.write(socketFrame{Type: "error", ID: id, Data: map[string]string{"message": "Service processing error: " + err.Error()}})
+		return
+	}
+	ended := false
+	for chunk := range chunks {
+		if chunk.SessionID != "" {
+			s.mu.Lock()
+			s.sessionID = chunk.SessionID
+			s.mu.Unlock()
+		}
+		event, data, ok := streamEvent(chunk)
+		if !ok {
+			continue
+		}
+		if err := s.write(socketFrame{Type: event, ID: id, Data: data}); err != nil {
+			log.Printf("Failed to write to chat socket: %v", err)
+			query.cancel()
+			return
+		}
+		if event == "complete" || event == "error" {
+			// The client may ask its follow-up while the session is still being titled
+			ended = true
+			s.release(query)
+		}
+	}
+	if !ended {
+		s.write(socketFrame{Type: "cancelled", ID: id})
+	}
+}
+
+func (s *socketConn) cancelQuery() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.active != nil {
+		s.active.cancel()
+	}
+}
+
+// release frees the connection for the next query once query has ended.
+func (s *socketConn) release(query *socketQuery) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.active == query {
+		s.active = nil
+	}
+}
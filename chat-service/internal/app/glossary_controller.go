@@ -0,0 +1,119 @@
+package app
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/usecase"
+)
+
+// maxLookupTextLen bounds the text the frontend may send to find the terms it contains.
+const maxLookupTextLen = 20000
+
+type GlossaryController struct {
+	glossary *usecase.GlossaryService
+}
+
+func NewGlossaryController(glossary *usecase.GlossaryService) *GlossaryController {
+	return &GlossaryController{glossary: glossary}
+}
+
+// glossaryTermRequest is a term as admins create and update it.
+type glossaryTermRequest struct {
+	Term         string            `json:"term" binding:"required"`
+	Translations map[string]string `json:"translations"`
+	Definitions  map[string]string `json:"definitions"`
+}
+
+func (r glossaryTermRequest) toDomain() domain.GlossaryTerm {
+	return domain.GlossaryTerm{Term: r.Term, Translations: r.Translations, Definitions: r.Definitions}
+}
+
+// search finds terms by name for the tooltip glossary, e.g. ?q=court&language=am.
+func (c *GlossaryController) search(ctx *gin.Context) {
+	terms := c.glossary.Search(ctx.Request.Context(), ctx.Query("language"), ctx.Query("q"))
+	ctx.JSON(http.StatusOK, gin.H{"terms": terms})
+}
+
+// lookup returns the terms that appear in a text, such as an answer, with their definitions.
+func (c *GlossaryController) lookup(ctx *gin.Context) {
+	var req struct {
+		Text     string `json:"text" binding:"required"`
+		Language string `json:"language"`
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(req.Text) > maxLookupTextLen {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Text is too long"})
+		return
+	}
+	terms := c.glossary.Lookup(ctx.Request.Context(), req.Language, req.Text)
+	ctx.JSON(http.StatusOK, gin.H{"terms": terms})
+}
+
+func (c *GlossaryController) listTerms(ctx *gin.Context) {
+	terms, err := c.glossary.ListTerms(ctx.Request.Context(), ctx.Query("q"))
+	if err != nil {
+		respondGlossaryError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"terms": terms})
+}
+
+func (c *GlossaryController) createTerm(ctx *gin.Context) {
+	var req glossaryTermRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	term, err := c.glossary.CreateTerm(ctx.Request.Context(), req.toDomain(), domain.PrincipalFrom(ctx).UserID)
+	if err != nil {
+		respondGlossaryError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, term)
+}
+
+// updateTerm replaces a term; translations and definitions left out of the request are removed.
+func (c *GlossaryController) updateTerm(ctx *gin.Context) {
+	var req glossaryTermRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	term, err := c.glossary.UpdateTerm(ctx.Request.Context(), ctx.Param("termId"), req.toDomain(), domain.PrincipalFrom(ctx).UserID)
+	if err != nil {
+		respondGlossaryError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, term)
+}
+
+func (c *GlossaryController) deleteTerm(ctx *gin.Context) {
+	if err := c.glossary.DeleteTerm(ctx.Request.Context(), ctx.Param("termId")); err != nil {
+		respondGlossaryError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// respondGlossaryError maps glossary errors to 404, 400 and 409, and anything else to a 500.
+func respondGlossaryError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, domain.ErrGlossaryTermNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Glossary term not found"})
+	case errors.Is(err, domain.ErrInvalidGlossaryTerm):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, domain.ErrDuplicateGlossaryTerm):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Glossary management error: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to manage the glossary"})
+	}
+}
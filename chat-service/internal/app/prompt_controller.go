@@ -0,0 +1,141 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/usecase"
+)
+
+type PromptController struct {
+	prompts *usecase.PromptService
+}
+
+func NewPromptController(prompts *usecase.PromptService) *PromptController {
+	return &PromptController{prompts: prompts}
+}
+
+func (c *PromptController) listPrompts(ctx *gin.Context) {
+	prompts, err := c.prompts.ListPrompts(ctx.Request.Context())
+	if err != nil {
+		respondPromptError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"prompts": prompts})
+}
+
+func (c *PromptController) getPrompt(ctx *gin.Context) {
+	prompt, err := c.prompts.GetPrompt(ctx.Request.Context(), ctx.Param("name"))
+	if err != nil {
+		respondPromptError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, prompt)
+}
+
+// createVersion stores a new version of a prompt without publishing it.
+func (c *PromptController) createVersion(ctx *gin.Context) {
+	var req struct {
+		Template string `json:"template" binding:"required"`
+		Note     string `json:"note"`
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	version, err := c.prompts.CreateVersion(ctx.Request.Context(), ctx.Param("name"), req.Template, req.Note, domain.PrincipalFrom(ctx).UserID)
+	if err != nil {
+		respondPromptError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, version)
+}
+
+// publish sends all of a prompt's traffic to one version; version 0 is the default from the environment.
+func (c *PromptController) publish(ctx *gin.Context) {
+	version, err := strconv.Atoi(ctx.Param("version"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version"})
+		return
+	}
+	rollout, err := c.prompts.Publish(ctx.Request.Context(), ctx.Param("name"), version, domain.PrincipalFrom(ctx).UserID)
+	if err != nil {
+		respondPromptError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, rollout)
+}
+
+// splitTraffic shares a prompt's traffic between versions, e.g. {"variants": [{"version": 0, "weight": 50}, {"version": 3, "weight": 50}]}.
+func (c *PromptController) splitTraffic(ctx *gin.Context) {
+	var req struct {
+		Variants []domain.PromptVariant `json:"variants" binding:"required"`
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	rollout, err := c.prompts.SplitTraffic(ctx.Request.Context(), ctx.Param("name"), req.Variants, domain.PrincipalFrom(ctx).UserID)
+	if err != nil {
+		respondPromptError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusCreated, rollout)
+}
+
+// rollBack retires the active rollout and returns the prompt as it is served afterwards.
+func (c *PromptController) rollBack(ctx *gin.Context) {
+	prompt, err := c.prompts.RollBack(ctx.Request.Context(), ctx.Param("name"), domain.PrincipalFrom(ctx).UserID)
+	if err != nil {
+		respondPromptError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, prompt)
+}
+
+// feedback compares the ratings of answers per prompt version, over the last 30 days unless ?from= and ?to= are given.
+func (c *PromptController) feedback(ctx *gin.Context) {
+	to := time.Now()
+	from := to.AddDate(0, 0, -30)
+	for param, target := range map[string]*time.Time{"from": &from, "to": &to} {
+		if value := ctx.Query(param); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid %s time, use RFC 3339", param)})
+				return
+			}
+			*target = t
+		}
+	}
+	name := ctx.Param("name")
+	stats, err := c.prompts.GetFeedbackStats(ctx.Request.Context(), name, from, to)
+	if err != nil {
+		respondPromptError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"prompt": name, "from": from, "to": to, "versions": stats})
+}
+
+// respondPromptError maps prompt errors to 404, 400 and 409, and anything else to a 500.
+func respondPromptError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, domain.ErrUnknownPrompt):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Unknown prompt"})
+	case errors.Is(err, domain.ErrPromptVersionNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, domain.ErrInvalidPrompt):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, domain.ErrNoPromptRollout):
+		ctx.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Prompt management error: %v", err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to manage prompts"})
+	}
+}
@@ -0,0 +1,182 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/usecase"
+)
+
+// streamEvent maps a chunk to the event sent to clients; ok is false for chunks with nothing to send.
+func streamEvent(chunk usecase.ChatResponseChunk) (event string, data interface{}, ok bool) {
+	switch {
+	case chunk.Error != nil:
+		var quotaErr *domain.QuotaExceededError
+		if errors.As(chunk.Error, &quotaErr) {
+			return "error", quotaErrorBody(quotaErr), true
+		}
+		return "error", map[string]string{"message": chunk.Error.Error()}, true
+	case chunk.SessionID != "":
+		// The new session's ID, for the client to send with follow-up questions
+		return "session_id", map[string]string{"id": chunk.SessionID}, true
+	case chunk.IsComplete:
+		// The final chunk with sources, the citation map and suggested questions
+		data := map[string]interface{}{
+			"is_complete": true,
+		}
+		if len(chunk.Sources) > 0 {
+			data["sources"] = chunk.Sources
+		}
+		if len(chunk.Citations) > 0 {
+			data["citations"] = citationMap(chunk.Citations)
+		}
+		if len(chunk.SuggestedQuestions) > 0 {
+			data["suggested_questions"] = chunk.SuggestedQuestions
+		}
+		if chunk.Text != "" {
+			data["text"] = chunk.Text
+		}
+		if chunk.MessageID != "" {
+			data["message_id"] = chunk.MessageID
+		}
+		if chunk.Disclaimer != "" {
+			data["disclaimer"] = chunk.Disclaimer
+		}
+		return "complete", data, true
+	case len(chunk.RetrievedSources) > 0:
+		// Sources arrive before the answer; each event holds the full list so far
+		return "sources", map[string]interface{}{"sources": chunk.RetrievedSources}, true
+	case chunk.Degraded:
+		return "degraded", map[string]string{
+			"message": "The legal research service is unavailable, so this answer is based on a limited set of cached articles.",
+		}, true
+	case chunk.Text != "":
+		msg := map[string]interface{}{"text": chunk.Text}
+		if len(chunk.Sources) > 0 {
+			msg["sources"] = chunk.Sources
+		}
+		return "message", msg, true
+	}
+	return "", nil, false
+}
+
+// startQueryStream runs a query detached from the request that started it. Its events are numbered and
+// recorded in Redis, so a client that loses its connection can resume with GET /stream/:messageId. The
+// returned channel carries the events for the starting connection and is closed when the stream ends or
+// that connection goes away; the answer then keeps generating for StreamResumeGrace, longer while a
+// resumed client is reading it.
+func (c *ChatController) startQueryStream(reqCtx context.Context, req usecase.QueryRequest) (<-chan domain.StreamEvent, error) {
+	req.MessageID = primitive.NewObjectID().Hex()
+	genCtx, cancel := context.WithCancel(context.WithoutCancel(reqCtx))
+	chunks, err := c.chatService.ProcessQuery(genCtx, req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	live := make(chan domain.StreamEvent)
+	go c.relayQuery(reqCtx, genCtx, cancel, req, chunks, live)
+	return live, nil
+}
+
+func (c *ChatController) relayQuery(reqCtx, genCtx context.Context, cancel context.CancelFunc, req usecase.QueryRequest, chunks <-chan usecase.ChatResponseChunk, live chan<- domain.StreamEvent) {
+	defer cancel()
+	info := &domain.StreamInfo{MessageID: req.MessageID, SessionID: req.SessionID, UserID: req.UserID}
+	var (
+		lastID       int64
+		unrecorded   []domain.StreamEvent // events from before the session ID is known
+		connected    = true
+		disconnected = reqCtx.Done()
+		grace        <-chan time.Time
+		announced    bool
+		ended        bool
+	)
+	defer func() {
+		if connected {
+			close(live)
+		}
+	}()
+	detach := func() {
+		connected = false
+		disconnected = nil
+		close(live)
+		grace = time.After(c.cfg.StreamResumeGrace)
+	}
+	emit := func(event string, data interface{}) {
+		payload, err := json.Marshal(data)
+		if err != nil {
+			log.Printf("Error marshaling stream event '%s': %v", event, err)
+			return
+		}
+		lastID++
+		ev := domain.StreamEvent{ID: lastID, Event: event, Data: payload}
+		ended = ended || ev.Terminal()
+		unrecorded = append(unrecorded, ev)
+		if info.SessionID != "" {
+			// Recorded even after generation was stopped, so resumed clients see how the stream ended
+			recordCtx, cancelRecord := context.WithTimeout(context.WithoutCancel(genCtx), 2*time.Second)
+			if err := c.chatService.RecordStreamEvents(recordCtx, info, unrecorded); err != nil {
+				log.Printf("Warning: Failed to record events of stream %s: %v", info.MessageID, err)
+			}
+			cancelRecord()
+			unrecorded = nil
+		}
+		if connected {
+			select {
+			case live <- ev:
+			case <-reqCtx.Done():
+				detach()
+			}
+		}
+	}
+	// The message ID goes out before anything else but a new session's ID, so guests get their session
+	// cookie before the response is flushed
+	announce := func() {
+		if !announced {
+			announced = true
+			emit("stream", map[string]string{"message_id": req.MessageID})
+		}
+	}
+
+	for {
+		select {
+		case chunk, ok := <-chunks:
+			if !ok {
+				if !ended {
+					announce()
+					emit("error", map[string]string{"message": "The answer was interrupted. Please ask again."})
+				}
+				return
+			}
+			if chunk.SessionID != "" {
+				info.SessionID = chunk.SessionID
+			}
+			event, data, ok := streamEvent(chunk)
+			if !ok {
+				continue
+			}
+			if event != "session_id" {
+				announce()
+			}
+			emit(event, data)
+			if event == "session_id" {
+				announce()
+			}
+		case <-disconnected:
+			detach()
+		case <-grace:
+			if c.chatService.HasStreamListener(genCtx, req.MessageID) {
+				grace = time.After(c.cfg.StreamResumeGrace)
+				continue
+			}
+			log.Printf("No client resumed stream %s within %s, stopping generation", req.MessageID, c.cfg.StreamResumeGrace)
+			grace = nil
+			cancel() // the usecase winds down and closes chunks
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package app
 
 import (
+	"errors"
+	"log"
 	"net/http"
 	"strconv"
 	"time"
@@ -54,6 +56,9 @@ func (c *QuizController) GetQuizzesByCategory(ctx *gin.Context) {
 		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	for i, quiz := range paginatedQuizzes.Items {
+		paginatedQuizzes.Items[i] = quiz.WithoutAnswers()
+	}
 	ctx.JSON(http.StatusOK, paginatedQuizzes)
 }
 
@@ -66,11 +71,11 @@ func (c *QuizController) GetQuiz(ctx *gin.Context) {
 
 	quizID := ctx.Param("quizId")
 	quiz, err := c.quizUseCase.GetQuiz(ctx.Request.Context(), quizID)
-	if err != nil {
+	if err != nil || quiz.IsDraft() {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "Quiz not found"})
 		return
 	}
-	ctx.JSON(http.StatusOK, quiz)
+	ctx.JSON(http.StatusOK, quiz.WithoutAnswers())
 }
 
 func (c *QuizController) GetQuestionsByQuiz(ctx *gin.Context) {
@@ -82,41 +87,68 @@ func (c *QuizController) GetQuestionsByQuiz(ctx *gin.Context) {
 
 	quizID := ctx.Param("quizId")
 	quiz, err := c.quizUseCase.GetQuiz(ctx.Request.Context(), quizID)
-	if err != nil {
+	if err != nil || quiz.IsDraft() {
 		ctx.JSON(http.StatusNotFound, gin.H{"error": "Quiz not found"})
 		return
 	}
-	ctx.JSON(http.StatusOK, quiz.Questions)
+	ctx.JSON(http.StatusOK, quiz.WithoutAnswers().Questions)
+}
+
+type SubmitQuizRequest struct {
+	Answers   map[string]string `json:"answers" binding:"required"` // question ID -> selected option key
+	StartedAt time.Time         `json:"started_at"`                 // optional; used to record how long the attempt took
+}
+
+// SubmitQuiz grades an attempt. Attempts by signed-in users are saved to their history; visitors only get the result.
+func (c *QuizController) SubmitQuiz(ctx *gin.Context) {
+	quizID := ctx.Param("quizId")
+	var req SubmitQuizRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	principal := domain.PrincipalFrom(ctx)
+	attempt, err := c.quizUseCase.SubmitQuiz(ctx.Request.Context(), principal.UserID, quizID, req.Answers, req.StartedAt)
+	if err != nil {
+		respondQuizError(ctx, err, "Failed to submit the quiz")
+		return
+	}
+	ctx.JSON(http.StatusOK, attempt)
 }
 
-// type Answers map[string]string
-
-// func (c *QuizController) SubmitQuiz(ctx *gin.Context) {
-// 	quizID := ctx.Param("quizId")
-// 	var answers Answers
-// 	if err := ctx.ShouldBindJSON(&answers); err != nil {
-// 		ctx.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
-// 		return
-// 	}
-
-// 	quiz, err := c.quizUseCase.GetQuiz(ctx.Request.Context(), quizID)
-// 	if err != nil {
-// 		ctx.JSON(http.StatusNotFound, gin.H{"error": "Quiz not found"})
-// 		return
-// 	}
-
-// 	score := 0
-// 	for _, question := range quiz.Questions {
-// 		if answer, ok := answers[question.ID.Hex()]; ok && answer == question.CorrectOption {
-// 			score++
-// 		}
-// 	}
-
-// 	ctx.JSON(http.StatusOK, gin.H{
-// 		"score":          score,
-// 		"total_question": len(quiz.Questions),
-// 	})
-// }
+func (c *QuizController) GetAttempts(ctx *gin.Context) {
+	principal := domain.PrincipalFrom(ctx)
+	if principal.IsVisitor() {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Sign in to see your quiz history"})
+		return
+	}
+
+	page, _ := strconv.ParseInt(ctx.DefaultQuery("page", "1"), 10, 64)
+	limit, _ := strconv.ParseInt(ctx.DefaultQuery("limit", "10"), 10, 64)
+
+	attempts, err := c.quizUseCase.ListAttempts(ctx.Request.Context(), principal.UserID, ctx.Query("quiz_id"), page, limit)
+	if err != nil {
+		respondQuizError(ctx, err, "Failed to load quiz history")
+		return
+	}
+	ctx.JSON(http.StatusOK, attempts)
+}
+
+func (c *QuizController) GetProgress(ctx *gin.Context) {
+	principal := domain.PrincipalFrom(ctx)
+	if principal.IsVisitor() {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "Sign in to see your quiz progress"})
+		return
+	}
+
+	progress, err := c.quizUseCase.GetProgress(ctx.Request.Context(), principal.UserID, ctx.Query("category_id"))
+	if err != nil {
+		respondQuizError(ctx, err, "Failed to load quiz progress")
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"categories": progress})
+}
 
 // --- Admin Handler Methods ---
 
@@ -199,6 +231,89 @@ func (c *QuizController) UpdateQuiz(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, quiz)
 }
 
+// AdminGetQuiz returns the quiz including each question's correct option.
+func (c *QuizController) AdminGetQuiz(ctx *gin.Context) {
+	quizID := ctx.Param("quizId")
+	quiz, err := c.quizUseCase.GetQuiz(ctx.Request.Context(), quizID)
+	if err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"error": "Quiz not found"})
+		return
+	}
+	ctx.JSON(http.StatusOK, quiz)
+}
+
+func (c *QuizController) UpdateQuizSettings(ctx *gin.Context) {
+	quizID := ctx.Param("quizId")
+	var req struct {
+		PassThreshold float64 `json:"pass_threshold"`
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	quiz, err := c.quizUseCase.UpdateQuizSettings(ctx.Request.Context(), quizID, req.PassThreshold)
+	if errors.Is(err, domain.ErrInvalidQuizSettings) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, quiz)
+}
+
+// GenerateQuiz drafts a quiz from a law source with AI-written questions. It stays hidden from learners until published.
+func (c *QuizController) GenerateQuiz(ctx *gin.Context) {
+	var req struct {
+		CategoryID   string `json:"category_id" binding:"required"`
+		Name         string `json:"name"`
+		Source       string `json:"source" binding:"required"`
+		ArticleFrom  int    `json:"article_from"`
+		ArticleTo    int    `json:"article_to"`
+		NumQuestions int    `json:"num_questions"`
+	}
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	quiz, err := c.quizUseCase.GenerateQuiz(ctx.Request.Context(), domain.GenerateQuizRequest{
+		CategoryID:   req.CategoryID,
+		Name:         req.Name,
+		Source:       req.Source,
+		ArticleFrom:  req.ArticleFrom,
+		ArticleTo:    req.ArticleTo,
+		NumQuestions: req.NumQuestions,
+	})
+	if err != nil {
+		respondQuizError(ctx, err, "Failed to generate the quiz")
+		return
+	}
+	ctx.JSON(http.StatusCreated, quiz)
+}
+
+func (c *QuizController) GetDraftQuizzes(ctx *gin.Context) {
+	page, _ := strconv.ParseInt(ctx.DefaultQuery("page", "1"), 10, 64)
+	limit, _ := strconv.ParseInt(ctx.DefaultQuery("limit", "10"), 10, 64)
+
+	paginatedQuizzes, err := c.quizUseCase.ListDraftQuizzes(ctx.Request.Context(), page, limit)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, paginatedQuizzes)
+}
+
+func (c *QuizController) PublishQuiz(ctx *gin.Context) {
+	quizID := ctx.Param("quizId")
+	quiz, err := c.quizUseCase.PublishQuiz(ctx.Request.Context(), quizID)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, quiz)
+}
+
 func (c *QuizController) DeleteQuiz(ctx *gin.Context) {
 	quizID := ctx.Param("quizId")
 	err := c.quizUseCase.DeleteQuiz(ctx.Request.Context(), quizID)
@@ -258,3 +373,16 @@ func (c *QuizController) DeleteQuestion(ctx *gin.Context) {
 	}
 	ctx.JSON(http.StatusNoContent, nil)
 }
+
+// respondQuizError maps caller mistakes to 400, unknown categories, quizzes and passages to 404, and anything else to a 500.
+func respondQuizError(ctx *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, domain.ErrInvalidQuizRequest):
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, domain.ErrCategoryNotFound), errors.Is(err, domain.ErrQuizNotFound), errors.Is(err, domain.ErrNoQuizPassages):
+		ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		log.Printf("%s: %v", fallback, err)
+		ctx.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
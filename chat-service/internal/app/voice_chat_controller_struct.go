@@ -6,13 +6,13 @@ import (
 )
 
 type VoiceChatController struct {
-	ChatService *usecase.ChatService
-	Config      *config.Config
+	cfg          *config.Config
+	voiceService *usecase.VoiceService
 }
 
-func NewVoiceChatController(chatService *usecase.ChatService, cfg *config.Config) *VoiceChatController {
+func NewVoiceChatController(cfg *config.Config, voiceService *usecase.VoiceService) *VoiceChatController {
 	return &VoiceChatController{
-		ChatService: chatService,
-		Config:      cfg,
+		cfg:          cfg,
+		voiceService: voiceService,
 	}
 }
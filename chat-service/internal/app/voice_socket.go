@@ -0,0 +1,335 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/usecase"
+)
+
+// maxUtteranceBytes bounds the audio of one question, several minutes of compressed speech.
+const maxUtteranceBytes = 8 << 20
+
+// voiceSocketRequest is a text frame from the client. The audio of a question is sent in binary frames
+// between its start and end frames.
+type voiceSocketRequest struct {
+	Type      string `json:"type"` // start, end, cancel, ping or pong
+	ID        string `json:"id"`   // optional; echoed on the frames answering this question
+	SessionID string `json:"sessionId"`
+	Language  string `json:"language"`
+	Format    string `json:"format"` // content type of the audio, e.g. audio/webm;codecs=opus
+}
+
+// rawFrame is a received frame, text or binary.
+type rawFrame struct {
+	binary bool
+	data   []byte
+}
+
+var rawFrameCodec = websocket.Codec{
+	Unmarshal: func(data []byte, payloadType byte, v interface{}) error {
+		frame := v.(*rawFrame)
+		frame.binary = payloadType == websocket.BinaryFrame
+		frame.data = data
+		return nil
+	},
+}
+
+// voiceSocket holds a spoken conversation. Partial transcripts come back while the user speaks, then the
+// text answer as on the chat socket, with the speech for each answer sentence in binary frames.
+func (c *VoiceChatController) voiceSocket(ctx *gin.Context) {
+	principal := domain.PrincipalFrom(ctx)
+	clientIP := ctx.ClientIP()
+	server := websocket.Server{
+		Handshake: checkOrigin(c.cfg.AllowedOrigins),
+		Handler: func(conn *websocket.Conn) {
+			s := &voiceConn{socketWriter: socketWriter{conn: conn}, voiceService: c.voiceService, principal: principal, clientIP: clientIP}
+			s.serve()
+		},
+	}
+	server.ServeHTTP(ctx.Writer, ctx.Request)
+}
+
+type voiceConn struct {
+	socketWriter
+	voiceService *usecase.VoiceService
+	principal    domain.Principal
+	clientIP     string
+	turns        sync.WaitGroup
+	recording    *voiceTurn // the turn still receiving audio; only used by serve
+
+	mu        sync.Mutex
+	active    *voiceTurn // the turn being answered; nil when idle
+	sessionID string     // the session of the last answer, used by follow-ups that don't name one
+}
+
+type voiceTurn struct {
+	id       string
+	cancel   context.CancelFunc
+	audio    *audioForwarder
+	received int
+}
+
+// audioForwarder passes a turn's audio on to the voice service from a goroutine of its own, so a slow STT
+// service never stops the socket from reading cancel, end and ping frames. The backlog is bounded by
+// maxUtteranceBytes.
+type audioForwarder struct {
+	mu     sync.Mutex
+	queue  [][]byte
+	closed bool
+	wake   chan struct{}
+}
+
+func newAudioForwarder() *audioForwarder {
+	return &audioForwarder{wake: make(chan struct{}, 1)}
+}
+
+func (f *audioForwarder) push(data []byte) {
+	f.mu.Lock()
+	f.queue = append(f.queue, data)
+	f.mu.Unlock()
+	f.signal()
+}
+
+// close ends the audio once everything queued has been forwarded.
+func (f *audioForwarder) close() {
+	f.mu.Lock()
+	f.closed = true
+	f.mu.Unlock()
+	f.signal()
+}
+
+func (f *audioForwarder) signal() {
+	select {
+	case f.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run forwards the queued audio to out, closing it after the last chunk. It gives up once ctx is done.
+func (f *audioForwarder) run(ctx context.Context, out chan<- []byte) {
+	for {
+		f.mu.Lock()
+		queue, closed := f.queue, f.closed
+		f.queue = nil
+		f.mu.Unlock()
+		for _, data := range queue {
+			select {
+			case out <- data:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if len(queue) > 0 {
+			continue
+		}
+		if closed {
+			close(out)
+			return
+		}
+		select {
+		case <-f.wake:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *voiceConn) serve() {
+	ctx, cancel := context.WithCancel(s.conn.Request().Context())
+	defer func() {
+		cancel()
+		s.conn.Close()
+		s.turns.Wait()
+	}()
+	go s.heartbeat(ctx)
+
+	for {
+		// Any frame, pongs included, shows the client is still there
+		s.conn.SetReadDeadline(time.Now().Add(2 * socketPingInterval))
+		var frame rawFrame
+		if err := rawFrameCodec.Receive(s.conn, &frame); err != nil {
+			return
+		}
+		if frame.binary {
+			s.receiveAudio(frame.data)
+			continue
+		}
+		var req voiceSocketRequest
+		if err := json.Unmarshal(frame.data, &req); err != nil {
+			s.write(socketFrame{Type: "error", Data: map[string]string{"message": "Frames must be JSON objects"}})
+			continue
+		}
+		switch req.Type {
+		case "start":
+			s.startTurn(ctx, req)
+		case "end":
+			s.endAudio()
+		case "cancel":
+			s.cancelTurn()
+		case "ping":
+			s.write(socketFrame{Type: "pong", ID: req.ID})
+		case "pong":
+		default:
+			s.write(socketFrame{Type: "error", ID: req.ID, Data: map[string]string{"message": "Unknown frame type: " + req.Type}})
+		}
+	}
+}
+
+// startTurn begins a question; one is answered at a time.
+func (s *voiceConn) startTurn(ctx context.Context, req voiceSocketRequest) {
+	s.mu.Lock()
+	if s.active != nil {
+		s.mu.Unlock()
+		s.write(socketFrame{Type: "error", ID: req.ID, Data: map[string]string{"message": "An answer is already in progress; cancel it before asking again"}})
+		return
+	}
+	sessionID := req.SessionID
+	if sessionID == "" {
+		sessionID = s.sessionID
+	}
+	turnCtx, cancel := context.WithCancel(ctx)
+	turn := &voiceTurn{id: req.ID, cancel: cancel, audio: newAudioForwarder()}
+	s.active = turn
+	s.mu.Unlock()
+
+	language := req.Language
+	if language == "" {
+		language = "en"
+	}
+	audio := make(chan []byte, 16)
+	events, err := s.voiceService.Converse(turnCtx, usecase.VoiceRequest{
+		SessionID:   sessionID,
+		UserID:      s.principal.UserID,
+		PlanID:      s.principal.PlanID,
+		ClientIP:    s.clientIP,
+		Language:    language,
+		ContentType: req.Format,
+	}, audio)
+	if err != nil {
+		cancel()
+		s.release(turn)
+		s.write(voiceErrorFrame(req.ID, err))
+		return
+	}
+	s.recording = turn
+	s.turns.Add(2)
+	go func() {
+		defer s.turns.Done()
+		turn.audio.run(turnCtx, audio)
+	}()
+	go func() {
+		defer s.turns.Done()
+		defer cancel()
+		defer s.release(turn)
+		s.relay(turnCtx, turn, events)
+	}()
+}
+
+func (s *voiceConn) receiveAudio(data []byte) {
+	turn := s.recording
+	if turn == nil {
+		s.write(socketFrame{Type: "error", Data: map[string]string{"message": "Send a start frame before audio"}})
+		return
+	}
+	turn.received += len(data)
+	if turn.received > maxUtteranceBytes {
+		s.write(socketFrame{Type: "error", ID: turn.id, Data: map[string]string{"message": "The recording is too long"}})
+		s.cancelTurn()
+		return
+	}
+	turn.audio.push(data)
+}
+
+// endAudio ends the question being recorded; its answer follows.
+func (s *voiceConn) endAudio() {
+	if s.recording != nil {
+		s.recording.audio.close()
+		s.recording = nil
+	}
+}
+
+func (s *voiceConn) cancelTurn() {
+	s.recording = nil
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.active != nil {
+		s.active.cancel()
+	}
+}
+
+// relay sends a turn's events. A turn ends with done once the answer has been spoken, with error, or
+// with cancelled.
+func (s *voiceConn) relay(ctx context.Context, turn *voiceTurn, events <-chan usecase.VoiceEvent) {
+	id := turn.id
+	failed := false
+	for event := range events {
+		var err error
+		switch {
+		case event.Error != nil:
+			failed = true
+			err = s.write(voiceErrorFrame(id, event.Error))
+		case event.Transcript != nil:
+			err = s.write(socketFrame{Type: "transcript", ID: id, Data: map[string]interface{}{
+				"text":  event.Transcript.Text,
+				"final": event.Transcript.Final,
+			}})
+		case event.Answer != nil:
+			if event.Answer.SessionID != "" {
+				s.mu.Lock()
+				s.sessionID = event.Answer.SessionID
+				s.mu.Unlock()
+			}
+			name, data, ok := streamEvent(*event.Answer)
+			if !ok {
+				continue
+			}
+			failed = failed || name == "error"
+			err = s.write(socketFrame{Type: name, ID: id, Data: data})
+		case event.Sentence != nil:
+			err = s.write(socketFrame{Type: "speech", ID: id, Data: map[string]interface{}{
+				"index":        event.Sentence.Index,
+				"text":         event.Sentence.Text,
+				"content_type": "audio/mpeg",
+			}})
+		case len(event.Audio) > 0:
+			err = s.writeBinary(event.Audio)
+		}
+		if err != nil {
+			log.Printf("Failed to write to voice socket: %v", err)
+			turn.cancel()
+			return
+		}
+	}
+	switch {
+	case ctx.Err() != nil:
+		s.write(socketFrame{Type: "cancelled", ID: id})
+	case !failed:
+		s.write(socketFrame{Type: "done", ID: id})
+	}
+}
+
+// release frees the connection for the next question once turn has ended.
+func (s *voiceConn) release(turn *voiceTurn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.active == turn {
+		s.active = nil
+	}
+}
+
+func voiceErrorFrame(id string, err error) socketFrame {
+	var quotaErr *domain.QuotaExceededError
+	if errors.As(err, &quotaErr) {
+		return socketFrame{Type: "error", ID: id, Data: quotaErrorBody(quotaErr)}
+	}
+	return socketFrame{Type: "error", ID: id, Data: map[string]string{"message": voiceErrorMessage(err)}}
+}
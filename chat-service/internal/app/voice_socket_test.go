@@ -0,0 +1,56 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAudioForwarderNeverBlocksTheReader(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	forwarder := newAudioForwarder()
+	out := make(chan []byte) // nobody reads it, like a hung STT service
+	stopped := make(chan struct{})
+	go func() {
+		forwarder.run(ctx, out)
+		close(stopped)
+	}()
+
+	pushed := make(chan struct{})
+	go func() {
+		for i := 0; i < 100; i++ {
+			forwarder.push([]byte{byte(i)})
+		}
+		forwarder.close()
+		close(pushed)
+	}()
+	select {
+	case <-pushed:
+	case <-time.After(time.Second):
+		t.Fatal("pushing audio blocked on the STT service")
+	}
+
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("the forwarder outlived its turn")
+	}
+}
+
+func TestAudioForwarderForwardsInOrderThenCloses(t *testing.T) {
+	forwarder := newAudioForwarder()
+	out := make(chan []byte, 4)
+	forwarder.push([]byte("a"))
+	forwarder.push([]byte("b"))
+	forwarder.close()
+	forwarder.run(context.Background(), out)
+
+	var got string
+	for data := range out {
+		got += string(data)
+	}
+	if got != "ab" {
+		t.Errorf("forwarded %q, want %q", got, "ab")
+	}
+}
@@ -0,0 +1,61 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/config"
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+)
+
+type httpEmbedder struct {
+	apiURL string
+	client *http.Client
+}
+
+// NewEmbedder returns an Embedder backed by the embedding API in cfg, or nil when none is configured,
+// in which case the response cache only serves exact matches.
+func NewEmbedder(cfg *config.Config) domain.Embedder {
+	if cfg.EmbeddingApiUrl == "" {
+		return nil
+	}
+	return &httpEmbedder{
+		apiURL: cfg.EmbeddingApiUrl,
+		client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+func (e *httpEmbedder) Embed(ctx context.Context, text string) ([]float32, error) {
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.apiURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("REST call to embedding API failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(b))
+	}
+
+	var parsed struct {
+		Embedding []float32 `json:"embedding"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse response JSON: %w", err)
+	}
+	return parsed.Embedding, nil
+}
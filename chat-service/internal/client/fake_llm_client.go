@@ -0,0 +1,135 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/config"
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+)
+
+const defaultFakeAnswer = "This is a scripted answer from the offline fake LLM provider."
+
+// FakeResponse is one scripted reply of the fake LLM provider. The first response whose
+// Match is contained in the prompt is used; an empty Match matches every prompt.
+// Error, when set, makes the call fail with the named domain error instead.
+type FakeResponse struct {
+	Match string `json:"match"`
+	Text  string `json:"text"`
+	Error string `json:"error,omitempty"` // rate_limited, safety_blocked, context_too_long or unavailable
+}
+
+var fakeErrors = map[string]error{
+	"rate_limited":     domain.ErrLLMRateLimited,
+	"safety_blocked":   domain.ErrLLMSafetyBlocked,
+	"context_too_long": domain.ErrLLMContextTooLong,
+	"unavailable":      domain.ErrLLMUnavailable,
+}
+
+// FakeLLMClient is a deterministic, network-free LLMService for local development and tests.
+type FakeLLMClient struct {
+	responses []FakeResponse
+
+	mu      sync.Mutex
+	prompts []string
+}
+
+// NewFakeLLM returns a fake provider that answers from the given script.
+func NewFakeLLM(responses ...FakeResponse) *FakeLLMClient {
+	return &FakeLLMClient{responses: responses}
+}
+
+// NewFakeLLMClient builds the fake provider from the script file in cfg, if any.
+func NewFakeLLMClient(cfg *config.Config) (domain.LLMService, error) {
+	if cfg.FakeLLMScriptPath == "" {
+		return NewFakeLLM(), nil
+	}
+	data, err := os.ReadFile(cfg.FakeLLMScriptPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fake LLM script: %w", err)
+	}
+	var responses []FakeResponse
+	if err := json.Unmarshal(data, &responses); err != nil {
+		return nil, fmt.Errorf("failed to parse fake LLM script: %w", err)
+	}
+	for _, r := range responses {
+		if _, ok := fakeErrors[r.Error]; r.Error != "" && !ok {
+			return nil, fmt.Errorf("unknown error %q in fake LLM script", r.Error)
+		}
+	}
+	return NewFakeLLM(responses...), nil
+}
+
+func (c *FakeLLMClient) Close() error {
+	return nil
+}
+
+// Prompts returns every prompt the fake has received, in order.
+func (c *FakeLLMClient) Prompts() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.prompts...)
+}
+
+func (c *FakeLLMClient) StreamGenerate(ctx context.Context, prompt string, history []domain.ChatEntry, maxWords int) (<-chan domain.LLMStreamResponse, error) {
+	text, err := c.respond(prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	resChan := make(chan domain.LLMStreamResponse)
+	go func() {
+		defer close(resChan)
+		chunks := strings.SplitAfter(text, " ")
+		chunks = append(chunks, "")
+		for i, chunk := range chunks {
+			resp := domain.LLMStreamResponse{Chunk: chunk}
+			if i == len(chunks)-1 {
+				resp = domain.LLMStreamResponse{Done: true}
+			}
+			select {
+			case resChan <- resp:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return resChan, nil
+}
+
+func (c *FakeLLMClient) Generate(ctx context.Context, prompt string, history []domain.ChatEntry) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return c.respond(prompt)
+}
+
+// Translate returns the text unchanged so the pipeline stays deterministic in every language.
+func (c *FakeLLMClient) Translate(ctx context.Context, prompt, text, targetLang string) (string, error) {
+	c.record(text)
+	return text, nil
+}
+
+func (c *FakeLLMClient) respond(prompt string) (string, error) {
+	c.record(prompt)
+	for _, r := range c.responses {
+		if r.Match != "" && !strings.Contains(prompt, r.Match) {
+			continue
+		}
+		if r.Error != "" {
+			return "", fmt.Errorf("%w: scripted failure", fakeErrors[r.Error])
+		}
+		return r.Text, nil
+	}
+	return defaultFakeAnswer, nil
+}
+
+func (c *FakeLLMClient) record(prompt string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.prompts = append(c.prompts, prompt)
+}
@@ -2,14 +2,18 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"strings"
-	"time"
 
 	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/LAWGEN/lawgen-backend/chat-service/internal/config"
 	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
@@ -59,26 +63,32 @@ func (c *llmClient) StreamGenerate(ctx context.Context, prompt string, history [
 
 	go func() {
 		defer close(resChan)
+		send := func(r domain.LLMStreamResponse) bool {
+			select {
+			case resChan <- r:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
 		for {
 			resp, err := iter.Next()
 			if err == iterator.Done {
-				resChan <- domain.LLMStreamResponse{Done: true}
+				send(domain.LLMStreamResponse{Done: true})
 				return
 			}
 			if err != nil {
 				log.Printf("LLM Stream error: %v", err)
-				resChan <- domain.LLMStreamResponse{Error: fmt.Errorf("LLM stream error: %w", err)}
+				send(domain.LLMStreamResponse{Error: fmt.Errorf("LLM stream error: %w", mapGeminiError(err))})
 				return
 			}
 
-			if resp != nil && len(resp.Candidates) > 0 {
+			// Forward chunks as they arrive. A send waits for the reader, so a slow client holds the stream back
+			if resp != nil && len(resp.Candidates) > 0 && resp.Candidates[0].Content != nil {
 				for _, part := range resp.Candidates[0].Content.Parts {
-					if text, ok := part.(genai.Text); ok {
-						// Stream word by word for improved readability
-						words := strings.Fields(string(text))
-						for _, word := range words {
-							time.Sleep(30 * time.Millisecond)
-							resChan <- domain.LLMStreamResponse{Chunk: word + " "}
+					if text, ok := part.(genai.Text); ok && text != "" {
+						if !send(domain.LLMStreamResponse{Chunk: string(text)}) {
+							return
 						}
 					}
 				}
@@ -104,7 +114,7 @@ func (c *llmClient) Generate(ctx context.Context, prompt string, history []domai
 
 	resp, err := cs.SendMessage(ctx, genai.Text(prompt))
 	if err != nil {
-		return "", fmt.Errorf("failed to generate content: %w", err)
+		return "", fmt.Errorf("failed to generate content: %w", mapGeminiError(err))
 	}
 	if len(resp.Candidates) > 0 && resp.Candidates[0].Content != nil {
 		var sb strings.Builder
@@ -118,8 +128,48 @@ func (c *llmClient) Generate(ctx context.Context, prompt string, history []domai
 	return "", fmt.Errorf("no text generated from LLM for prompt: %s", prompt)
 }
 
-func (c *llmClient) Translate(ctx context.Context, text, targetLang string) (string, error) {
-	prompt := strings.ReplaceAll(c.cfg.LLMPromptConverter, "{{.Text}}", text)
+func (c *llmClient) Translate(ctx context.Context, prompt, text, targetLang string) (string, error) {
 	// Translation typically doesn't need prior chat history for context
 	return c.Generate(ctx, prompt, nil)
 }
+
+// mapGeminiError wraps Gemini API failures in the matching domain LLM error.
+func mapGeminiError(err error) error {
+	var blocked *genai.BlockedError
+	if errors.As(err, &blocked) {
+		return fmt.Errorf("%w: %v", domain.ErrLLMSafetyBlocked, err)
+	}
+
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.Code == http.StatusTooManyRequests:
+			return fmt.Errorf("%w: %v", domain.ErrLLMRateLimited, err)
+		case apiErr.Code == http.StatusBadRequest && isContextLengthMessage(apiErr.Message):
+			return fmt.Errorf("%w: %v", domain.ErrLLMContextTooLong, err)
+		case apiErr.Code >= http.StatusInternalServerError:
+			return fmt.Errorf("%w: %v", domain.ErrLLMUnavailable, err)
+		}
+		return err
+	}
+
+	if st, ok := status.FromError(err); ok {
+		switch st.Code() {
+		case codes.ResourceExhausted:
+			return fmt.Errorf("%w: %v", domain.ErrLLMRateLimited, err)
+		case codes.InvalidArgument:
+			if isContextLengthMessage(st.Message()) {
+				return fmt.Errorf("%w: %v", domain.ErrLLMContextTooLong, err)
+			}
+		case codes.Unavailable, codes.DeadlineExceeded, codes.Internal:
+			return fmt.Errorf("%w: %v", domain.ErrLLMUnavailable, err)
+		}
+	}
+	return err
+}
+
+// isContextLengthMessage reports whether a provider error message complains about prompt size.
+func isContextLengthMessage(msg string) bool {
+	msg = strings.ToLower(msg)
+	return strings.Contains(msg, "token") && (strings.Contains(msg, "exceed") || strings.Contains(msg, "too long") || strings.Contains(msg, "limit"))
+}
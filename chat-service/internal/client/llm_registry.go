@@ -0,0 +1,39 @@
+package client
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/config"
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+)
+
+// LLMProviderFactory builds an LLMService from the application config.
+type LLMProviderFactory func(cfg *config.Config) (domain.LLMService, error)
+
+var llmProviders = map[string]LLMProviderFactory{
+	"gemini": NewLLMClient,
+	"openai": NewOpenAIClient,
+	"fake":   NewFakeLLMClient,
+}
+
+// RegisterLLMProvider makes an additional LLM backend selectable through LLM_PROVIDER.
+func RegisterLLMProvider(name string, factory LLMProviderFactory) {
+	llmProviders[strings.ToLower(name)] = factory
+}
+
+// NewLLMService builds the LLM provider selected by cfg.LLMProvider.
+func NewLLMService(cfg *config.Config) (domain.LLMService, error) {
+	name := strings.ToLower(cfg.LLMProvider)
+	factory, ok := llmProviders[name]
+	if !ok {
+		names := make([]string, 0, len(llmProviders))
+		for n := range llmProviders {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown LLM provider %q (available: %s)", cfg.LLMProvider, strings.Join(names, ", "))
+	}
+	return factory(cfg)
+}
@@ -0,0 +1,214 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/config"
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+)
+
+// openAIClient talks to any endpoint implementing the OpenAI chat completions API.
+type openAIClient struct {
+	baseURL string
+	apiKey  string
+	model   string
+	client  *http.Client
+	cfg     *config.Config
+}
+
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIChoice struct {
+	Message      openAIMessage `json:"message"`
+	Delta        openAIMessage `json:"delta"`
+	FinishReason string        `json:"finish_reason"`
+}
+
+type openAIResponse struct {
+	Choices []openAIChoice `json:"choices"`
+}
+
+type openAIErrorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+		Code    string `json:"code"`
+	} `json:"error"`
+}
+
+func NewOpenAIClient(cfg *config.Config) (domain.LLMService, error) {
+	if cfg.OpenAIBaseURL == "" {
+		return nil, fmt.Errorf("OPENAI_BASE_URL is required for the openai LLM provider")
+	}
+	// Bound the wait for the first byte only; streamed answers may take longer than any fixed timeout
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = 60 * time.Second
+	return &openAIClient{
+		baseURL: strings.TrimRight(cfg.OpenAIBaseURL, "/"),
+		apiKey:  cfg.OpenAIAPIKey,
+		model:   cfg.OpenAIModel,
+		client:  &http.Client{Transport: transport},
+		cfg:     cfg,
+	}, nil
+}
+
+func (c *openAIClient) Close() error {
+	// nothing to close for REST
+	return nil
+}
+
+func (c *openAIClient) StreamGenerate(ctx context.Context, prompt string, history []domain.ChatEntry, maxWords int) (<-chan domain.LLMStreamResponse, error) {
+	resp, err := c.post(ctx, prompt, history, true)
+	if err != nil {
+		return nil, err
+	}
+
+	resChan := make(chan domain.LLMStreamResponse)
+	go func() {
+		defer close(resChan)
+		defer resp.Body.Close()
+
+		send := func(r domain.LLMStreamResponse) bool {
+			select {
+			case resChan <- r:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if !strings.HasPrefix(line, "data:") {
+				continue
+			}
+			data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+			if data == "[DONE]" {
+				send(domain.LLMStreamResponse{Done: true})
+				return
+			}
+
+			var chunk openAIResponse
+			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+				send(domain.LLMStreamResponse{Error: fmt.Errorf("failed to parse stream chunk: %w", err)})
+				return
+			}
+			if len(chunk.Choices) == 0 {
+				continue
+			}
+			choice := chunk.Choices[0]
+			if choice.FinishReason == "content_filter" {
+				send(domain.LLMStreamResponse{Error: fmt.Errorf("%w: finish reason content_filter", domain.ErrLLMSafetyBlocked)})
+				return
+			}
+			if choice.Delta.Content != "" && !send(domain.LLMStreamResponse{Chunk: choice.Delta.Content}) {
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			send(domain.LLMStreamResponse{Error: fmt.Errorf("LLM stream error: %w", err)})
+			return
+		}
+		send(domain.LLMStreamResponse{Done: true})
+	}()
+
+	return resChan, nil
+}
+
+func (c *openAIClient) Generate(ctx context.Context, prompt string, history []domain.ChatEntry) (string, error) {
+	resp, err := c.post(ctx, prompt, history, false)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var parsed openAIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return "", fmt.Errorf("failed to parse completion response: %w", err)
+	}
+	if len(parsed.Choices) == 0 {
+		return "", fmt.Errorf("no text generated from LLM for prompt: %s", prompt)
+	}
+	if parsed.Choices[0].FinishReason == "content_filter" {
+		return "", fmt.Errorf("%w: finish reason content_filter", domain.ErrLLMSafetyBlocked)
+	}
+	return parsed.Choices[0].Message.Content, nil
+}
+
+func (c *openAIClient) Translate(ctx context.Context, prompt, text, targetLang string) (string, error) {
+	return c.Generate(ctx, prompt, nil)
+}
+
+// post sends a chat completion request and returns the response once it has a 200 status.
+func (c *openAIClient) post(ctx context.Context, prompt string, history []domain.ChatEntry, stream bool) (*http.Response, error) {
+	messages := make([]openAIMessage, 0, len(history)+1)
+	for _, entry := range history {
+		role := "user"
+		if entry.Type == domain.MessageTypeLLM {
+			role = "assistant"
+		}
+		messages = append(messages, openAIMessage{Role: role, Content: entry.Content})
+	}
+	messages = append(messages, openAIMessage{Role: "user", Content: prompt})
+
+	body, err := json.Marshal(map[string]interface{}{
+		"model":    c.model,
+		"messages": messages,
+		"stream":   stream,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrLLMUnavailable, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return nil, mapOpenAIError(resp.StatusCode, b)
+	}
+	return resp, nil
+}
+
+// mapOpenAIError converts an OpenAI-style error response into a domain LLM error.
+func mapOpenAIError(statusCode int, body []byte) error {
+	var parsed openAIErrorResponse
+	_ = json.Unmarshal(body, &parsed)
+	err := fmt.Errorf("unexpected status %d: %s", statusCode, string(body))
+
+	switch {
+	case statusCode == http.StatusTooManyRequests:
+		return fmt.Errorf("%w: %v", domain.ErrLLMRateLimited, err)
+	case parsed.Error.Code == "context_length_exceeded" || isContextLengthMessage(parsed.Error.Message):
+		return fmt.Errorf("%w: %v", domain.ErrLLMContextTooLong, err)
+	case parsed.Error.Code == "content_filter" || parsed.Error.Code == "content_policy_violation":
+		return fmt.Errorf("%w: %v", domain.ErrLLMSafetyBlocked, err)
+	case statusCode >= http.StatusInternalServerError:
+		return fmt.Errorf("%w: %v", domain.ErrLLMUnavailable, err)
+	}
+	return err
+}
@@ -1,14 +1,15 @@
 package client
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"strings"
-	"time"
 
 	"github.com/LAWGEN/lawgen-backend/chat-service/internal/config"
 	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
@@ -22,7 +23,7 @@ type ragClient struct {
 func NewRAGClient(cfg *config.Config) (domain.RAGService, error) {
 	return &ragClient{
 		apiURL: cfg.RAGServiceAddr, // e.g. "http://127.0.0.1:8000"
-		client: &http.Client{Timeout: 15 * time.Second},
+		client: &http.Client{Timeout: cfg.RAGTimeout},
 	}, nil
 }
 
@@ -31,7 +32,30 @@ func (r *ragClient) Close() error {
 	return nil
 }
 
+// Retrieve collects a whole /ask response.
 func (r *ragClient) Retrieve(ctx context.Context, query string, k int) (*domain.RAGResult, error) {
+	events, err := r.RetrieveStream(ctx, query, k)
+	if err != nil {
+		return nil, err
+	}
+	result := &domain.RAGResult{
+		References: nil, // REST API response doesn’t include references
+	}
+	for event := range events {
+		if event.Err != nil {
+			return nil, event.Err
+		}
+		result.Results = append(result.Results, event.Sources...)
+		if event.Message != "" {
+			result.Message = event.Message
+		}
+	}
+	return result, nil
+}
+
+// RetrieveStream reads /ask incrementally. The AI service may answer with NDJSON, SSE or a single JSON
+// body; each line, SSE data payload or body holds either a batch of results or a single result.
+func (r *ragClient) RetrieveStream(ctx context.Context, query string, k int) (<-chan domain.RAGStreamEvent, error) {
 	// Prepare request body
 	body, err := json.Marshal(map[string]interface{}{
 		"query": query,
@@ -47,49 +71,159 @@ func (r *ragClient) Retrieve(ctx context.Context, query string, k int) (*domain.
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/x-ndjson, text/event-stream, application/json")
 
 	resp, err := r.client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("REST call to /ask failed: %w", err)
 	}
-	defer resp.Body.Close()
-
 	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
 		b, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(b))
+		return nil, &ragStatusError{status: resp.StatusCode, body: string(b)}
 	}
 
-	// Parse response JSON
-	var parsed struct {
-		Results []struct {
-			Content       string `json:"content"`
-			Source        string `json:"source"`
-			ArticleNumber string `json:"article_number"`
-			Topics        string `json:"topics"`
-		} `json:"results"`
-		Message string `json:"message"`
+	events := make(chan domain.RAGStreamEvent)
+	go func() {
+		defer close(events)
+		defer resp.Body.Close()
+
+		send := func(event domain.RAGStreamEvent) bool {
+			select {
+			case events <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		handle := func(data []byte) bool {
+			var item ragItem
+			if err := json.Unmarshal(data, &item); err != nil {
+				send(domain.RAGStreamEvent{Err: fmt.Errorf("failed to parse response JSON: %w", err)})
+				return false
+			}
+			return send(item.event())
+		}
+
+		var err error
+		if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType == "text/event-stream" {
+			err = readSSEData(resp.Body, handle)
+		} else {
+			err = readJSONValues(resp.Body, handle)
+		}
+		if err != nil {
+			send(domain.RAGStreamEvent{Err: fmt.Errorf("failed to read /ask response: %w", err)})
+		}
+	}()
+	return events, nil
+}
+
+// readJSONValues calls handle with each JSON value in r, which covers NDJSON as well as a single body.
+// It stops early when handle returns false.
+func readJSONValues(r io.Reader, handle func([]byte) bool) error {
+	dec := json.NewDecoder(r)
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if !handle(raw) {
+			return nil
+		}
 	}
+}
 
-	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
-		return nil, fmt.Errorf("failed to parse response JSON: %w", err)
+// readSSEData calls handle with the data of each server-sent event in r, until a [DONE] payload or
+// until handle returns false.
+func readSSEData(r io.Reader, handle func([]byte) bool) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024) // law articles can make long lines
+	var data []string
+	flush := func() bool {
+		payload := strings.Join(data, "\n")
+		data = data[:0]
+		if payload == "" {
+			return true
+		}
+		if payload == "[DONE]" {
+			return false
+		}
+		return handle([]byte(payload))
 	}
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if !flush() {
+				return nil
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "data:") {
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	flush()
+	return nil
+}
 
+// ragItem is one unit of an /ask response: a batch of results with a message, or a single result.
+type ragItem struct {
+	Results []ragResultJSON `json:"results"`
+	Message string          `json:"message"`
+	ragResultJSON
+}
+
+type ragResultJSON struct {
+	Content       string          `json:"content"`
+	Source        string          `json:"source"`
+	ArticleNumber string          `json:"article_number"`
+	Topics        json.RawMessage `json:"topics"`
+}
+
+func (i ragItem) event() domain.RAGStreamEvent {
+	event := domain.RAGStreamEvent{Message: i.Message}
+	results := i.Results
+	if results == nil && i.Content != "" {
+		results = []ragResultJSON{i.ragResultJSON}
+	}
 	// Convert to domain
-	var sources []domain.RAGSource
-	for _, r := range parsed.Results {
-		sources = append(sources, domain.RAGSource{
+	for _, r := range results {
+		event.Sources = append(event.Sources, domain.RAGSource{
 			Content:       r.Content,
 			Source:        r.Source,
 			ArticleNumber: r.ArticleNumber,
-			Topics:        parseTopics(r.Topics),
+			Topics:        topicsFromJSON(r.Topics),
 		})
 	}
+	return event
+}
 
-	return &domain.RAGResult{
-		Results:    sources,
-		Message:    parsed.Message,
-		References: nil, // REST API response doesn’t include references
-	}, nil
+// ragStatusError is a non-200 response from the RAG service.
+type ragStatusError struct {
+	status int
+	body   string
+}
+
+func (e *ragStatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d: %s", e.status, e.body)
+}
+
+// topicsFromJSON accepts topics sent as a JSON array or as a string.
+func topicsFromJSON(raw json.RawMessage) []string {
+	var arr []string
+	if err := json.Unmarshal(raw, &arr); err == nil {
+		return arr
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return parseTopics(s)
+	}
+	return nil
 }
 
 // parseTopics parses the topics string into a slice of strings
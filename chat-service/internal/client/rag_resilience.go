@@ -0,0 +1,325 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/config"
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+)
+
+// Prometheus metrics for RAG retrieval. Registered in main.
+var (
+	RAGBreakerStateGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "rag_circuit_breaker_state",
+		Help: "State of the RAG service circuit breaker: 0 closed, 1 half-open, 2 open",
+	})
+	RAGFallbackRetrievalsCounter = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "rag_fallback_retrievals_total",
+		Help: "Number of retrievals answered from cached law articles while the RAG service was unavailable",
+	})
+)
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerHalfOpen
+	breakerOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerHalfOpen:
+		return "half-open"
+	case breakerOpen:
+		return "open"
+	}
+	return "closed"
+}
+
+// circuitBreaker stops calls to a failing dependency for a cooldown after threshold consecutive failures,
+// then lets a single probe through (half-open) to decide whether to close again.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	state     breakerState
+	failures  int
+	threshold int
+	cooldown  time.Duration
+	openedAt  time.Time
+	probing   bool
+}
+
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	RAGBreakerStateGauge.Set(float64(breakerClosed))
+	return &circuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// allow reports whether a call may go ahead. Each allowed call must be followed by record or cancel.
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.setState(breakerHalfOpen)
+		fallthrough
+	case breakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+	}
+	return true
+}
+
+// record reports the outcome of an allowed call.
+func (b *circuitBreaker) record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if success {
+		b.failures = 0
+		b.setState(breakerClosed)
+		return
+	}
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		b.openedAt = time.Now()
+		b.setState(breakerOpen)
+	}
+}
+
+// cancel releases an allowed call that ended without telling anything about the dependency's health,
+// e.g. because the client went away.
+func (b *circuitBreaker) cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+func (b *circuitBreaker) setState(state breakerState) {
+	if b.state != state {
+		log.Printf("RAG circuit breaker: %s -> %s", b.state, state)
+	}
+	b.state = state
+	RAGBreakerStateGauge.Set(float64(state))
+}
+
+type resilientRAG struct {
+	primary    domain.RAGService
+	cache      domain.LawArticleCache // nil disables the fallback
+	breaker    *circuitBreaker
+	maxRetries int
+	baseDelay  time.Duration
+	maxDelay   time.Duration
+}
+
+// NewResilientRAGService wraps the RAG client with retries, a circuit breaker and, when cache is not nil,
+// a keyword-search fallback over the law articles earlier retrievals returned. Results from the fallback
+// are marked Degraded.
+func NewResilientRAGService(cfg *config.Config, primary domain.RAGService, cache domain.LawArticleCache) domain.RAGService {
+	if !cfg.RAGFallbackEnabled {
+		cache = nil
+	}
+	return &resilientRAG{
+		primary:    primary,
+		cache:      cache,
+		breaker:    newCircuitBreaker(cfg.RAGBreakerThreshold, cfg.RAGBreakerCooldown),
+		maxRetries: cfg.RAGMaxRetries,
+		baseDelay:  cfg.RAGRetryBaseDelay,
+		maxDelay:   cfg.RAGRetryMaxDelay,
+	}
+}
+
+func (r *resilientRAG) Close() error {
+	return r.primary.Close()
+}
+
+func (r *resilientRAG) Retrieve(ctx context.Context, query string, k int) (*domain.RAGResult, error) {
+	if !r.breaker.allow() {
+		return r.fallback(ctx, query, k, errors.New("circuit breaker open"))
+	}
+	result, err := r.retrieveWithRetries(ctx, query, k)
+	switch {
+	case err == nil:
+		r.breaker.record(true)
+		r.cacheArticles(ctx, result.Results)
+		return result, nil
+	case ctx.Err() != nil:
+		r.breaker.cancel()
+		return nil, err
+	case !retryable(err):
+		// The service answered, so it is up; the request itself was bad
+		r.breaker.record(true)
+		return nil, err
+	}
+	r.breaker.record(false)
+	log.Printf("Warning: RAG retrieval failed after %d attempts: %v", r.maxRetries+1, err)
+	return r.fallback(ctx, query, k, err)
+}
+
+// RetrieveStream streams from the primary when it supports that. Retries and the breaker cover opening the
+// stream; once results flow, a failure ends the stream instead of being retried, since results may already
+// have been shown.
+func (r *resilientRAG) RetrieveStream(ctx context.Context, query string, k int) (<-chan domain.RAGStreamEvent, error) {
+	streamer, ok := r.primary.(domain.StreamingRAGService)
+	if !ok {
+		result, err := r.Retrieve(ctx, query, k)
+		if err != nil {
+			return nil, err
+		}
+		return singleEvent(result), nil
+	}
+	if !r.breaker.allow() {
+		result, err := r.fallback(ctx, query, k, errors.New("circuit breaker open"))
+		if err != nil {
+			return nil, err
+		}
+		return singleEvent(result), nil
+	}
+
+	var upstream <-chan domain.RAGStreamEvent
+	var err error
+	for attempt := 0; ; attempt++ {
+		upstream, err = streamer.RetrieveStream(ctx, query, k)
+		if err == nil || attempt >= r.maxRetries || !retryable(err) || ctx.Err() != nil {
+			break
+		}
+		delay := r.backoff(attempt)
+		log.Printf("Warning: RAG stream attempt %d failed, retrying in %s: %v", attempt+1, delay, err)
+		select {
+		case <-ctx.Done():
+		case <-time.After(delay):
+		}
+	}
+	switch {
+	case err == nil:
+		r.breaker.record(true)
+	case ctx.Err() != nil:
+		r.breaker.cancel()
+		return nil, err
+	case !retryable(err):
+		r.breaker.record(true)
+		return nil, err
+	default:
+		r.breaker.record(false)
+		log.Printf("Warning: RAG stream failed after %d attempts: %v", r.maxRetries+1, err)
+		result, err := r.fallback(ctx, query, k, err)
+		if err != nil {
+			return nil, err
+		}
+		return singleEvent(result), nil
+	}
+
+	// Pass events through, keeping the articles for the fallback once the stream completes
+	events := make(chan domain.RAGStreamEvent)
+	go func() {
+		defer close(events)
+		var articles []domain.RAGSource
+		for event := range upstream {
+			articles = append(articles, event.Sources...)
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return
+			}
+			if event.Err != nil {
+				return
+			}
+		}
+		r.cacheArticles(ctx, articles)
+	}()
+	return events, nil
+}
+
+func singleEvent(result *domain.RAGResult) <-chan domain.RAGStreamEvent {
+	events := make(chan domain.RAGStreamEvent, 1)
+	events <- domain.RAGStreamEvent{Sources: result.Results, Message: result.Message, Degraded: result.Degraded}
+	close(events)
+	return events
+}
+
+func (r *resilientRAG) retrieveWithRetries(ctx context.Context, query string, k int) (*domain.RAGResult, error) {
+	for attempt := 0; ; attempt++ {
+		result, err := r.primary.Retrieve(ctx, query, k)
+		if err == nil || attempt >= r.maxRetries || !retryable(err) || ctx.Err() != nil {
+			return result, err
+		}
+		delay := r.backoff(attempt)
+		log.Printf("Warning: RAG retrieval attempt %d failed, retrying in %s: %v", attempt+1, delay, err)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// backoff doubles the delay per attempt up to maxDelay, with full jitter so that replicas retrying
+// after the same outage do not hit the service in lockstep.
+func (r *resilientRAG) backoff(attempt int) time.Duration {
+	delay := r.baseDelay
+	for i := 0; i < attempt && delay < r.maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > r.maxDelay {
+		delay = r.maxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(delay))) + 1
+}
+
+// retryable reports whether a failed retrieval may succeed if repeated: transport errors, timeouts,
+// 5xx and 429 responses.
+func retryable(err error) bool {
+	var statusErr *ragStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.status >= http.StatusInternalServerError || statusErr.status == http.StatusTooManyRequests
+	}
+	return true
+}
+
+func (r *resilientRAG) fallback(ctx context.Context, query string, k int, cause error) (*domain.RAGResult, error) {
+	if r.cache == nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrRAGUnavailable, cause)
+	}
+	articles, err := r.cache.SearchArticles(ctx, query, k)
+	if err != nil {
+		log.Printf("Warning: Fallback law article search failed: %v", err)
+	}
+	if len(articles) == 0 {
+		return nil, fmt.Errorf("%w: %v", domain.ErrRAGUnavailable, cause)
+	}
+	RAGFallbackRetrievalsCounter.Inc()
+	return &domain.RAGResult{Results: articles, Degraded: true}, nil
+}
+
+// cacheArticles keeps retrieved articles for the fallback, off the response path.
+func (r *resilientRAG) cacheArticles(ctx context.Context, articles []domain.RAGSource) {
+	if r.cache == nil || len(articles) == 0 {
+		return
+	}
+	go func() {
+		cacheCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		if err := r.cache.SaveArticles(cacheCtx, articles); err != nil {
+			log.Printf("Warning: Failed to cache law articles: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,331 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/config"
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+)
+
+const (
+	speechTimeout       = 30 * time.Second
+	sttStreamRetryAfter = time.Minute // how long to transcribe whole recordings after the socket could not be opened
+)
+
+type sttClient struct {
+	apiBase   string
+	streamURL string // empty disables the socket
+	client    *http.Client
+
+	mu              sync.Mutex
+	streamDownUntil time.Time
+}
+
+// NewSTTClient transcribes over the AI service's speech-to-text socket, which sends partial transcripts while
+// audio arrives. While the socket is unavailable, audio is buffered and transcribed in one call once the
+// utterance ends.
+func NewSTTClient(cfg *config.Config) domain.SpeechToTextService {
+	return &sttClient{
+		apiBase:   cfg.STTApiBase,
+		streamURL: cfg.STTStreamUrl,
+		client:    &http.Client{Timeout: speechTimeout},
+	}
+}
+
+func (c *sttClient) Transcribe(ctx context.Context, audio []byte, contentType, language string) (*domain.Transcript, error) {
+	if contentType == "" {
+		contentType = "audio/mpeg"
+	}
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%s"`, audioFilename(contentType)))
+	h.Set("Content-Type", contentType)
+	part, err := writer.CreatePart(h)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create form file: %w", err)
+	}
+	if _, err := part.Write(audio); err != nil {
+		return nil, fmt.Errorf("failed to write form file: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close form: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.apiBase+url.PathEscape(language)+"?mode=file", &body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: REST call to STT API failed: %v", domain.ErrSpeechUnavailable, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("%w: STT API returned status %d: %s", domain.ErrSpeechUnavailable, resp.StatusCode, string(b))
+	}
+	var transcript struct {
+		Text     string  `json:"text"`
+		Duration float64 `json:"duration"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&transcript); err != nil {
+		return nil, fmt.Errorf("failed to parse response JSON: %w", err)
+	}
+	return &domain.Transcript{Text: transcript.Text, Duration: transcript.Duration}, nil
+}
+
+// audioFilename names an upload after its type, since the STT service picks the decoder by extension.
+func audioFilename(contentType string) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "audio/wav", "audio/wave", "audio/x-wav":
+		return "audio.wav"
+	case "audio/webm":
+		return "audio.webm"
+	case "audio/ogg":
+		return "audio.ogg"
+	case "audio/mp4", "audio/x-m4a":
+		return "audio.m4a"
+	}
+	return "audio.mp3"
+}
+
+func (c *sttClient) StartTranscription(ctx context.Context, contentType, language string) (domain.TranscriptionStream, error) {
+	if c.streamAvailable() {
+		stream, err := c.dial(ctx, contentType, language)
+		if err == nil {
+			return stream, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		c.markStreamDown(err)
+	}
+	return &bufferedTranscription{
+		ctx:         ctx,
+		client:      c,
+		contentType: contentType,
+		language:    language,
+		events:      make(chan domain.TranscriptEvent),
+		done:        make(chan struct{}),
+	}, nil
+}
+
+func (c *sttClient) dial(ctx context.Context, contentType, language string) (*socketTranscription, error) {
+	streamURL := c.streamURL + url.PathEscape(language)
+	if contentType != "" {
+		streamURL += "?format=" + url.QueryEscape(contentType)
+	}
+	wsConfig, err := websocket.NewConfig(streamURL, "http://localhost")
+	if err != nil {
+		return nil, fmt.Errorf("invalid speech-to-text socket URL: %w", err)
+	}
+	dialCtx, cancel := context.WithTimeout(ctx, speechTimeout)
+	defer cancel()
+	conn, err := wsConfig.DialContext(dialCtx)
+	if err != nil {
+		return nil, err
+	}
+	t := &socketTranscription{
+		conn:   conn,
+		events: make(chan domain.TranscriptEvent),
+		done:   make(chan struct{}),
+	}
+	// Closing the socket on cancellation unblocks the reader and any write
+	t.stop = context.AfterFunc(ctx, func() { conn.Close() })
+	go t.read(ctx)
+	return t, nil
+}
+
+func (c *sttClient) streamAvailable() bool {
+	if c.streamURL == "" {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return time.Now().After(c.streamDownUntil)
+}
+
+func (c *sttClient) markStreamDown(err error) {
+	log.Printf("Warning: Speech-to-text socket unavailable, transcribing whole recordings for %s: %v", sttStreamRetryAfter, err)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.streamDownUntil = time.Now().Add(sttStreamRetryAfter)
+}
+
+// socketTranscription sends audio as binary frames and an {"type": "end"} frame after the last one. The
+// service answers with {"text", "final", "duration"} frames, or {"error"} on failure.
+type socketTranscription struct {
+	conn      *websocket.Conn
+	events    chan domain.TranscriptEvent
+	done      chan struct{}
+	closeOnce sync.Once
+	stop      func() bool
+}
+
+type sttStreamMessage struct {
+	Text     string  `json:"text"`
+	Final    bool    `json:"final"`
+	Duration float64 `json:"duration"`
+	Error    string  `json:"error"`
+}
+
+func (t *socketTranscription) read(ctx context.Context) {
+	defer close(t.events)
+	for {
+		var msg sttStreamMessage
+		if err := websocket.JSON.Receive(t.conn, &msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				err = errors.New("socket closed before the final transcript")
+			}
+			t.send(ctx, domain.TranscriptEvent{Err: fmt.Errorf("%w: %v", domain.ErrSpeechUnavailable, err)})
+			return
+		}
+		if msg.Error != "" {
+			t.send(ctx, domain.TranscriptEvent{Err: fmt.Errorf("%w: %s", domain.ErrSpeechUnavailable, msg.Error)})
+			return
+		}
+		if !t.send(ctx, domain.TranscriptEvent{Text: msg.Text, Final: msg.Final, Duration: msg.Duration}) || msg.Final {
+			return
+		}
+	}
+}
+
+func (t *socketTranscription) send(ctx context.Context, event domain.TranscriptEvent) bool {
+	select {
+	case t.events <- event:
+		return true
+	case <-ctx.Done():
+	case <-t.done:
+	}
+	return false
+}
+
+func (t *socketTranscription) Write(audio []byte) error {
+	t.conn.SetWriteDeadline(time.Now().Add(speechTimeout))
+	if err := websocket.Message.Send(t.conn, audio); err != nil {
+		return fmt.Errorf("%w: failed to send audio: %v", domain.ErrSpeechUnavailable, err)
+	}
+	return nil
+}
+
+func (t *socketTranscription) CloseSend() error {
+	t.conn.SetWriteDeadline(time.Now().Add(speechTimeout))
+	if err := websocket.JSON.Send(t.conn, map[string]string{"type": "end"}); err != nil {
+		return fmt.Errorf("%w: failed to end audio: %v", domain.ErrSpeechUnavailable, err)
+	}
+	// The final transcript should follow promptly; don't wait forever for it
+	t.conn.SetReadDeadline(time.Now().Add(speechTimeout))
+	return nil
+}
+
+func (t *socketTranscription) Events() <-chan domain.TranscriptEvent {
+	return t.events
+}
+
+func (t *socketTranscription) Close() error {
+	t.closeOnce.Do(func() { close(t.done) })
+	t.stop()
+	return t.conn.Close()
+}
+
+// bufferedTranscription collects the utterance and transcribes it in one call. It has no partial transcripts.
+type bufferedTranscription struct {
+	ctx         context.Context
+	client      *sttClient
+	contentType string
+	language    string
+	audio       bytes.Buffer
+	events      chan domain.TranscriptEvent
+	done        chan struct{}
+	closeOnce   sync.Once
+}
+
+func (t *bufferedTranscription) Write(audio []byte) error {
+	t.audio.Write(audio)
+	return nil
+}
+
+func (t *bufferedTranscription) CloseSend() error {
+	go func() {
+		defer close(t.events)
+		event := domain.TranscriptEvent{Final: true}
+		transcript, err := t.client.Transcribe(t.ctx, t.audio.Bytes(), t.contentType, t.language)
+		if err != nil {
+			event.Err = err
+		} else {
+			event.Text, event.Duration = transcript.Text, transcript.Duration
+		}
+		select {
+		case t.events <- event:
+		case <-t.ctx.Done():
+		case <-t.done:
+		}
+	}()
+	return nil
+}
+
+func (t *bufferedTranscription) Events() <-chan domain.TranscriptEvent {
+	return t.events
+}
+
+func (t *bufferedTranscription) Close() error {
+	t.closeOnce.Do(func() { close(t.done) })
+	return nil
+}
+
+type ttsClient struct {
+	apiURL string
+	client *http.Client
+}
+
+// NewTTSClient speaks text through the AI service's text-to-speech endpoint.
+func NewTTSClient(cfg *config.Config) domain.TextToSpeechService {
+	return &ttsClient{
+		apiURL: cfg.TTSApiUrl,
+		client: &http.Client{Timeout: speechTimeout},
+	}
+}
+
+func (c *ttsClient) Synthesize(ctx context.Context, text, language string) (io.ReadCloser, error) {
+	body, err := json.Marshal(map[string]string{
+		"text":     text,
+		"language": language,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.apiURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: REST call to TTS API failed: %v", domain.ErrSpeechUnavailable, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("%w: TTS API returned status %d: %s", domain.ErrSpeechUnavailable, resp.StatusCode, string(b))
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,226 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/config"
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+)
+
+const (
+	translateTimeout          = 15 * time.Second
+	maxIdleTranslateConns     = 4
+	translateStreamRetryAfter = time.Minute // how long to use plain HTTP after the socket could not be opened
+)
+
+// The AI service's /translate-stream socket answers with one of these instead of a translation on failure
+var translateStreamErrorPrefixes = []string{"Error:", "An error occurred:"}
+
+var errTranslateStreamUnavailable = errors.New("translation socket unavailable")
+
+type translationClient struct {
+	httpURL   string
+	streamURL string // empty disables the socket
+	client    *http.Client
+	cache     domain.TranslationCache // nil disables caching
+	cacheTTL  time.Duration
+	idle      chan *websocket.Conn
+
+	mu              sync.Mutex
+	streamDownUntil time.Time
+}
+
+// NewTranslationClient translates over the AI service's /translate-stream WebSocket, keeping a few
+// connections open between calls, and falls back to POST /translate while the socket is unavailable.
+// Translations are cached when cache is not nil.
+func NewTranslationClient(cfg *config.Config, cache domain.TranslationCache) domain.TranslationService {
+	return &translationClient{
+		httpURL:   cfg.TranslateApiUrl,
+		streamURL: cfg.TranslateStreamUrl,
+		client:    &http.Client{Timeout: translateTimeout},
+		cache:     cache,
+		cacheTTL:  cfg.TranslationCacheTTL,
+		idle:      make(chan *websocket.Conn, maxIdleTranslateConns),
+	}
+}
+
+func (t *translationClient) Close() error {
+	for {
+		select {
+		case conn := <-t.idle:
+			conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+func (t *translationClient) Translate(ctx context.Context, text, targetLang string) (string, error) {
+	if strings.TrimSpace(text) == "" {
+		return text, nil
+	}
+	if t.cache != nil {
+		cached, err := t.cache.Get(ctx, text, targetLang)
+		if err != nil {
+			log.Printf("Warning: Translation cache lookup failed: %v", err)
+		} else if cached != "" {
+			return cached, nil
+		}
+	}
+
+	translated, err := t.translateStream(ctx, text, targetLang)
+	if errors.Is(err, errTranslateStreamUnavailable) {
+		translated, err = t.translateHTTP(ctx, text, targetLang)
+	}
+	if err != nil {
+		return "", err
+	}
+	if t.cache != nil && translated != "" {
+		if err := t.cache.Put(ctx, text, targetLang, translated, t.cacheTTL); err != nil {
+			log.Printf("Warning: Failed to cache translation: %v", err)
+		}
+	}
+	return translated, nil
+}
+
+// translateStream sends one request over the socket. It returns errTranslateStreamUnavailable when the
+// socket cannot be used, so the caller can fall back to HTTP.
+func (t *translationClient) translateStream(ctx context.Context, text, targetLang string) (string, error) {
+	if !t.streamAvailable() {
+		return "", errTranslateStreamUnavailable
+	}
+	conn, pooled, err := t.conn(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		t.markStreamDown(err)
+		return "", errTranslateStreamUnavailable
+	}
+
+	// Closing the socket on cancellation unblocks the exchange below
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	reply, err := exchange(conn, text, targetLang)
+	if !stop() {
+		return "", ctx.Err()
+	}
+	if err != nil {
+		conn.Close()
+		// An idle connection may just have been dropped by the server; only a fresh one says the socket is down
+		if !pooled {
+			t.markStreamDown(err)
+		}
+		return "", errTranslateStreamUnavailable
+	}
+	t.release(conn)
+
+	for _, prefix := range translateStreamErrorPrefixes {
+		if strings.HasPrefix(reply, prefix) {
+			return "", fmt.Errorf("translation failed: %s", reply)
+		}
+	}
+	return reply, nil
+}
+
+func exchange(conn *websocket.Conn, text, targetLang string) (string, error) {
+	if err := conn.SetDeadline(time.Now().Add(translateTimeout)); err != nil {
+		return "", err
+	}
+	if err := websocket.JSON.Send(conn, map[string]string{"text": text, "target_lang": targetLang}); err != nil {
+		return "", fmt.Errorf("failed to send translation request: %w", err)
+	}
+	var reply string
+	if err := websocket.Message.Receive(conn, &reply); err != nil {
+		return "", fmt.Errorf("failed to read translation: %w", err)
+	}
+	return reply, nil
+}
+
+// conn takes an idle connection or dials a new one; pooled reports which.
+func (t *translationClient) conn(ctx context.Context) (*websocket.Conn, bool, error) {
+	select {
+	case conn := <-t.idle:
+		return conn, true, nil
+	default:
+	}
+	wsConfig, err := websocket.NewConfig(t.streamURL, "http://localhost")
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid translation socket URL: %w", err)
+	}
+	dialCtx, cancel := context.WithTimeout(ctx, translateTimeout)
+	defer cancel()
+	conn, err := wsConfig.DialContext(dialCtx)
+	if err != nil {
+		return nil, false, err
+	}
+	return conn, false, nil
+}
+
+func (t *translationClient) release(conn *websocket.Conn) {
+	select {
+	case t.idle <- conn:
+	default:
+		conn.Close()
+	}
+}
+
+func (t *translationClient) streamAvailable() bool {
+	if t.streamURL == "" {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return time.Now().After(t.streamDownUntil)
+}
+
+func (t *translationClient) markStreamDown(err error) {
+	log.Printf("Warning: Translation socket unavailable, using HTTP for %s: %v", translateStreamRetryAfter, err)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.streamDownUntil = time.Now().Add(translateStreamRetryAfter)
+}
+
+func (t *translationClient) translateHTTP(ctx context.Context, text, targetLang string) (string, error) {
+	body, err := json.Marshal(map[string]string{
+		"text":        text,
+		"target_lang": targetLang,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.httpURL, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("REST call to translation API failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(b))
+	}
+
+	var parsed struct {
+		TranslatedText string `json:"translated_text"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return "", fmt.Errorf("failed to parse response JSON: %w", err)
+	}
+	return parsed.TranslatedText, nil
+}
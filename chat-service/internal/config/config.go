@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -15,18 +16,59 @@ type Config struct {
 	MongoURI                string
 	MongoDBName             string
 	RAGServiceAddr          string
+	RAGTimeout              time.Duration // per attempt
+	RAGMaxRetries           int
+	RAGRetryBaseDelay       time.Duration // first retry delay, doubled per attempt with full jitter
+	RAGRetryMaxDelay        time.Duration
+	RAGBreakerThreshold     int           // consecutive failed retrievals that open the circuit breaker
+	RAGBreakerCooldown      time.Duration // how long the breaker stays open before a half-open probe
+	RAGFallbackEnabled      bool          // keyword search over cached law articles while the RAG service is down
+	LLMProvider             string // gemini, openai or fake
 	GoogleAPIKey            string
 	GeminiModel             string
+	OpenAIBaseURL           string // any OpenAI-compatible endpoint, e.g. https://api.openai.com/v1
+	OpenAIAPIKey            string
+	OpenAIModel             string
+	FakeLLMScriptPath       string // optional JSON file with scripted responses for the fake provider
 	LLMPromptRefine         string
 	LLMPromptAnswer         string
 	LLMPromptNoResult       string
 	LLMPromptConverter      string
+	LLMPromptQuizGeneration string
+	LLMPromptSummarize      string
+	LLMPromptTitle          string
+	QueryRewriteTurns       int // previous turns the refine prompt sees to resolve follow-ups; 0 rewrites each query alone
+	MaxSubQueries           int // queries a question may be split into for retrieval; 1 disables splitting
+	PromptRefreshInterval   time.Duration // how often published prompt versions are reloaded from MongoDB
+	GlossaryRefreshInterval time.Duration // how often the legal glossary is reloaded from MongoDB
+	GuardrailsPath          string        // optional JSON file with guardrail rules per language, replacing the built-in ones
 	SessionTTLSeconds       int
 	ChatHistorySyncInterval time.Duration
+	ChatHistorySyncLockTTL  time.Duration // how long one replica may hold the sync lock without refreshing it
+	ChatHistorySyncMaxRetry time.Duration // upper bound for the per-session retry backoff
 	STTApiBase              string // e.g. http://127.0.0.1:8000/speech-to-text/
+	STTStreamUrl            string // e.g. ws://127.0.0.1:8000/speech-to-text-stream/; the language is appended, empty uses STTApiBase only
 	TranslateApiUrl         string // e.g. http://127.0.0.1:8000/translate
+	TranslateStreamUrl      string        // e.g. ws://127.0.0.1:8000/translate-stream; empty uses TranslateApiUrl only
+	TranslationCacheTTL     time.Duration // how long translated sentences stay in Redis
+	StreamBufferTTL         time.Duration // how long query stream events are kept for clients that reconnect
+	StreamResumeGrace       time.Duration // how long an answer keeps generating after its client disconnected
+	StreamReadPoolSize      int           // Redis connections reserved for resumed streams waiting on new events
 	TTSApiUrl               string // e.g. http://127.0.0.1:8000/text-to-speech
+	EmbeddingApiUrl         string // optional; enables similarity matching in the response cache
+	ResponseCacheEnabled    bool
+	ResponseCacheTTL        time.Duration
+	ResponseCacheSimilarity float64 // minimum cosine similarity for a similar-question cache hit
 	AccessSecret 			string
+	TrustedProxies          []string      // IPs or CIDRs of the proxies allowed to set X-Forwarded-For; empty uses the peer address
+	AllowedOrigins          []string      // browser origins allowed by CORS and on the WebSockets
+	GatewaySecret           string        // shared HMAC secret for signed identity headers; empty disables gateway mode
+	GatewayMaxClockSkew     time.Duration // how old a signed identity header may be
+	ExportLatinFontPath     string        // TTF used for Latin text in exported briefs
+	ExportEthiopicFontPath  string        // TTF with Ge'ez glyphs, embedded in Amharic briefs
+	ExportEthiopicFontName  string        // family name of the Ethiopic font, as DOCX readers look it up
+	ExportLinkSecret        string        // HMAC secret for signed export download links; empty disables them
+	ExportLinkTTL           time.Duration // lifetime of a signed export download link
 }
 
 // New loads configuration from environment variables.
@@ -39,19 +81,60 @@ func New() (*Config, error) {
 		RedisAddr:               getEnv("REDIS_ADDR", "localhost:6379"),
 		MongoURI:                getEnv("DB_ENDPOINT", "mongodb://localhost:27017"),
 		MongoDBName:             getEnv("DB_NAME", "chatdb"),
+		LLMProvider:             getEnv("LLM_PROVIDER", "gemini"),
 		GoogleAPIKey:            getEnv("GOOGLE_API_KEY", ""),
 		GeminiModel:             getEnv("GEMINI_MODEL", "gemini-pro"),
+		OpenAIBaseURL:           getEnv("OPENAI_BASE_URL", "https://api.openai.com/v1"),
+		OpenAIAPIKey:            getEnv("OPENAI_API_KEY", ""),
+		OpenAIModel:             getEnv("OPENAI_MODEL", "gpt-4o-mini"),
+		FakeLLMScriptPath:       getEnv("FAKE_LLM_SCRIPT_PATH", ""),
 		RAGServiceAddr:          getEnv("RAG_SERVICE_ADDR", "localhost:50051"), // gRPC address
-		LLMPromptRefine:         getEnv("LLM_PROMPT_REFINE", "Refine the following query for a RAG system, making it concise and clear: {{.Query}}"),
-		LLMPromptAnswer:         getEnv("LLM_PROMPT_ANSWER", "Based on the provided context and conversation history, answer the user's question. Adhere strictly to word limits and sources. Do not hallucinate. Context: {{.RAGResults}} History: {{.ChatHistory}} Question: {{.Query}} MaxWords: {{.MaxWords}} MaxRefs: {{.MaxRefs}}"),
+		RAGTimeout:              time.Second * time.Duration(getEnvAsInt("RAG_TIMEOUT_SECONDS", 15)),
+		RAGMaxRetries:           getEnvAsInt("RAG_MAX_RETRIES", 2),
+		RAGRetryBaseDelay:       time.Millisecond * time.Duration(getEnvAsInt("RAG_RETRY_BASE_DELAY_MS", 200)),
+		RAGRetryMaxDelay:        time.Millisecond * time.Duration(getEnvAsInt("RAG_RETRY_MAX_DELAY_MS", 2000)),
+		RAGBreakerThreshold:     getEnvAsInt("RAG_BREAKER_THRESHOLD", 5),
+		RAGBreakerCooldown:      time.Second * time.Duration(getEnvAsInt("RAG_BREAKER_COOLDOWN_SECONDS", 30)),
+		RAGFallbackEnabled:      getEnvAsBool("RAG_FALLBACK_ENABLED", true),
+		LLMPromptRefine:         getEnv("LLM_PROMPT_REFINE", "Rewrite the user's latest question as a standalone search query for a RAG system over Ethiopian law, making it concise and clear. {{if .History}}Use the conversation so far to resolve pronouns and fill in what the question leaves out. Conversation: {{.History}} {{end}}{{if gt .MaxQueries 1}}If the question asks about several distinct legal issues, write one query per issue, up to {{.MaxQueries}}, one per line. {{end}}Reply with the search query text only, without numbering or explanations. Question: {{.Query}}"),
+		LLMPromptAnswer:         getEnv("LLM_PROMPT_ANSWER", "Based on the provided context and conversation history, answer the user's question. Cite the numbered sources inline by their bracketed number, e.g. [1], right after the sentence they support, and never cite a number that is not listed in the context. Adhere strictly to word limits and sources. Do not hallucinate. Context: {{.RAGResults}} History: {{.ChatHistory}} Question: {{.Query}} MaxWords: {{.MaxWords}} MaxRefs: {{.MaxRefs}}"),
 		LLMPromptNoResult:       getEnv("LLM_PROMPT_NO_RESULT", "I couldn't find information related to your question. Here are some refined questions you might try, separated by newlines: {{.Query}}"),
-		LLMPromptConverter:      getEnv("LLM_PROMPT_CONVERTER", "Translate the following text to English, maintaining its original meaning and context. Text: {{.Text}}"),
+		LLMPromptConverter:      getEnv("LLM_PROMPT_CONVERTER", "Translate the following text from {{.From}} to {{.To}}, maintaining its original meaning and context. {{if .Glossary}}Copy the placeholders in double square brackets, such as [[1]], unchanged; they stand for these legal terms: {{.Glossary}}. {{end}}Reply with the translation only. Text: {{.Text}}"),
+		LLMPromptQuizGeneration: getEnv("LLM_PROMPT_QUIZ_GENERATION", "You write multiple-choice questions that test understanding of Ethiopian law. Using only the legal passages below, write {{.NumQuestions}} distinct questions. Each question has exactly four options labelled A, B, C and D, exactly one of which is correct, and no two options may say the same thing. Reply with JSON only, no prose or code fences, in this exact schema: {\"questions\": [{\"text\": \"...\", \"options\": {\"A\": \"...\", \"B\": \"...\", \"C\": \"...\", \"D\": \"...\"}, \"correct_option\": \"A\", \"article_number\": \"...\"}]}. Passages: {{.Passages}}"),
+		LLMPromptSummarize:      getEnv("LLM_PROMPT_SUMMARIZE", "You maintain a running summary of a legal consultation. Update the existing summary with the new conversation turns, keeping every fact the user stated about their situation (names, dates, amounts, places, relationships), the legal questions asked and the conclusions and articles given. Drop greetings and repetition. Reply with the updated summary only, in at most {{.MaxWords}} words. Existing summary: {{.Summary}} New turns: {{.Turns}}"),
+		LLMPromptTitle:          getEnv("LLM_PROMPT_TITLE", "Write a short title of at most 6 words for a legal consultation that starts with the exchange below, in the same language as the question. Reply with the title only, without quotes. Question: {{.Query}} Answer: {{.Answer}}"),
+		QueryRewriteTurns:       getEnvAsInt("QUERY_REWRITE_TURNS", 3),
+		MaxSubQueries:           getEnvAsInt("MAX_SUB_QUERIES", 3),
+		PromptRefreshInterval:   time.Second * time.Duration(getEnvAsInt("PROMPT_REFRESH_SECONDS", 30)),
+		GlossaryRefreshInterval: time.Second * time.Duration(getEnvAsInt("GLOSSARY_REFRESH_SECONDS", 60)),
+		GuardrailsPath:          getEnv("GUARDRAILS_PATH", ""),
 		SessionTTLSeconds:       getEnvAsInt("SESSION_TTL_SECONDS", 7200),                                            // 2 hours
 		ChatHistorySyncInterval: time.Second * time.Duration(getEnvAsInt("CHAT_HISTORY_SYNC_INTERVAL_SECONDS", 300)), // 5 minutes
+		ChatHistorySyncLockTTL:  time.Second * time.Duration(getEnvAsInt("CHAT_HISTORY_SYNC_LOCK_TTL_SECONDS", 60)),
+		ChatHistorySyncMaxRetry: time.Second * time.Duration(getEnvAsInt("CHAT_HISTORY_SYNC_MAX_RETRY_SECONDS", 1800)), // 30 minutes
 		STTApiBase:              getEnv("STT_API_BASE", "http://127.0.0.1:8000/speech-to-text/"),
+		STTStreamUrl:            getEnv("STT_STREAM_URL", "ws://127.0.0.1:8000/speech-to-text-stream/"),
 		TranslateApiUrl:         getEnv("TRANSLATE_API_URL", "http://127.0.0.1:8000/translate"),
+		TranslateStreamUrl:      getEnv("TRANSLATE_STREAM_URL", "ws://127.0.0.1:8000/translate-stream"),
+		TranslationCacheTTL:     time.Second * time.Duration(getEnvAsInt("TRANSLATION_CACHE_TTL_SECONDS", 2592000)), // 30 days
+		StreamBufferTTL:         time.Second * time.Duration(getEnvAsInt("STREAM_BUFFER_TTL_SECONDS", 300)),
+		StreamResumeGrace:       time.Second * time.Duration(getEnvAsInt("STREAM_RESUME_GRACE_SECONDS", 60)),
+		StreamReadPoolSize:      getEnvAsInt("STREAM_READ_POOL_SIZE", 50),
 		TTSApiUrl:               getEnv("TTS_API_URL", "http://127.0.0.1:8000/text-to-speech"),
+		EmbeddingApiUrl:         getEnv("EMBEDDING_API_URL", ""),
+		ResponseCacheEnabled:    getEnvAsBool("RESPONSE_CACHE_ENABLED", true),
+		ResponseCacheTTL:        time.Second * time.Duration(getEnvAsInt("RESPONSE_CACHE_TTL_SECONDS", 86400)), // 24 hours
+		ResponseCacheSimilarity: getEnvAsFloat("RESPONSE_CACHE_SIMILARITY", 0.95),
 		AccessSecret:			 getEnv("ACCESS_TOKEN_SECRET", "your_access_token_secret"),
+		TrustedProxies:          getEnvAsList("TRUSTED_PROXIES", nil),
+		AllowedOrigins:          getEnvAsList("ALLOWED_ORIGINS", []string{"http://localhost:3000", "https://lawgen-frontend-wine.vercel.app"}),
+		GatewaySecret:           getEnv("GATEWAY_IDENTITY_SECRET", ""),
+		GatewayMaxClockSkew:     time.Second * time.Duration(getEnvAsInt("GATEWAY_MAX_CLOCK_SKEW_SECONDS", 300)),
+		ExportLatinFontPath:     getEnv("EXPORT_LATIN_FONT_PATH", "/usr/share/fonts/dejavu/DejaVuSans.ttf"),
+		ExportEthiopicFontPath:  getEnv("EXPORT_ETHIOPIC_FONT_PATH", "/usr/share/fonts/noto/NotoSansEthiopic-Regular.ttf"),
+		ExportEthiopicFontName:  getEnv("EXPORT_ETHIOPIC_FONT_NAME", "Noto Sans Ethiopic"),
+		ExportLinkSecret:        getEnv("EXPORT_LINK_SECRET", ""),
+		ExportLinkTTL:           time.Second * time.Duration(getEnvAsInt("EXPORT_LINK_TTL_SECONDS", 86400)), // 24 hours
 	}, nil
 
 }
@@ -72,3 +155,37 @@ func getEnvAsInt(key string, fallback int) int {
 	}
 	return fallback
 }
+
+// getEnvAsBool returns the value of the environment variable as a bool, or fallback if not set / invalid.
+func getEnvAsBool(key string, fallback bool) bool {
+	if valueStr, exists := os.LookupEnv(key); exists {
+		if value, err := strconv.ParseBool(valueStr); err == nil {
+			return value
+		}
+	}
+	return fallback
+}
+
+// getEnvAsFloat returns the value of the environment variable as a float64, or fallback if not set / invalid.
+func getEnvAsFloat(key string, fallback float64) float64 {
+	if valueStr, exists := os.LookupEnv(key); exists {
+		if value, err := strconv.ParseFloat(valueStr, 64); err == nil {
+			return value
+		}
+	}
+	return fallback
+}
+
+// getEnvAsList returns the comma-separated values of the environment variable, or defaultValue if it has none.
+func getEnvAsList(key string, defaultValue []string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	if len(values) == 0 {
+		return defaultValue
+	}
+	return values
+}
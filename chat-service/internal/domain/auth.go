@@ -0,0 +1,45 @@
+package domain
+
+const (
+	RoleAdmin   = "admin"
+	RoleUser    = "user"
+	RoleVisitor = "visitor"
+)
+
+// PrincipalContextKey is the request context key the auth middleware stores the Principal under.
+const PrincipalContextKey = "principal"
+
+// Principal is the verified identity of the caller. Visitors have an empty UserID.
+type Principal struct {
+	UserID string
+	PlanID string
+	Role   string
+	Age    int
+	Gender string
+}
+
+// VisitorPrincipal is the identity of an unauthenticated caller.
+func VisitorPrincipal() Principal {
+	return Principal{PlanID: string(TierGuest), Role: RoleVisitor}
+}
+
+func (p Principal) IsVisitor() bool {
+	return p.UserID == ""
+}
+
+func (p Principal) IsAdmin() bool {
+	return p.UserID != "" && p.Role == RoleAdmin
+}
+
+// PrincipalFrom returns the principal stored in a request context such as *gin.Context,
+// or a visitor principal if the auth middleware did not set one.
+func PrincipalFrom(c interface {
+	Get(key string) (any, bool)
+}) Principal {
+	if v, ok := c.Get(PrincipalContextKey); ok {
+		if p, ok := v.(Principal); ok {
+			return p
+		}
+	}
+	return VisitorPrincipal()
+}
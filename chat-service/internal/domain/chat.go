@@ -2,6 +2,7 @@ package domain
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -24,23 +25,30 @@ type UserParams struct {
 	MaxReferences  int
 	ContextWindow  int
 	SaveHistory    bool
+	// Word budget for the rolling summary of turns that fell out of the context window; 0 disables summarization
+	MaxSummaryWords int
+	// Usage quotas; 0 means unlimited
+	DailyQueries        int64
+	MonthlyQueries      int64
+	DailyVoiceMinutes   int64
+	MonthlyVoiceMinutes int64
 }
 
 // GetUserParamsFromPlanID returns subscription-specific parameters based on the provided plan ID.
 func GetUserParamsFromPlanID(planID string) UserParams {
 	switch SubscriptionTier(planID) {
 	case TierFree:
-		return UserParams{MaxAnswerWords: 500, MaxReferences: 10, ContextWindow: 2, SaveHistory: true}
+		return UserParams{MaxAnswerWords: 500, MaxReferences: 10, ContextWindow: 2, SaveHistory: true, MaxSummaryWords: 100, DailyQueries: 20, MonthlyQueries: 300, DailyVoiceMinutes: 5, MonthlyVoiceMinutes: 15}
 	case TierBasic:
-		return UserParams{MaxAnswerWords: 500, MaxReferences: 10, ContextWindow: 3, SaveHistory: true}
+		return UserParams{MaxAnswerWords: 500, MaxReferences: 10, ContextWindow: 3, SaveHistory: true, MaxSummaryWords: 150, DailyQueries: 100, MonthlyQueries: 2000, DailyVoiceMinutes: 15, MonthlyVoiceMinutes: 60}
 	case TierPro:
-		return UserParams{MaxAnswerWords: 500, MaxReferences: 10, ContextWindow: 5, SaveHistory: true}
+		return UserParams{MaxAnswerWords: 500, MaxReferences: 10, ContextWindow: 5, SaveHistory: true, MaxSummaryWords: 250, DailyQueries: 500, MonthlyQueries: 10000, DailyVoiceMinutes: 60, MonthlyVoiceMinutes: 300}
 	case TierEnterprise:
-		return UserParams{MaxAnswerWords: 500, MaxReferences: 15, ContextWindow: 5, SaveHistory: true}
+		return UserParams{MaxAnswerWords: 500, MaxReferences: 15, ContextWindow: 5, SaveHistory: true, MaxSummaryWords: 400} // Unlimited usage
 	case TierGuest: // Visitor
-		return UserParams{MaxAnswerWords: 500, MaxReferences: 10, ContextWindow: 1, SaveHistory: true}
+		return UserParams{MaxAnswerWords: 500, MaxReferences: 10, ContextWindow: 1, SaveHistory: true, DailyQueries: 10, MonthlyQueries: 100, DailyVoiceMinutes: 2, MonthlyVoiceMinutes: 5}
 	default:
-		return UserParams{MaxAnswerWords: 500, MaxReferences: 10, ContextWindow: 1, SaveHistory: true} // Default guest-like
+		return UserParams{MaxAnswerWords: 500, MaxReferences: 10, ContextWindow: 1, SaveHistory: true, DailyQueries: 10, MonthlyQueries: 100, DailyVoiceMinutes: 2, MonthlyVoiceMinutes: 5} // Default guest-like
 	}
 }
 
@@ -55,6 +63,28 @@ type Session struct {
 	LastActiveAt time.Time          `bson:"lastActiveAt" json:"last_active_at"`
 	IsGuest      bool               `bson:"isGuest" json:"is_guest"`
 	Title        string             `bson:"title" json:"title"`
+	Pinned       bool               `bson:"pinned" json:"pinned"`
+	Archived     bool               `bson:"archived" json:"archived"` // archived sessions are listed separately
+}
+
+// SessionUpdate holds the user-editable session fields; nil fields are left unchanged.
+type SessionUpdate struct {
+	Title    *string
+	Pinned   *bool
+	Archived *bool
+}
+
+var (
+	ErrSessionNotFound      = errors.New("session not found")
+	ErrSessionAccessDenied  = errors.New("you do not have access to this session")
+	ErrInvalidSessionUpdate = errors.New("invalid session update")
+)
+
+// ChatSearchResult is a chat entry matching a history search, with the session it belongs to.
+type ChatSearchResult struct {
+	Entry        ChatEntry `json:"entry"`
+	SessionTitle string    `json:"session_title"`
+	Score        float64   `json:"score"`
 }
 
 // --- Chat Entry Model ---
@@ -64,18 +94,39 @@ type ChatMessageType string
 const (
 	MessageTypeUser    ChatMessageType = "user_query"
 	MessageTypeLLM     ChatMessageType = "llm_response"
-	MessageTypeSummary ChatMessageType = "summary"
+	MessageTypeSummary ChatMessageType = "summary" // rolling summary of turns older than the context window, stored apart from the history
 )
 
 type ChatEntry struct {
-	ID         string             `bson:"-" json:"id"`
-	MongoID    primitive.ObjectID `bson:"_id,omitempty" json:"-"`
-	SessionID  string             `bson:"sessionId" json:"session_id"`
-	Type       ChatMessageType    `bson:"type" json:"type"`
-	Content    string             `bson:"content" json:"content"`
-	Sources    []RAGSource        `bson:"sources,omitempty" json:"sources,omitempty"`
-	CreatedAt  time.Time          `bson:"createdAt" json:"created_at"`
-	SyncedToDB bool               `bson:"syncedToDB,omitempty" json:"-"`
+	ID        string             `bson:"-" json:"id"`
+	MongoID   primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	SessionID string             `bson:"sessionId" json:"session_id"`
+	Type      ChatMessageType    `bson:"type" json:"type"`
+	Content   string             `bson:"content" json:"content"`
+	Sources   []RAGSource        `bson:"sources,omitempty" json:"sources,omitempty"`
+	Citations []Citation         `bson:"citations,omitempty" json:"citations,omitempty"`
+	// For summaries: CreatedAt of the newest entry rolled into the summary
+	SummarizedUntil *time.Time      `bson:"summarizedUntil,omitempty" json:"summarized_until,omitempty"`
+	Feedback        *AnswerFeedback `bson:"feedback,omitempty" json:"feedback,omitempty"`
+	Disclaimer      string          `bson:"disclaimer,omitempty" json:"disclaimer,omitempty"`
+	// Set on canned answers to emergency and out-of-scope queries
+	Guardrail GuardrailVerdict `bson:"guardrail,omitempty" json:"guardrail,omitempty"`
+	// For answers: the retrieval query, the queries the question was rewritten into when there are several,
+	// and the prompt the answer was generated from, kept for debugging low-rated answers. All are stripped by
+	// WithoutTrace before entries are shown to users.
+	RefinedQuery     string   `bson:"refinedQuery,omitempty" json:"refined_query,omitempty"`
+	RewrittenQueries []string `bson:"rewrittenQueries,omitempty" json:"rewritten_queries,omitempty"`
+	Prompt           string   `bson:"prompt,omitempty" json:"prompt,omitempty"`
+	// The version of each prompt used for the entry, keyed by prompt name, for comparing feedback across versions
+	PromptVersions map[string]int `bson:"promptVersions,omitempty" json:"prompt_versions,omitempty"`
+	CreatedAt      time.Time      `bson:"createdAt" json:"created_at"`
+	SyncedToDB     bool           `bson:"syncedToDB,omitempty" json:"-"`
+}
+
+// WithoutTrace returns a copy of the entry without the internal debugging fields.
+func (e ChatEntry) WithoutTrace() ChatEntry {
+	e.RefinedQuery, e.RewrittenQueries, e.Prompt, e.PromptVersions = "", nil, "", nil
+	return e
 }
 
 type RAGSource struct {
@@ -85,10 +136,20 @@ type RAGSource struct {
 	Topics        []string `json:"topics,omitempty"`
 }
 
+// Citation links an inline marker such as [2] in an answer to the source it refers to.
+// Marker n always refers to the n-th entry of the answer's Sources.
+type Citation struct {
+	Marker        int      `bson:"marker" json:"marker"`
+	Source        string   `bson:"source" json:"source"`
+	ArticleNumber string   `bson:"articleNumber" json:"article_number"`
+	Sentences     []string `bson:"sentences,omitempty" json:"sentences,omitempty"` // answer sentences backed by this source
+}
+
 type RAGResult struct {
 	Results    []RAGSource `json:"results"`
 	Message    string      `json:"message"`
 	References []string    `json:"references,omitempty"`
+	Degraded   bool        `json:"degraded,omitempty"` // served by the fallback retriever while the RAG service is down
 }
 
 // --- Repository Interfaces ---
@@ -97,12 +158,18 @@ type SessionRepository interface {
 	GetSessionByID(ctx context.Context, id string) (*Session, error)
 	CreateSession(ctx context.Context, session *Session) error
 	UpdateSession(ctx context.Context, session *Session) error
-	GetSessionsByUserID(ctx context.Context, userID string, page, limit int) ([]*Session, int, error)
+	// GetSessionsByUserID lists a user's archived or active sessions, pinned first and then by last activity
+	GetSessionsByUserID(ctx context.Context, userID string, archived bool, page, limit int) ([]*Session, int, error)
+	GetSessionIDsByUserID(ctx context.Context, userID string) ([]string, error)
+	DeleteSession(ctx context.Context, id string) error
 	SetSessionTTL(ctx context.Context, sessionID string, ttl time.Duration) error
 	// For sync job to find active user sessions
 	GetUserSessionIDs(ctx context.Context) ([]string, error)
 	AddUserSessionID(ctx context.Context, sessionID string) error
 	RemoveUserSessionID(ctx context.Context, sessionID string) error
+	// MarkSessionDeleted leaves a tombstone so background writes still in flight can tell the session is gone
+	MarkSessionDeleted(ctx context.Context, sessionID string) error
+	IsSessionDeleted(ctx context.Context, sessionID string) (bool, error)
 }
 
 type ChatRepository interface {
@@ -111,6 +178,27 @@ type ChatRepository interface {
 	BulkSaveChatEntries(ctx context.Context, entries []ChatEntry) error
 	GetUnsyncedChatEntries(ctx context.Context, sessionID string) ([]ChatEntry, error)
 	MarkChatEntriesAsSynced(ctx context.Context, sessionID string, entryMongoIDs []string) error
+	// GetSummary returns the session's summary entry, or nil if it has none yet
+	GetSummary(ctx context.Context, sessionID string) (*ChatEntry, error)
+	SaveSummary(ctx context.Context, summary *ChatEntry) error
+	// DeleteChatHistory removes every entry and the summary of a session
+	DeleteChatHistory(ctx context.Context, sessionID string) error
+	// SearchChatEntries runs a full-text search over the entries of the given sessions, best matches first
+	SearchChatEntries(ctx context.Context, sessionIDs []string, query string, limit int) ([]ChatSearchResult, error)
+	// SetFeedback stores a rating on an llm_response entry; it returns ErrChatEntryNotFound if the session has no such answer
+	SetFeedback(ctx context.Context, sessionID, entryID string, feedback *AnswerFeedback) error
+	GetFeedbackReport(ctx context.Context, filter FeedbackReportFilter) (*FeedbackReport, error)
+	// GetPromptFeedbackStats counts the answers created in [from, to) and their ratings per version of a prompt
+	GetPromptFeedbackStats(ctx context.Context, prompt string, from, to time.Time) ([]PromptVersionStats, error)
+}
+
+// DistributedLock coordinates background jobs across service replicas.
+// Acquire returns a token that must be presented to Refresh and Release so a
+// replica can never release a lock that has since been taken over by another.
+type DistributedLock interface {
+	Acquire(ctx context.Context, key string, ttl time.Duration) (token string, acquired bool, err error)
+	Refresh(ctx context.Context, key, token string, ttl time.Duration) (bool, error)
+	Release(ctx context.Context, key, token string) error
 }
 
 // --- External Service Interfaces ---
@@ -121,10 +209,20 @@ type LLMStreamResponse struct {
 	Done  bool
 }
 
+// Errors that LLM providers map their failures into, so callers can react
+// without knowing which backend is configured.
+var (
+	ErrLLMRateLimited    = errors.New("llm provider rate limit exceeded")
+	ErrLLMSafetyBlocked  = errors.New("llm response blocked by safety filters")
+	ErrLLMContextTooLong = errors.New("llm prompt exceeds the model context window")
+	ErrLLMUnavailable    = errors.New("llm provider unavailable")
+)
+
 type LLMService interface {
 	StreamGenerate(ctx context.Context, prompt string, history []ChatEntry, maxWords int) (<-chan LLMStreamResponse, error)
 	Generate(ctx context.Context, prompt string, history []ChatEntry) (string, error)
-	Translate(ctx context.Context, text, targetLang string) (string, error)
+	// Translate translates text with prompt, the rendered converter prompt asking for it
+	Translate(ctx context.Context, prompt, text, targetLang string) (string, error)
 	Close() error
 }
 
@@ -132,3 +230,27 @@ type RAGService interface {
 	Retrieve(ctx context.Context, query string, k int) (*RAGResult, error)
 	Close() error
 }
+
+// RAGStreamEvent is one increment of a streamed retrieval. Err is always the last event of a stream.
+type RAGStreamEvent struct {
+	Sources  []RAGSource
+	Message  string
+	Degraded bool
+	Err      error
+}
+
+// StreamingRAGService is a RAGService that can hand out results as the AI service produces them.
+type StreamingRAGService interface {
+	RAGService
+	RetrieveStream(ctx context.Context, query string, k int) (<-chan RAGStreamEvent, error)
+}
+
+// ErrRAGUnavailable is returned when the RAG service cannot be reached and no fallback results are available.
+var ErrRAGUnavailable = errors.New("rag service unavailable")
+
+// LawArticleCache keeps the law articles the RAG service has returned, so they can still be found by
+// keyword while it is down.
+type LawArticleCache interface {
+	SaveArticles(ctx context.Context, articles []RAGSource) error
+	SearchArticles(ctx context.Context, query string, k int) ([]RAGSource, error)
+}
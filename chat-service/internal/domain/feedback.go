@@ -0,0 +1,101 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+type FeedbackRating string
+
+const (
+	FeedbackUp   FeedbackRating = "up"
+	FeedbackDown FeedbackRating = "down"
+)
+
+// FeedbackReason says what was wrong with an answer; it is only given with a thumbs down.
+type FeedbackReason string
+
+const (
+	FeedbackReasonWrongLaw   FeedbackReason = "wrong_law"
+	FeedbackReasonOutdated   FeedbackReason = "outdated"
+	FeedbackReasonUnclear    FeedbackReason = "unclear"
+	FeedbackReasonIncomplete FeedbackReason = "incomplete"
+	FeedbackReasonOther      FeedbackReason = "other"
+)
+
+func (r FeedbackReason) Valid() bool {
+	switch r {
+	case FeedbackReasonWrongLaw, FeedbackReasonOutdated, FeedbackReasonUnclear, FeedbackReasonIncomplete, FeedbackReasonOther:
+		return true
+	}
+	return false
+}
+
+// AnswerFeedback is a user's rating of an llm_response entry. Rating again replaces it.
+type AnswerFeedback struct {
+	Rating    FeedbackRating `bson:"rating" json:"rating"`
+	Reason    FeedbackReason `bson:"reason,omitempty" json:"reason,omitempty"`
+	CreatedAt time.Time      `bson:"createdAt" json:"created_at"`
+}
+
+var (
+	ErrChatEntryNotFound = errors.New("answer not found in this session")
+	ErrInvalidFeedback   = errors.New("invalid feedback")
+)
+
+// LowRatedAnswer is a thumbs-down answer with the question that led to it. Entry keeps its prompt and
+// refined query for debugging retrieval.
+type LowRatedAnswer struct {
+	Entry    ChatEntry `bson:"entry" json:"entry"`
+	Question string    `bson:"question" json:"question"`
+}
+
+type FeedbackReasonCount struct {
+	Reason FeedbackReason `bson:"_id" json:"reason"`
+	Count  int            `bson:"count" json:"count"`
+}
+
+// FeedbackReport aggregates the low-rated answers in a time range.
+type FeedbackReport struct {
+	Total    int                   `json:"total"`
+	ByReason []FeedbackReasonCount `json:"by_reason"`
+	Answers  []LowRatedAnswer      `json:"answers"`
+	Page     int                   `json:"page"`
+	Limit    int                   `json:"limit"`
+}
+
+// FeedbackReportFilter selects the answers in a report; an empty Reason matches every reason.
+type FeedbackReportFilter struct {
+	From, To    time.Time
+	Reason      FeedbackReason
+	Page, Limit int
+}
+
+// AnalyticsEvent has the shape of the events the content service aggregates, so both services can write
+// to the same analytics_events collection.
+type AnalyticsEvent struct {
+	EventType string      `bson:"event_type" json:"event_type"`
+	UserID    string      `bson:"user_id" json:"user_id"`
+	Payload   interface{} `bson:"payload" json:"payload"`
+	Timestamp int64       `bson:"timestamp" json:"timestamp"` // Unix seconds
+}
+
+const EventAnswerFeedback = "ANSWER_FEEDBACK"
+
+// AnswerFeedbackAnalytic is the payload of an ANSWER_FEEDBACK event.
+type AnswerFeedbackAnalytic struct {
+	SessionID string         `bson:"session_id" json:"session_id"`
+	EntryID   string         `bson:"entry_id" json:"entry_id"`
+	Rating    FeedbackRating `bson:"rating" json:"rating"`
+	Reason    FeedbackReason `bson:"reason,omitempty" json:"reason,omitempty"`
+	Sources   []string       `bson:"sources,omitempty" json:"sources,omitempty"` // cited documents, for per-law quality
+	// The prompt versions behind the answer, for comparing prompt variants
+	PromptVersions map[string]int `bson:"prompt_versions,omitempty" json:"prompt_versions,omitempty"`
+	Age            int            `bson:"age" json:"age"`
+	Gender         string         `bson:"gender" json:"gender"`
+}
+
+type AnalyticsRepository interface {
+	SaveEvent(ctx context.Context, event *AnalyticsEvent) error
+}
@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrGlossaryTermNotFound  = errors.New("glossary term not found")
+	ErrInvalidGlossaryTerm   = errors.New("invalid glossary term")
+	ErrDuplicateGlossaryTerm = errors.New("glossary term already exists")
+)
+
+// GlossaryTerm is a legal term with its approved equivalents, which translations must use, and short
+// definitions shown to users.
+type GlossaryTerm struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Term         string             `bson:"term" json:"term"`                                   // in English
+	Key          string             `bson:"key" json:"-"`                                       // Term in lower case, unique
+	Translations map[string]string  `bson:"translations" json:"translations"`                   // approved equivalents by language code, e.g. am, om
+	Definitions  map[string]string  `bson:"definitions,omitempty" json:"definitions,omitempty"` // by language code, en included
+	UpdatedBy    string             `bson:"updatedBy,omitempty" json:"updated_by,omitempty"`
+	CreatedAt    time.Time          `bson:"createdAt" json:"created_at"`
+	UpdatedAt    time.Time          `bson:"updatedAt" json:"updated_at"`
+}
+
+// GlossaryDefinition is a term as a tooltip shows it, in the language the user reads.
+type GlossaryDefinition struct {
+	ID           string            `json:"id"`
+	Term         string            `json:"term"` // in the requested language
+	English      string            `json:"english"`
+	Definition   string            `json:"definition,omitempty"` // in the requested language, else in English
+	Translations map[string]string `json:"translations"`
+}
+
+type GlossaryRepository interface {
+	// CreateTerm returns ErrDuplicateGlossaryTerm if another term has the same key
+	CreateTerm(ctx context.Context, term *GlossaryTerm) error
+	// UpdateTerm replaces a term; it returns ErrGlossaryTermNotFound or ErrDuplicateGlossaryTerm
+	UpdateTerm(ctx context.Context, term *GlossaryTerm) error
+	DeleteTerm(ctx context.Context, id string) error
+	// GetTerms returns every term, sorted by key
+	GetTerms(ctx context.Context) ([]GlossaryTerm, error)
+}
@@ -0,0 +1,10 @@
+package domain
+
+// GuardrailVerdict is how the guardrails classified a query.
+type GuardrailVerdict string
+
+const (
+	VerdictAllowed    GuardrailVerdict = ""
+	VerdictEmergency  GuardrailVerdict = "emergency"    // someone may be in danger; answered with emergency contacts
+	VerdictOutOfScope GuardrailVerdict = "out_of_scope" // not about Ethiopian law
+)
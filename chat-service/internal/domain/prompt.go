@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrUnknownPrompt         = errors.New("unknown prompt")
+	ErrInvalidPrompt         = errors.New("invalid prompt template")
+	ErrPromptVersionNotFound = errors.New("prompt version not found")
+	ErrNoPromptRollout       = errors.New("prompt has no published version to roll back")
+)
+
+// PromptVersion is an immutable revision of an LLM prompt template. Version 0 is never stored: it stands
+// for the default template from the environment.
+type PromptVersion struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name      string             `bson:"name" json:"name"`
+	Version   int                `bson:"version" json:"version"`
+	Template  string             `bson:"template" json:"template"`
+	Note      string             `bson:"note,omitempty" json:"note,omitempty"`
+	CreatedBy string             `bson:"createdBy,omitempty" json:"created_by,omitempty"`
+	CreatedAt time.Time          `bson:"createdAt" json:"created_at"`
+}
+
+// PromptVariant is a version serving a share of the traffic; shares are proportional to Weight.
+type PromptVariant struct {
+	Version int `bson:"version" json:"version"`
+	Weight  int `bson:"weight" json:"weight"`
+}
+
+// PromptRollout says which versions of a prompt serve traffic. The newest rollout that has not been rolled
+// back is active; without one, the default from the environment is used.
+type PromptRollout struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name         string             `bson:"name" json:"name"`
+	Variants     []PromptVariant    `bson:"variants" json:"variants"`
+	CreatedBy    string             `bson:"createdBy,omitempty" json:"created_by,omitempty"`
+	CreatedAt    time.Time          `bson:"createdAt" json:"created_at"`
+	RolledBackAt *time.Time         `bson:"rolledBackAt,omitempty" json:"rolled_back_at,omitempty"`
+	RolledBackBy string             `bson:"rolledBackBy,omitempty" json:"rolled_back_by,omitempty"`
+}
+
+// PromptStatus is a prompt with the variants currently serving it.
+type PromptStatus struct {
+	Name     string          `json:"name"`
+	Default  string          `json:"default"` // the template from the environment, version 0
+	Variants []PromptVariant `json:"variants"`
+}
+
+// PromptDetails adds a prompt's versions and its rollouts, newest first.
+type PromptDetails struct {
+	PromptStatus
+	Versions []PromptVersion `json:"versions"`
+	Rollouts []PromptRollout `json:"rollouts"`
+}
+
+// PromptVersionStats counts the answers generated with a prompt version and their ratings.
+type PromptVersionStats struct {
+	Version int `bson:"_id" json:"version"`
+	Answers int `bson:"answers" json:"answers"`
+	Up      int `bson:"up" json:"up"`
+	Down    int `bson:"down" json:"down"`
+}
+
+type PromptRepository interface {
+	// CreateVersion stores v under the next version number of its prompt and sets v.Version
+	CreateVersion(ctx context.Context, v *PromptVersion) error
+	// GetVersions returns the given versions of a prompt, or all of them when versions is nil, newest first
+	GetVersions(ctx context.Context, name string, versions []int) ([]PromptVersion, error)
+	SaveRollout(ctx context.Context, rollout *PromptRollout) error
+	// GetActiveRollouts returns the active rollout of every prompt that has one
+	GetActiveRollouts(ctx context.Context) ([]PromptRollout, error)
+	// GetRollouts returns a prompt's rollouts, newest first
+	GetRollouts(ctx context.Context, name string, limit int) ([]PromptRollout, error)
+	// RollBack retires the active rollout of a prompt; it returns ErrNoPromptRollout if there is none
+	RollBack(ctx context.Context, name, by string) error
+}
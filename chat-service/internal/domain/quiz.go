@@ -2,6 +2,7 @@ package domain
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -11,7 +12,8 @@ type Question struct {
 	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	Text          string             `bson:"text" json:"text"`
 	Options       map[string]string  `bson:"options" json:"options"`
-	CorrectOption string             `bson:"correct_option" json:"correct_option"`
+	CorrectOption string             `bson:"correct_option" json:"correct_option,omitempty"`
+	Reference     string             `bson:"reference,omitempty" json:"reference,omitempty"` // law article a generated question was written from
 	CreatedAt     time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt     time.Time          `bson:"updated_at" json:"updated_at"`
 }
@@ -23,10 +25,116 @@ type Quiz struct {
 	Description    string             `bson:"description" json:"description"`
 	Questions      []Question         `bson:"questions" json:"questions"`
 	TotalQuestions int                `bson:"total_questions" json:"total_questions"`
+	PassThreshold  float64            `bson:"pass_threshold" json:"pass_threshold"` // percentage needed to pass; 0 means DefaultQuizPassThreshold
+	Status         QuizStatus         `bson:"status,omitempty" json:"status"`
 	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
 }
 
+type QuizStatus string
+
+// Draft quizzes are hidden from learners until an admin publishes them.
+// Quizzes created before statuses existed have no status and count as published.
+const (
+	QuizStatusDraft     QuizStatus = "draft"
+	QuizStatusPublished QuizStatus = "published"
+)
+
+func (q *Quiz) IsDraft() bool {
+	return q.Status == QuizStatusDraft
+}
+
+// GenerateQuizRequest describes the law passages a quiz should be generated from.
+type GenerateQuizRequest struct {
+	CategoryID   string
+	Name         string // optional; derived from the source if empty
+	Source       string // law document, e.g. "Labour Proclamation No. 1156/2019"
+	ArticleFrom  int    // optional inclusive article range; 0 means unbounded
+	ArticleTo    int
+	NumQuestions int
+}
+
+var (
+	// ErrInvalidQuizSettings is returned when quiz settings are rejected, such as a pass threshold outside 0-100.
+	ErrInvalidQuizSettings = errors.New("invalid quiz settings")
+	// ErrInvalidQuizRequest is returned for malformed IDs, empty sources, bad article ranges and similar caller mistakes.
+	ErrInvalidQuizRequest = errors.New("invalid quiz request")
+	ErrCategoryNotFound   = errors.New("category not found")
+	ErrQuizNotFound       = errors.New("quiz not found")
+	// ErrNoQuizPassages is returned when a law source and article range match no retrievable passages.
+	ErrNoQuizPassages = errors.New("no passages found for the requested source and articles")
+)
+
+// DefaultQuizPassThreshold is the pass mark, in percent, for quizzes without one configured.
+const DefaultQuizPassThreshold = 60.0
+
+func (q *Quiz) EffectivePassThreshold() float64 {
+	if q.PassThreshold <= 0 {
+		return DefaultQuizPassThreshold
+	}
+	return q.PassThreshold
+}
+
+// WithoutAnswers returns a copy of the quiz that is safe to show to learners, with every CorrectOption cleared.
+func (q *Quiz) WithoutAnswers() *Quiz {
+	learnerQuiz := *q
+	learnerQuiz.Questions = make([]Question, len(q.Questions))
+	for i, question := range q.Questions {
+		question.CorrectOption = ""
+		learnerQuiz.Questions[i] = question
+	}
+	return &learnerQuiz
+}
+
+// QuestionResult records how one question of an attempt was answered.
+// CorrectOption is only filled in for answered questions so an empty submission can't reveal the answer key.
+type QuestionResult struct {
+	QuestionID     primitive.ObjectID `bson:"question_id" json:"question_id"`
+	SelectedOption string             `bson:"selected_option" json:"selected_option"`
+	CorrectOption  string             `bson:"correct_option" json:"correct_option,omitempty"`
+	IsCorrect      bool               `bson:"is_correct" json:"is_correct"`
+}
+
+// QuizAttempt is one graded submission of a quiz.
+type QuizAttempt struct {
+	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID          string             `bson:"user_id" json:"user_id"`
+	QuizID          primitive.ObjectID `bson:"quiz_id" json:"quiz_id"`
+	QuizName        string             `bson:"quiz_name" json:"quiz_name"`
+	CategoryID      primitive.ObjectID `bson:"category_id" json:"category_id"`
+	Score           int                `bson:"score" json:"score"`
+	TotalQuestions  int                `bson:"total_questions" json:"total_questions"`
+	Percentage      float64            `bson:"percentage" json:"percentage"`
+	PassThreshold   float64            `bson:"pass_threshold" json:"pass_threshold"`
+	Passed          bool               `bson:"passed" json:"passed"`
+	Results         []QuestionResult   `bson:"results" json:"results"`
+	DurationSeconds int64              `bson:"duration_seconds" json:"duration_seconds"`
+	SubmittedAt     time.Time          `bson:"submitted_at" json:"submitted_at"`
+}
+
+// QuizProgress summarizes a user's attempts at one quiz.
+type QuizProgress struct {
+	QuizID         primitive.ObjectID `bson:"_id" json:"quiz_id"`
+	QuizName       string             `bson:"quiz_name" json:"quiz_name"`
+	CategoryID     primitive.ObjectID `bson:"category_id" json:"category_id"`
+	Attempts       int                `bson:"attempts" json:"attempts"`
+	BestScore      int                `bson:"best_score" json:"best_score"`
+	BestPercentage float64            `bson:"best_percentage" json:"best_percentage"`
+	Passed         bool               `bson:"passed" json:"passed"`
+	LastAttemptAt  time.Time          `bson:"last_attempt_at" json:"last_attempt_at"`
+}
+
+// CategoryProgress summarizes a user's progress through the quizzes of one category.
+type CategoryProgress struct {
+	CategoryID            primitive.ObjectID `json:"category_id"`
+	CategoryName          string             `json:"category_name"`
+	TotalQuizzes          int                `json:"total_quizzes"`
+	AttemptedQuizzes      int                `json:"attempted_quizzes"`
+	PassedQuizzes         int                `json:"passed_quizzes"`
+	AverageBestPercentage float64            `json:"average_best_percentage"`
+	Quizzes               []*QuizProgress    `json:"quizzes"`
+}
+
 type QuizCategory struct {
 	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	Name         string             `bson:"name" json:"name"`
@@ -51,6 +159,14 @@ type PaginatedQuizzes struct {
 	PageSize    int64   `json:"page_size"`
 }
 
+type PaginatedQuizAttempts struct {
+	Items       []*QuizAttempt `json:"items"`
+	TotalItems  int64          `json:"total_items"`
+	TotalPages  int64          `json:"total_pages"`
+	CurrentPage int64          `json:"current_page"`
+	PageSize    int64          `json:"page_size"`
+}
+
 type IQuizRepository interface {
 	// Category methods
 	CreateCategory(ctx context.Context, category *QuizCategory) error
@@ -67,12 +183,22 @@ type IQuizRepository interface {
 	UpdateQuiz(ctx context.Context, quiz *Quiz) error
 	// delete the questions recursively
 	DeleteQuiz(ctx context.Context, id primitive.ObjectID) error
+	GetDraftQuizzes(ctx context.Context, page, limit int64) ([]*Quiz, int64, error)
+	// PublishQuiz makes a draft visible to learners; publishing an already published quiz is a no-op
+	PublishQuiz(ctx context.Context, id primitive.ObjectID) error
 
 	// Question methods
 	AddQuestionToQuiz(ctx context.Context, quizID primitive.ObjectID, question *Question) error
 	GetQuestionByID(ctx context.Context, quizID, questionID primitive.ObjectID) (*Question, error)
 	UpdateQuestionInQuiz(ctx context.Context, quizID primitive.ObjectID, question *Question) error
 	DeleteQuestionFromQuiz(ctx context.Context, quizID, questionID primitive.ObjectID) error
+
+	// Attempt methods
+	CreateAttempt(ctx context.Context, attempt *QuizAttempt) error
+	// quizID may be primitive.NilObjectID to list attempts at every quiz, newest first
+	GetAttemptsByUser(ctx context.Context, userID string, quizID primitive.ObjectID, page, limit int64) ([]*QuizAttempt, int64, error)
+	// categoryID may be primitive.NilObjectID to report every quiz the user has attempted
+	GetQuizProgressByUser(ctx context.Context, userID string, categoryID primitive.ObjectID) ([]*QuizProgress, error)
 }
 
 type IQuizUseCase interface {
@@ -88,10 +214,21 @@ type IQuizUseCase interface {
 	GetQuiz(ctx context.Context, id string) (*Quiz, error)
 	ListQuizzesByCategory(ctx context.Context, categoryID string, page, limit int64) (*PaginatedQuizzes, error)
 	UpdateQuiz(ctx context.Context, id, name, description string) (*Quiz, error)
+	UpdateQuizSettings(ctx context.Context, id string, passThreshold float64) (*Quiz, error)
 	DeleteQuiz(ctx context.Context, id string) error
+	// GenerateQuiz drafts a quiz from RAG passages of a law source for an admin to review before publishing
+	GenerateQuiz(ctx context.Context, req GenerateQuizRequest) (*Quiz, error)
+	ListDraftQuizzes(ctx context.Context, page, limit int64) (*PaginatedQuizzes, error)
+	PublishQuiz(ctx context.Context, id string) (*Quiz, error)
 
 	// Question methods
 	AddQuestion(ctx context.Context, quizID string, text string, options map[string]string, correctOption string) (*Quiz, error)
 	UpdateQuestion(ctx context.Context, quizID, questionID, text string, options map[string]string, correctOption string) (*Question, error)
 	DeleteQuestion(ctx context.Context, quizID, questionID string) error
+
+	// Attempt methods
+	// SubmitQuiz grades answers (question ID -> option key). Attempts by visitors (empty userID) are graded but not stored.
+	SubmitQuiz(ctx context.Context, userID, quizID string, answers map[string]string, startedAt time.Time) (*QuizAttempt, error)
+	ListAttempts(ctx context.Context, userID, quizID string, page, limit int64) (*PaginatedQuizAttempts, error)
+	GetProgress(ctx context.Context, userID, categoryID string) ([]*CategoryProgress, error)
 }
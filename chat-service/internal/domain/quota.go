@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type QuotaKind string
+
+const (
+	QuotaQueries      QuotaKind = "queries"
+	QuotaVoiceSeconds QuotaKind = "voice_seconds"
+)
+
+type QuotaPeriod string
+
+const (
+	QuotaDaily   QuotaPeriod = "daily"
+	QuotaMonthly QuotaPeriod = "monthly"
+)
+
+// QuotaExceededCode is the machine-readable code sent to clients when a quota is exhausted.
+const QuotaExceededCode = "QUOTA_EXCEEDED"
+
+// QuotaLimit caps usage of one kind over one period. A Limit of 0 means unlimited.
+type QuotaLimit struct {
+	Kind    QuotaKind
+	Period  QuotaPeriod
+	Limit   int64
+	ResetAt time.Time // end of the current period
+}
+
+// QuotaUsage reports consumption against a QuotaLimit.
+type QuotaUsage struct {
+	Kind      QuotaKind   `json:"kind"`
+	Period    QuotaPeriod `json:"period"`
+	Used      int64       `json:"used"`
+	Limit     int64       `json:"limit"`     // 0 means unlimited
+	Remaining int64       `json:"remaining"` // 0 when unlimited
+	Unlimited bool        `json:"unlimited"`
+	ResetAt   time.Time   `json:"reset_at"`
+}
+
+// QuotaExceededError is returned when a request would exceed one of the caller's plan quotas.
+type QuotaExceededError struct {
+	Kind    QuotaKind
+	Period  QuotaPeriod
+	Limit   int64
+	ResetAt time.Time
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("You have reached your %s limit of %d %s. It resets at %s.", e.Period, e.Limit, e.Kind, e.ResetAt.Format(time.RFC3339))
+}
+
+type QuotaRepository interface {
+	// Consume atomically adds amount to every limit's counter for subject, unless doing so would
+	// exceed any of them, in which case nothing is consumed and the exceeded limit is returned.
+	Consume(ctx context.Context, subject string, amount int64, limits []QuotaLimit) (*QuotaLimit, error)
+	// ConsumeUpTo adds amount, or as much of it as the limits still allow, for usage that has already
+	// happened. It returns the limit that cut the amount short, if any.
+	ConsumeUpTo(ctx context.Context, subject string, amount int64, limits []QuotaLimit) (*QuotaLimit, error)
+	// Refund takes amount back off every limit's counter for subject, never going below zero.
+	Refund(ctx context.Context, subject string, amount int64, limits []QuotaLimit) error
+	Usage(ctx context.Context, subject string, limits []QuotaLimit) ([]QuotaUsage, error)
+}
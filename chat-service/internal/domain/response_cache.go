@@ -0,0 +1,37 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// CachedAnswer is a generated answer stored for reuse by later identical or similar questions.
+type CachedAnswer struct {
+	Key       string      `json:"key"`
+	Query     string      `json:"query"` // normalized refined query
+	Language  string      `json:"language"`
+	PlanTier  string      `json:"plan_tier"`
+	Answer    string      `json:"answer"`
+	Sources   []RAGSource `json:"sources,omitempty"`
+	Citations []Citation  `json:"citations,omitempty"`
+	Embedding []float32   `json:"embedding,omitempty"`
+	// The versions of the prompts that generated the answer, by prompt name
+	PromptVersions map[string]int `json:"prompt_versions,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+}
+
+// ResponseCache stores answers keyed on the normalized refined query, language and plan tier.
+// Get and FindSimilar return nil without an error on a miss.
+type ResponseCache interface {
+	Get(ctx context.Context, key string) (*CachedAnswer, error)
+	FindSimilar(ctx context.Context, language, planTier string, embedding []float32, minSimilarity float64) (*CachedAnswer, error)
+	Put(ctx context.Context, answer *CachedAnswer, ttl time.Duration) error
+	// InvalidateSource drops every answer that cites the given law document, e.g. after it is re-ingested.
+	InvalidateSource(ctx context.Context, source string) (int, error)
+	InvalidateAll(ctx context.Context) (int, error)
+}
+
+// Embedder turns text into a vector for similarity matching in the response cache.
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float32, error)
+}
@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ShareLink grants read-only access to a session to anyone holding its token, until it expires or the
+// owner revokes it.
+type ShareLink struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Token     string             `bson:"token" json:"token"`
+	SessionID string             `bson:"sessionId" json:"session_id"`
+	UserID    string             `bson:"userId" json:"-"`
+	CreatedAt time.Time          `bson:"createdAt" json:"created_at"`
+	ExpiresAt *time.Time         `bson:"expiresAt,omitempty" json:"expires_at,omitempty"` // nil never expires
+	RevokedAt *time.Time         `bson:"revokedAt,omitempty" json:"revoked_at,omitempty"`
+}
+
+// Active reports whether the link can still be used to open the session.
+func (l *ShareLink) Active(now time.Time) bool {
+	return l.RevokedAt == nil && (l.ExpiresAt == nil || now.Before(*l.ExpiresAt))
+}
+
+var (
+	ErrShareLinkNotFound  = errors.New("share link not found")
+	ErrInvalidShareExpiry = errors.New("share link expiry must be in the future")
+)
+
+// SharedSession is the public view of a shared session. It carries no user, session or entry IDs.
+type SharedSession struct {
+	Title     string          `json:"title"`
+	Language  string          `json:"language"`
+	CreatedAt time.Time       `json:"created_at"`
+	Messages  []SharedMessage `json:"messages"`
+}
+
+type SharedMessage struct {
+	Type       ChatMessageType `json:"type"`
+	Content    string          `json:"content"`
+	Sources    []RAGSource     `json:"sources,omitempty"`
+	Citations  []Citation      `json:"citations,omitempty"`
+	Disclaimer string          `json:"disclaimer,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+type ShareLinkRepository interface {
+	CreateShareLink(ctx context.Context, link *ShareLink) error
+	// GetShareLinkByToken returns ErrShareLinkNotFound for unknown tokens, whether or not the link is active
+	GetShareLinkByToken(ctx context.Context, token string) (*ShareLink, error)
+	// GetActiveShareLinksByUserID lists the user's unrevoked, unexpired links, newest first
+	GetActiveShareLinksByUserID(ctx context.Context, userID string) ([]ShareLink, error)
+	// RevokeShareLink revokes one of the user's links; it returns ErrShareLinkNotFound if the user has no such active link
+	RevokeShareLink(ctx context.Context, userID, id string) error
+	DeleteShareLinksBySessionID(ctx context.Context, sessionID string) error
+}
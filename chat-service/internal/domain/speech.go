@@ -0,0 +1,52 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrSpeechUnavailable is returned when the speech-to-text or text-to-speech service fails.
+var ErrSpeechUnavailable = errors.New("speech service unavailable")
+
+// ErrNoSpeech is returned when a recording was transcribed to nothing.
+var ErrNoSpeech = errors.New("no text transcribed")
+
+// Transcript is the text of a recording.
+type Transcript struct {
+	Text     string
+	Duration float64 // seconds of audio, if the STT service reports it
+}
+
+// TranscriptEvent is a partial transcript of the audio received so far or, when Final, the transcript of
+// the whole utterance. Partial transcripts may still change.
+type TranscriptEvent struct {
+	Text     string
+	Final    bool
+	Duration float64 // set on the final transcript, if the STT service reports it
+	Err      error
+}
+
+// SpeechToTextService transcribes Amharic and English speech.
+type SpeechToTextService interface {
+	// Transcribe transcribes a whole recording.
+	Transcribe(ctx context.Context, audio []byte, contentType, language string) (*Transcript, error)
+	// StartTranscription opens a stream that transcribes audio as it is written.
+	StartTranscription(ctx context.Context, contentType, language string) (TranscriptionStream, error)
+}
+
+// TranscriptionStream transcribes one utterance. Write and CloseSend are called from one goroutine.
+type TranscriptionStream interface {
+	Write(audio []byte) error
+	// CloseSend marks the end of the utterance; the final transcript follows on Events.
+	CloseSend() error
+	// Events yields partial transcripts, then the final one or an error, and is then closed.
+	Events() <-chan TranscriptEvent
+	Close() error
+}
+
+// TextToSpeechService speaks text in Amharic or English.
+type TextToSpeechService interface {
+	// Synthesize returns the speech for text as audio/mpeg, read as the service produces it. The caller closes it.
+	Synthesize(ctx context.Context, text, language string) (io.ReadCloser, error)
+}
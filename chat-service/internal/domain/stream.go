@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// StreamEvent is one numbered event of a query stream. Events are kept briefly so a client that lost its
+// connection can resume from the last ID it saw.
+type StreamEvent struct {
+	ID    int64           `json:"id"`
+	Event string          `json:"event"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// Terminal reports whether no events follow this one.
+func (e StreamEvent) Terminal() bool {
+	return e.Event == "complete" || e.Event == "error"
+}
+
+// StreamInfo says whose answer a resumable stream carries.
+type StreamInfo struct {
+	MessageID string `json:"message_id"`
+	SessionID string `json:"session_id"`
+	UserID    string `json:"user_id"` // empty for visitors, who resume with the session ID instead
+}
+
+var ErrStreamNotFound = errors.New("stream not found or expired")
+
+// StreamBuffer keeps the events of recent query streams.
+type StreamBuffer interface {
+	// AppendStreamEvents adds events to the stream and refreshes its TTL
+	AppendStreamEvents(ctx context.Context, info *StreamInfo, events []StreamEvent, ttl time.Duration) error
+	// GetStreamInfo returns ErrStreamNotFound once the stream has expired
+	GetStreamInfo(ctx context.Context, messageID string) (*StreamInfo, error)
+	// ReadStreamEvents returns the events after afterID, waiting up to block for one if there are none yet
+	ReadStreamEvents(ctx context.Context, info *StreamInfo, afterID int64, block time.Duration) ([]StreamEvent, error)
+	// SetStreamListener records that a resumed client is reading the stream, for ttl
+	SetStreamListener(ctx context.Context, messageID string, ttl time.Duration) error
+	HasStreamListener(ctx context.Context, messageID string) (bool, error)
+}
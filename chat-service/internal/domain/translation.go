@@ -0,0 +1,19 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// TranslationService translates text between English and the languages the AI service supports.
+type TranslationService interface {
+	Translate(ctx context.Context, text, targetLang string) (string, error)
+	Close() error
+}
+
+// TranslationCache stores translations keyed on the source text and target language.
+// Get returns "" without an error on a miss.
+type TranslationCache interface {
+	Get(ctx context.Context, text, targetLang string) (string, error)
+	Put(ctx context.Context, text, targetLang, translated string, ttl time.Duration) error
+}
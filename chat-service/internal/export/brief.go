@@ -0,0 +1,184 @@
+// Package export renders chat sessions as PDF and DOCX legal briefs.
+package export
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/config"
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+)
+
+type Format string
+
+const (
+	FormatPDF  Format = "pdf"
+	FormatDOCX Format = "docx"
+)
+
+// ErrFontUnavailable is returned when a brief needs a font whose file could not be loaded.
+var ErrFontUnavailable = errors.New("export font unavailable")
+
+func ParseFormat(s string) (Format, error) {
+	switch Format(s) {
+	case FormatPDF, FormatDOCX:
+		return Format(s), nil
+	}
+	return "", fmt.Errorf("unsupported export format %q (use pdf or docx)", s)
+}
+
+func (f Format) ContentType() string {
+	if f == FormatDOCX {
+		return "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	}
+	return "application/pdf"
+}
+
+// Brief is a session prepared for export: the conversation, the sources each answer cited and the labels
+// and disclaimer in the brief's language.
+type Brief struct {
+	Title       string
+	Language    string // "en" or "am"
+	StartedAt   time.Time
+	GeneratedAt time.Time
+	Messages    []Message
+}
+
+type Message struct {
+	IsQuestion bool
+	Content    string
+	Sources    []domain.RAGSource // numbered from 1, matching the [n] markers in Content
+}
+
+type labels struct {
+	Heading, Started, Generated, Question, Answer, Sources, Article, Disclaimer, DisclaimerText string
+}
+
+var briefLabels = map[string]labels{
+	"en": {
+		Heading:        "Legal Consultation Brief",
+		Started:        "Consultation started",
+		Generated:      "Generated",
+		Question:       "Question",
+		Answer:         "Answer",
+		Sources:        "Cited sources",
+		Article:        "Article",
+		Disclaimer:     "Disclaimer",
+		DisclaimerText: "This brief was produced by the LawGen legal assistant and contains general legal information only. It is not legal advice and does not create a lawyer-client relationship. Consult a licensed advocate about your specific situation.",
+	},
+	"am": {
+		Heading:        "የሕግ ምክክር ማጠቃለያ",
+		Started:        "ምክክሩ የተጀመረበት",
+		Generated:      "የተዘጋጀበት",
+		Question:       "ጥያቄ",
+		Answer:         "መልስ",
+		Sources:        "የተጠቀሱ ምንጮች",
+		Article:        "አንቀጽ",
+		Disclaimer:     "ማሳሰቢያ",
+		DisclaimerText: "ይህ ማጠቃለያ በLawGen የሕግ ረዳት የተዘጋጀ ሲሆን አጠቃላይ የሕግ መረጃ ብቻ ይዟል። የሕግ ምክር አይደለም፤ የጠበቃና የደንበኛ ግንኙነትም አይፈጥርም። ስለ ጉዳይዎ ፈቃድ ካለው ጠበቃ ጋር ይማከሩ።",
+	},
+}
+
+// SupportedLanguage reports whether briefs can be labelled in the given language.
+func SupportedLanguage(language string) bool {
+	_, ok := briefLabels[language]
+	return ok
+}
+
+func (b *Brief) labels() labels {
+	if l, ok := briefLabels[b.Language]; ok {
+		return l
+	}
+	return briefLabels["en"]
+}
+
+// Exporter renders briefs with the fonts configured for export. Font files are read once, on first use.
+type Exporter struct {
+	cfg *config.Config
+
+	fontsOnce sync.Once
+	fonts     fonts
+}
+
+type fonts struct {
+	latin        []byte
+	ethiopic     []byte
+	ethiopicName string
+}
+
+func NewExporter(cfg *config.Config) *Exporter {
+	return &Exporter{cfg: cfg}
+}
+
+func (e *Exporter) loadFonts() fonts {
+	e.fontsOnce.Do(func() {
+		var err error
+		e.fonts.ethiopicName = e.cfg.ExportEthiopicFontName
+		if e.fonts.latin, err = os.ReadFile(e.cfg.ExportLatinFontPath); err != nil {
+			log.Printf("Warning: Failed to load export font %s: %v", e.cfg.ExportLatinFontPath, err)
+		}
+		if e.fonts.ethiopic, err = os.ReadFile(e.cfg.ExportEthiopicFontPath); err != nil {
+			log.Printf("Warning: Failed to load Ethiopic export font %s: %v", e.cfg.ExportEthiopicFontPath, err)
+		}
+	})
+	return e.fonts
+}
+
+// Render returns the brief as a document in the given format.
+func (e *Exporter) Render(b *Brief, format Format) ([]byte, error) {
+	f := e.loadFonts()
+	if f.latin == nil {
+		return nil, fmt.Errorf("%w: %s", ErrFontUnavailable, e.cfg.ExportLatinFontPath)
+	}
+	if f.ethiopic == nil && b.needsEthiopic() {
+		return nil, fmt.Errorf("%w: %s", ErrFontUnavailable, e.cfg.ExportEthiopicFontPath)
+	}
+	if format == FormatDOCX {
+		return renderDOCX(b, f)
+	}
+	return renderPDF(b, f)
+}
+
+func (b *Brief) needsEthiopic() bool {
+	if hasEthiopic(b.Title) || hasEthiopic(b.labels().Heading) {
+		return true
+	}
+	for _, m := range b.Messages {
+		if hasEthiopic(m.Content) {
+			return true
+		}
+		for _, s := range m.Sources {
+			if hasEthiopic(s.Source) || hasEthiopic(s.Content) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sourceLine describes a cited source as "[n] Source, Article x".
+func sourceLine(l labels, n int, s domain.RAGSource) string {
+	line := fmt.Sprintf("[%d] %s", n, s.Source)
+	if s.ArticleNumber != "" {
+		line += fmt.Sprintf(", %s %s", l.Article, s.ArticleNumber)
+	}
+	return line
+}
+
+// excerpt shortens a source passage for the brief.
+func excerpt(content string, maxRunes int) string {
+	runes := []rune(content)
+	if len(runes) <= maxRunes {
+		return content
+	}
+	return string(runes[:maxRunes]) + "…"
+}
+
+const (
+	sourceExcerptRunes = 400
+	dateLayout         = "2006-01-02 15:04 MST"
+)
@@ -0,0 +1,192 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	wordNS    = "http://schemas.openxmlformats.org/wordprocessingml/2006/main"
+	relNS     = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+	fontPart  = "fonts/font1.odttf"
+	fontRelID = "rId1"
+)
+
+const contentTypesXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Default Extension="odttf" ContentType="application/vnd.openxmlformats-officedocument.obfuscatedFont"/>
+<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>
+<Override PartName="/word/settings.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.settings+xml"/>
+<Override PartName="/word/fontTable.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.fontTable+xml"/>
+</Types>`
+
+const packageRelsXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>
+</Relationships>`
+
+const documentRelsXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/settings" Target="settings.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/fontTable" Target="fontTable.xml"/>
+</Relationships>`
+
+const settingsXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:settings xmlns:w="` + wordNS + `"><w:embedTrueTypeFonts/></w:settings>`
+
+// docxStyle is the direct formatting of a paragraph; the package has no styles part.
+type docxStyle struct {
+	halfPoints int
+	bold       bool
+	color      string
+}
+
+var (
+	docxHeading  = docxStyle{halfPoints: 36, bold: true}
+	docxTitle    = docxStyle{halfPoints: 26, bold: true}
+	docxMeta     = docxStyle{halfPoints: 18, color: "646464"}
+	docxLabel    = docxStyle{halfPoints: 24, bold: true, color: "282828"}
+	docxBody     = docxStyle{halfPoints: 20}
+	docxSource   = docxStyle{halfPoints: 18}
+	docxExcerpt  = docxStyle{halfPoints: 16, color: "5A5A5A"}
+	docxSubLabel = docxStyle{halfPoints: 20, bold: true, color: "282828"}
+)
+
+// renderDOCX writes a minimal WordprocessingML package. When the brief has Ge'ez text, the Ethiopic font is
+// embedded (obfuscated as the format requires) so the document renders on machines without it installed.
+func renderDOCX(b *Brief, f fonts) ([]byte, error) {
+	l := b.labels()
+	embed := f.ethiopic != nil && b.needsEthiopic()
+
+	var body bytes.Buffer
+	paragraph := func(text string, st docxStyle) {
+		for _, line := range strings.Split(text, "\n") {
+			body.WriteString("<w:p>")
+			for _, run := range scriptRuns(line) {
+				body.WriteString("<w:r><w:rPr>")
+				if run.ethiopic && embed {
+					fmt.Fprintf(&body, `<w:rFonts w:ascii="%[1]s" w:hAnsi="%[1]s" w:eastAsia="%[1]s" w:cs="%[1]s"/>`, xmlAttr(f.ethiopicName))
+				}
+				if st.bold {
+					body.WriteString("<w:b/>")
+				}
+				if st.color != "" {
+					fmt.Fprintf(&body, `<w:color w:val="%s"/>`, st.color)
+				}
+				fmt.Fprintf(&body, `<w:sz w:val="%d"/><w:szCs w:val="%d"/>`, st.halfPoints, st.halfPoints)
+				if run.ethiopic {
+					body.WriteString(`<w:lang w:val="am-ET"/>`)
+				}
+				body.WriteString(`</w:rPr><w:t xml:space="preserve">`)
+				xml.EscapeText(&body, []byte(run.text))
+				body.WriteString("</w:t></w:r>")
+			}
+			body.WriteString("</w:p>")
+		}
+	}
+
+	paragraph(l.Heading, docxHeading)
+	paragraph(b.Title, docxTitle)
+	paragraph(fmt.Sprintf("%s: %s", l.Started, b.StartedAt.Format(dateLayout)), docxMeta)
+	paragraph(fmt.Sprintf("%s: %s", l.Generated, b.GeneratedAt.Format(dateLayout)), docxMeta)
+	for _, m := range b.Messages {
+		if m.IsQuestion {
+			paragraph(l.Question, docxLabel)
+		} else {
+			paragraph(l.Answer, docxLabel)
+		}
+		paragraph(m.Content, docxBody)
+		if len(m.Sources) > 0 {
+			paragraph(l.Sources, docxSubLabel)
+			for i, s := range m.Sources {
+				paragraph(sourceLine(l, i+1, s), docxSource)
+				if s.Content != "" {
+					paragraph(excerpt(s.Content, sourceExcerptRunes), docxExcerpt)
+				}
+			}
+		}
+	}
+	paragraph(l.Disclaimer, docxLabel)
+	paragraph(l.DisclaimerText, docxMeta)
+
+	document := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<w:document xmlns:w="` + wordNS + `" xmlns:r="` + relNS + `"><w:body>` + body.String() +
+		`<w:sectPr><w:pgSz w:w="11906" w:h="16838"/><w:pgMar w:top="1134" w:right="1134" w:bottom="1134" w:left="1134" w:header="709" w:footer="709" w:gutter="0"/></w:sectPr>` +
+		`</w:body></w:document>`
+
+	parts := map[string][]byte{
+		"[Content_Types].xml":          []byte(contentTypesXML),
+		"_rels/.rels":                  []byte(packageRelsXML),
+		"word/document.xml":            []byte(document),
+		"word/_rels/document.xml.rels": []byte(documentRelsXML),
+		"word/settings.xml":            []byte(settingsXML),
+	}
+	fontTable := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?><w:fonts xmlns:w="` + wordNS + `" xmlns:r="` + relNS + `">`
+	if embed {
+		fontKey := "{" + strings.ToUpper(uuid.New().String()) + "}"
+		obfuscated, err := obfuscateFont(f.ethiopic, fontKey)
+		if err != nil {
+			return nil, err
+		}
+		fontTable += fmt.Sprintf(`<w:font w:name="%s"><w:charset w:val="00"/><w:family w:val="swiss"/><w:pitch w:val="variable"/><w:embedRegular r:id="%s" w:fontKey="%s"/></w:font>`,
+			xmlAttr(f.ethiopicName), fontRelID, fontKey)
+		parts["word/_rels/fontTable.xml.rels"] = []byte(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="` + fontRelID + `" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/font" Target="` + fontPart + `"/>` +
+			`</Relationships>`)
+		parts["word/"+fontPart] = obfuscated
+	}
+	parts["word/fontTable.xml"] = []byte(fontTable + `</w:fonts>`)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	// [Content_Types].xml goes first, as some readers expect
+	order := []string{"[Content_Types].xml", "_rels/.rels", "word/document.xml", "word/_rels/document.xml.rels",
+		"word/settings.xml", "word/fontTable.xml", "word/_rels/fontTable.xml.rels", "word/" + fontPart}
+	for _, name := range order {
+		data, ok := parts[name]
+		if !ok {
+			continue
+		}
+		w, err := zw.Create(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render DOCX: %w", err)
+		}
+		if _, err := w.Write(data); err != nil {
+			return nil, fmt.Errorf("failed to render DOCX: %w", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to render DOCX: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// obfuscateFont applies the embedded font obfuscation of ECMA-376 Part 1, 17.8.1: the first 32 bytes are
+// XORed with the font key's 16 bytes taken in reverse order.
+func obfuscateFont(font []byte, fontKey string) ([]byte, error) {
+	raw, err := hex.DecodeString(strings.NewReplacer("{", "", "}", "", "-", "").Replace(fontKey))
+	if err != nil || len(raw) != 16 {
+		return nil, fmt.Errorf("invalid font key %q", fontKey)
+	}
+	out := make([]byte, len(font))
+	copy(out, font)
+	for i := 0; i < 32 && i < len(out); i++ {
+		out[i] ^= raw[15-i%16]
+	}
+	return out, nil
+}
+
+func xmlAttr(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
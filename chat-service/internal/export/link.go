@@ -0,0 +1,52 @@
+package export
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+var (
+	// ErrInvalidLink is returned for download links with a bad signature or that have expired.
+	ErrInvalidLink = errors.New("export link is invalid or has expired")
+	// ErrLinksDisabled is returned when no secret to sign download links with is configured.
+	ErrLinksDisabled = errors.New("export links are not configured")
+)
+
+// Link identifies an export that can be downloaded without authentication until Expires.
+// The owner's user ID is signed but not part of the URL; it is checked against the session's current owner.
+type Link struct {
+	SessionID string
+	OwnerID   string
+	Format    Format
+	Language  string
+	Expires   time.Time
+}
+
+// Sign returns the hex HMAC-SHA256 of the link's fields under secret.
+func (l Link) Sign(secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%s\n%d", l.SessionID, l.OwnerID, l.Format, l.Language, l.Expires.Unix())
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyLink parses the query values of a download link and checks its signature, for the session's owner, and expiry.
+func VerifyLink(secret, ownerID, sessionID, format, language, expires, signature string) (Link, error) {
+	f, err := ParseFormat(format)
+	if err != nil || secret == "" {
+		return Link{}, ErrInvalidLink
+	}
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return Link{}, ErrInvalidLink
+	}
+	link := Link{SessionID: sessionID, OwnerID: ownerID, Format: f, Language: language, Expires: time.Unix(unix, 0)}
+	if !hmac.Equal([]byte(link.Sign(secret)), []byte(signature)) || time.Now().After(link.Expires) {
+		return Link{}, ErrInvalidLink
+	}
+	return link, nil
+}
@@ -0,0 +1,84 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/go-pdf/fpdf"
+)
+
+const (
+	pdfFontLatin    = "latin"
+	pdfFontEthiopic = "ethiopic"
+)
+
+func renderPDF(b *Brief, f fonts) ([]byte, error) {
+	l := b.labels()
+
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(20, 20, 20)
+	pdf.SetAutoPageBreak(true, 20)
+	pdf.SetTitle(b.Title, true)
+	pdf.SetCreator("LawGen", true)
+	pdf.AddUTF8FontFromBytes(pdfFontLatin, "", f.latin)
+	if f.ethiopic != nil {
+		pdf.AddUTF8FontFromBytes(pdfFontEthiopic, "", f.ethiopic)
+	}
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-15)
+		pdf.SetFont(pdfFontLatin, "", 8)
+		pdf.SetTextColor(120, 120, 120)
+		pdf.CellFormat(0, 10, fmt.Sprintf("%d", pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+	pdf.AddPage()
+
+	// write sets a paragraph, switching to the Ethiopic font for Ge'ez runs
+	write := func(text string, size float64, gray int) {
+		pdf.SetTextColor(gray, gray, gray)
+		lineHeight := size * 0.5
+		for _, run := range scriptRuns(text) {
+			family := pdfFontLatin
+			if run.ethiopic && f.ethiopic != nil {
+				family = pdfFontEthiopic
+			}
+			pdf.SetFont(family, "", size)
+			pdf.Write(lineHeight, run.text)
+		}
+		pdf.Ln(lineHeight + 1)
+	}
+
+	write(l.Heading, 18, 0)
+	write(b.Title, 13, 0)
+	write(fmt.Sprintf("%s: %s", l.Started, b.StartedAt.Format(dateLayout)), 9, 100)
+	write(fmt.Sprintf("%s: %s", l.Generated, b.GeneratedAt.Format(dateLayout)), 9, 100)
+	pdf.Ln(4)
+
+	for _, m := range b.Messages {
+		if m.IsQuestion {
+			write(l.Question, 12, 40)
+		} else {
+			write(l.Answer, 12, 40)
+		}
+		write(m.Content, 10, 0)
+		if len(m.Sources) > 0 {
+			pdf.Ln(1)
+			write(l.Sources, 10, 40)
+			for i, s := range m.Sources {
+				write(sourceLine(l, i+1, s), 9, 0)
+				if s.Content != "" {
+					write(excerpt(s.Content, sourceExcerptRunes), 8, 90)
+				}
+			}
+		}
+		pdf.Ln(4)
+	}
+
+	write(l.Disclaimer, 11, 40)
+	write(l.DisclaimerText, 9, 60)
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("failed to render PDF: %w", err)
+	}
+	return buf.Bytes(), nil
+}
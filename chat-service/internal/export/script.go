@@ -0,0 +1,43 @@
+package export
+
+import "unicode"
+
+// scriptRun is a stretch of text that can be set in a single font.
+type scriptRun struct {
+	text     string
+	ethiopic bool
+}
+
+func isEthiopic(r rune) bool {
+	return unicode.Is(unicode.Ethiopic, r)
+}
+
+func hasEthiopic(s string) bool {
+	for _, r := range s {
+		if isEthiopic(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// scriptRuns splits text into runs of Ethiopic and other characters, so each can be set in a font that has
+// its glyphs. Spaces, digits and common punctuation stay in the current run to avoid needless font switches.
+func scriptRuns(s string) []scriptRun {
+	var runs []scriptRun
+	start, current := 0, false
+	for i, r := range s {
+		ethiopic := isEthiopic(r)
+		if ethiopic == current || (!ethiopic && !unicode.IsLetter(r)) {
+			continue
+		}
+		if i > start {
+			runs = append(runs, scriptRun{text: s[start:i], ethiopic: current})
+		}
+		start, current = i, ethiopic
+	}
+	if start < len(s) {
+		runs = append(runs, scriptRun{text: s[start:], ethiopic: current})
+	}
+	return runs
+}
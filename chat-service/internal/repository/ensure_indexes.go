@@ -5,6 +5,7 @@ import (
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // EnsureIndexes creates necessary indexes for optimal performance.
@@ -28,6 +29,18 @@ func EnsureIndexes(db *mongo.Database) error {
 		return err
 	}
 
+	_, err = db.Collection("quiz_attempts").Indexes().CreateOne(ctx,
+		mongo.IndexModel{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "submitted_at", Value: -1}}})
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Collection("quiz_attempts").Indexes().CreateOne(ctx,
+		mongo.IndexModel{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "category_id", Value: 1}}})
+	if err != nil {
+		return err
+	}
+
 	_, err = db.Collection("sessions").Indexes().CreateOne(ctx,
 		mongo.IndexModel{Keys: bson.D{{Key: "userId", Value: 1}}})
 	if err != nil {
@@ -36,5 +49,68 @@ func EnsureIndexes(db *mongo.Database) error {
 
 	_, err = db.Collection("sessions").Indexes().CreateOne(ctx,
 		mongo.IndexModel{Keys: bson.D{{Key: "lastActiveAt", Value: -1}}})
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Collection("chats").Indexes().CreateOne(ctx,
+		mongo.IndexModel{Keys: bson.D{{Key: "sessionId", Value: 1}, {Key: "createdAt", Value: 1}}})
+	if err != nil {
+		return err
+	}
+
+	// Full-text search over a user's chat history
+	_, err = db.Collection("chats").Indexes().CreateOne(ctx,
+		mongo.IndexModel{Keys: bson.D{{Key: "content", Value: "text"}}})
+	if err != nil {
+		return err
+	}
+
+	// Low-rated answer report
+	_, err = db.Collection("chats").Indexes().CreateOne(ctx,
+		mongo.IndexModel{
+			Keys:    bson.D{{Key: "feedback.rating", Value: 1}, {Key: "feedback.createdAt", Value: -1}},
+			Options: options.Index().SetPartialFilterExpression(bson.M{"feedback": bson.M{"$exists": true}}),
+		})
+	if err != nil {
+		return err
+	}
+
+	// Keyword search over cached law articles while the RAG service is down
+	_, err = db.Collection("law_articles").Indexes().CreateOne(ctx,
+		mongo.IndexModel{
+			Keys:    bson.D{{Key: "content", Value: "text"}, {Key: "source", Value: "text"}, {Key: "topics", Value: "text"}},
+			Options: options.Index().SetWeights(bson.M{"content": 1, "source": 3, "topics": 5}),
+		})
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Collection("share_links").Indexes().CreateOne(ctx,
+		mongo.IndexModel{Keys: bson.D{{Key: "token", Value: 1}}, Options: options.Index().SetUnique(true)})
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Collection("share_links").Indexes().CreateOne(ctx,
+		mongo.IndexModel{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: -1}}})
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Collection("prompt_versions").Indexes().CreateOne(ctx,
+		mongo.IndexModel{Keys: bson.D{{Key: "name", Value: 1}, {Key: "version", Value: 1}}, Options: options.Index().SetUnique(true)})
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Collection("prompt_rollouts").Indexes().CreateOne(ctx,
+		mongo.IndexModel{Keys: bson.D{{Key: "name", Value: 1}, {Key: "createdAt", Value: -1}}})
+	if err != nil {
+		return err
+	}
+
+	_, err = db.Collection("glossary_terms").Indexes().CreateOne(ctx,
+		mongo.IndexModel{Keys: bson.D{{Key: "key", Value: 1}}, Options: options.Index().SetUnique(true)})
 	return err
 }
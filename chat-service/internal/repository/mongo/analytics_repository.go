@@ -0,0 +1,25 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+)
+
+type AnalyticsRepository struct {
+	collection *mongo.Collection
+}
+
+func NewAnalyticsRepository(db *mongo.Database) domain.AnalyticsRepository {
+	return &AnalyticsRepository{collection: db.Collection("analytics_events")}
+}
+
+func (r *AnalyticsRepository) SaveEvent(ctx context.Context, event *domain.AnalyticsEvent) error {
+	if _, err := r.collection.InsertOne(ctx, event); err != nil {
+		return fmt.Errorf("failed to save analytics event to MongoDB: %w", err)
+	}
+	return nil
+}
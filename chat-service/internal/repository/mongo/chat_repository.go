@@ -22,7 +22,10 @@ func NewSessionRepository(db *mongo.Database) domain.SessionRepository {
 }
 
 func (r *SessionRepository) CreateSession(ctx context.Context, session *domain.Session) error {
-	session.MongoID = primitive.NewObjectID()
+	// Keep an ID already assigned by the Redis repository so both stores agree on it
+	if session.MongoID.IsZero() {
+		session.MongoID = primitive.NewObjectID()
+	}
 	session.ID = session.MongoID.Hex()
 	if session.CreatedAt.IsZero() {
 		session.CreatedAt = time.Now()
@@ -68,6 +71,8 @@ func (r *SessionRepository) UpdateSession(ctx context.Context, session *domain.S
 		"lastActiveAt": session.LastActiveAt,
 		"isGuest":      session.IsGuest,
 		"title":        session.Title,
+		"pinned":       session.Pinned,
+		"archived":     session.Archived,
 	}}
 	_, err = r.collection.UpdateByID(ctx, objID, update)
 	if err != nil {
@@ -76,10 +81,13 @@ func (r *SessionRepository) UpdateSession(ctx context.Context, session *domain.S
 	return nil
 }
 
-func (r *SessionRepository) GetSessionsByUserID(ctx context.Context, userID string, page, limit int) ([]*domain.Session, int, error) {
+func (r *SessionRepository) GetSessionsByUserID(ctx context.Context, userID string, archived bool, page, limit int) ([]*domain.Session, int, error) {
 	var sessions []*domain.Session
-	filter := bson.M{"userId": userID}
-	opts := options.Find().SetSkip(int64((page - 1) * limit)).SetLimit(int64(limit)).SetSort(bson.D{{Key: "lastActiveAt", Value: -1}}) // Use lastActiveAt for sorting
+	filter := bson.M{"userId": userID, "archived": bson.M{"$ne": true}} // Sessions created before archiving existed have no field
+	if archived {
+		filter["archived"] = true
+	}
+	opts := options.Find().SetSkip(int64((page - 1) * limit)).SetLimit(int64(limit)).SetSort(bson.D{{Key: "pinned", Value: -1}, {Key: "lastActiveAt", Value: -1}}) // Pinned sessions first, then by lastActiveAt
 	cursor, err := r.collection.Find(ctx, filter, opts)
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to find sessions by user ID: %w", err)
@@ -102,6 +110,38 @@ func (r *SessionRepository) GetSessionsByUserID(ctx context.Context, userID stri
 	return sessions, int(total), nil
 }
 
+func (r *SessionRepository) GetSessionIDsByUserID(ctx context.Context, userID string) ([]string, error) {
+	opts := options.Find().SetProjection(bson.M{"_id": 1})
+	cursor, err := r.collection.Find(ctx, bson.M{"userId": userID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find session IDs by user ID: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var docs []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to decode session IDs: %w", err)
+	}
+	ids := make([]string, len(docs))
+	for i, doc := range docs {
+		ids[i] = doc.ID.Hex()
+	}
+	return ids, nil
+}
+
+func (r *SessionRepository) DeleteSession(ctx context.Context, id string) error {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("invalid session ID format: %w", err)
+	}
+	if _, err := r.collection.DeleteOne(ctx, bson.M{"_id": objID}); err != nil {
+		return fmt.Errorf("failed to delete session from MongoDB: %w", err)
+	}
+	return nil
+}
+
 func (r *SessionRepository) SetSessionTTL(ctx context.Context, sessionID string, ttl time.Duration) error {
 	// MongoDB manages TTL via indexes if configured, not per-document explicit calls
 	// This method is primarily for Redis. No-op for MongoRepo.
@@ -123,14 +163,23 @@ func (r *SessionRepository) RemoveUserSessionID(ctx context.Context, sessionID s
 	return nil
 }
 
+func (r *SessionRepository) MarkSessionDeleted(ctx context.Context, sessionID string) error {
+	return fmt.Errorf("MarkSessionDeleted not implemented for MongoDB repository")
+}
+
+func (r *SessionRepository) IsSessionDeleted(ctx context.Context, sessionID string) (bool, error) {
+	return false, fmt.Errorf("IsSessionDeleted not implemented for MongoDB repository")
+}
+
 // ---------------- CHAT REPOSITORY --------------------------------
 
 type MongoChatRepository struct {
 	collection *mongo.Collection
+	summaries  *mongo.Collection // one summary document per session, kept apart so it never shows up in the message list
 }
 
 func NewChatRepository(db *mongo.Database) domain.ChatRepository {
-	return &MongoChatRepository{collection: db.Collection("chats")}
+	return &MongoChatRepository{collection: db.Collection("chats"), summaries: db.Collection("chat_summaries")}
 }
 
 func (r *MongoChatRepository) SaveChatEntry(ctx context.Context, entry *domain.ChatEntry) error {
@@ -179,13 +228,21 @@ func (r *MongoChatRepository) BulkSaveChatEntries(ctx context.Context, entries [
 
 	var models []mongo.WriteModel
 	for _, entry := range entries {
-		// Use ReplaceOneModel with Upsert: true.
-		// If _id exists, replace the document. If not, insert it.
-		// This handles both initial sync and potential re-syncs.
-		filter := bson.M{"_id": entry.MongoID}
-		model := mongo.NewReplaceOneModel().
-			SetFilter(filter).
-			SetReplacement(entry).
+		// Upsert by _id, so both the initial sync and re-syncs work. The rating is only written on insert:
+		// once the entry is in MongoDB SetFeedback updates it there, and a sync that loaded the entry from
+		// Redis before it was rated must not wipe the rating out.
+		fields, err := entryFields(entry)
+		if err != nil {
+			return err
+		}
+		update := bson.M{"$set": fields}
+		if feedback, ok := fields["feedback"]; ok {
+			delete(fields, "feedback")
+			update["$setOnInsert"] = bson.M{"feedback": feedback}
+		}
+		model := mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": entry.MongoID}).
+			SetUpdate(update).
 			SetUpsert(true)
 		models = append(models, model)
 	}
@@ -198,6 +255,20 @@ func (r *MongoChatRepository) BulkSaveChatEntries(ctx context.Context, entries [
 	return nil
 }
 
+// entryFields returns an entry's stored fields without its _id, which an update may not set.
+func entryFields(entry domain.ChatEntry) (bson.M, error) {
+	data, err := bson.Marshal(entry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal chat entry %s: %w", entry.MongoID.Hex(), err)
+	}
+	var fields bson.M
+	if err := bson.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal chat entry %s: %w", entry.MongoID.Hex(), err)
+	}
+	delete(fields, "_id")
+	return fields, nil
+}
+
 func (r *MongoChatRepository) GetUnsyncedChatEntries(ctx context.Context, sessionID string) ([]domain.ChatEntry, error) {
 	return nil, fmt.Errorf("GetUnsyncedChatEntries is for Redis repository, not MongoDB")
 }
@@ -207,3 +278,203 @@ func (r *MongoChatRepository) MarkChatEntriesAsSynced(ctx context.Context, sessi
 	// fmt.Errorf("MarkChatEntriesAsSynced is for Redis repository, not MongoDB")
 }
 
+func (r *MongoChatRepository) GetSummary(ctx context.Context, sessionID string) (*domain.ChatEntry, error) {
+	var summary domain.ChatEntry
+	err := r.summaries.FindOne(ctx, bson.M{"sessionId": sessionID}).Decode(&summary)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat summary from MongoDB: %w", err)
+	}
+	summary.ID = summary.MongoID.Hex()
+	return &summary, nil
+}
+
+func (r *MongoChatRepository) SaveSummary(ctx context.Context, summary *domain.ChatEntry) error {
+	// Replace the session's summary whatever its _id is; the replacement must not try to change it
+	doc := *summary
+	doc.MongoID = primitive.NilObjectID
+	_, err := r.summaries.ReplaceOne(ctx, bson.M{"sessionId": summary.SessionID}, doc, options.Replace().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("failed to save chat summary to MongoDB: %w", err)
+	}
+	return nil
+}
+
+func (r *MongoChatRepository) DeleteChatHistory(ctx context.Context, sessionID string) error {
+	if _, err := r.collection.DeleteMany(ctx, bson.M{"sessionId": sessionID}); err != nil {
+		return fmt.Errorf("failed to delete chat entries from MongoDB: %w", err)
+	}
+	if _, err := r.summaries.DeleteOne(ctx, bson.M{"sessionId": sessionID}); err != nil {
+		return fmt.Errorf("failed to delete chat summary from MongoDB: %w", err)
+	}
+	return nil
+}
+
+// SearchChatEntries relies on the text index on content created by EnsureIndexes.
+func (r *MongoChatRepository) SearchChatEntries(ctx context.Context, sessionIDs []string, query string, limit int) ([]domain.ChatSearchResult, error) {
+	if len(sessionIDs) == 0 {
+		return nil, nil
+	}
+	filter := bson.M{
+		"$text":     bson.M{"$search": query},
+		"sessionId": bson.M{"$in": sessionIDs},
+	}
+	score := bson.M{"$meta": "textScore"}
+	opts := options.Find().
+		SetProjection(bson.M{"score": score}).
+		SetSort(bson.D{{Key: "score", Value: score}, {Key: "createdAt", Value: -1}}).
+		SetLimit(int64(limit))
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search chat entries in MongoDB: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var docs []struct {
+		domain.ChatEntry `bson:",inline"`
+		Score            float64 `bson:"score"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to decode chat search results: %w", err)
+	}
+	results := make([]domain.ChatSearchResult, len(docs))
+	for i, doc := range docs {
+		doc.ChatEntry.ID = doc.ChatEntry.MongoID.Hex()
+		results[i] = domain.ChatSearchResult{Entry: doc.ChatEntry, Score: doc.Score}
+	}
+	return results, nil
+}
+
+func (r *MongoChatRepository) SetFeedback(ctx context.Context, sessionID, entryID string, feedback *domain.AnswerFeedback) error {
+	objID, err := primitive.ObjectIDFromHex(entryID)
+	if err != nil {
+		return domain.ErrChatEntryNotFound
+	}
+	filter := bson.M{"_id": objID, "sessionId": sessionID, "type": domain.MessageTypeLLM}
+	res, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"feedback": feedback}})
+	if err != nil {
+		return fmt.Errorf("failed to save feedback to MongoDB: %w", err)
+	}
+	if res.MatchedCount == 0 {
+		return domain.ErrChatEntryNotFound
+	}
+	return nil
+}
+
+// GetFeedbackReport counts thumbs-down answers by reason and returns a page of them, newest rating first,
+// each with the user question that preceded it in its session.
+func (r *MongoChatRepository) GetFeedbackReport(ctx context.Context, filter domain.FeedbackReportFilter) (*domain.FeedbackReport, error) {
+	match := bson.M{
+		"type":               domain.MessageTypeLLM,
+		"feedback.rating":    domain.FeedbackDown,
+		"feedback.createdAt": bson.M{"$gte": filter.From, "$lt": filter.To},
+	}
+	if filter.Reason != "" {
+		match["feedback.reason"] = filter.Reason
+	}
+	question := bson.M{
+		"from": r.collection.Name(),
+		"let":  bson.M{"sid": "$sessionId", "at": "$createdAt"},
+		"pipeline": bson.A{
+			bson.M{"$match": bson.M{"$expr": bson.M{"$and": bson.A{
+				bson.M{"$eq": bson.A{"$sessionId", "$$sid"}},
+				bson.M{"$eq": bson.A{"$type", domain.MessageTypeUser}},
+				bson.M{"$lt": bson.A{"$createdAt", "$$at"}},
+			}}}},
+			bson.M{"$sort": bson.M{"createdAt": -1}},
+			bson.M{"$limit": 1},
+			bson.M{"$project": bson.M{"content": 1}},
+		},
+		"as": "question",
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$facet", Value: bson.M{
+			"total": bson.A{bson.M{"$count": "count"}},
+			"byReason": bson.A{
+				bson.M{"$group": bson.M{"_id": "$feedback.reason", "count": bson.M{"$sum": 1}}},
+				bson.M{"$sort": bson.M{"count": -1}},
+			},
+			"answers": bson.A{
+				bson.M{"$sort": bson.M{"feedback.createdAt": -1}},
+				bson.M{"$skip": int64((filter.Page - 1) * filter.Limit)},
+				bson.M{"$limit": int64(filter.Limit)},
+				bson.M{"$lookup": question},
+				bson.M{"$project": bson.M{
+					"entry":    "$$ROOT",
+					"question": bson.M{"$ifNull": bson.A{bson.M{"$arrayElemAt": bson.A{"$question.content", 0}}, ""}},
+				}},
+			},
+		}}},
+	}
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate answer feedback: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var facets []struct {
+		Total []struct {
+			Count int `bson:"count"`
+		} `bson:"total"`
+		ByReason []domain.FeedbackReasonCount `bson:"byReason"`
+		Answers  []domain.LowRatedAnswer      `bson:"answers"`
+	}
+	if err := cursor.All(ctx, &facets); err != nil {
+		return nil, fmt.Errorf("failed to decode answer feedback report: %w", err)
+	}
+	report := &domain.FeedbackReport{
+		ByReason: []domain.FeedbackReasonCount{},
+		Answers:  []domain.LowRatedAnswer{},
+		Page:     filter.Page,
+		Limit:    filter.Limit,
+	}
+	if len(facets) == 0 {
+		return report, nil
+	}
+	if len(facets[0].Total) > 0 {
+		report.Total = facets[0].Total[0].Count
+	}
+	if facets[0].ByReason != nil {
+		report.ByReason = facets[0].ByReason
+	}
+	for _, answer := range facets[0].Answers {
+		answer.Entry.ID = answer.Entry.MongoID.Hex()
+		report.Answers = append(report.Answers, answer)
+	}
+	return report, nil
+}
+
+// GetPromptFeedbackStats groups answers by the version of the prompt they were generated with. Answers that
+// predate prompt versioning count as version 0, the default from the environment.
+func (r *MongoChatRepository) GetPromptFeedbackStats(ctx context.Context, prompt string, from, to time.Time) ([]domain.PromptVersionStats, error) {
+	ratedAs := func(rating domain.FeedbackRating) bson.M {
+		return bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$feedback.rating", rating}}, 1, 0}}}
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"type":      domain.MessageTypeLLM,
+			"createdAt": bson.M{"$gte": from, "$lt": to},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":     bson.M{"$ifNull": bson.A{"$promptVersions." + prompt, 0}},
+			"answers": bson.M{"$sum": 1},
+			"up":      ratedAs(domain.FeedbackUp),
+			"down":    ratedAs(domain.FeedbackDown),
+		}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	}
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate prompt feedback: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	stats := []domain.PromptVersionStats{}
+	if err := cursor.All(ctx, &stats); err != nil {
+		return nil, fmt.Errorf("failed to decode prompt feedback: %w", err)
+	}
+	return stats, nil
+}
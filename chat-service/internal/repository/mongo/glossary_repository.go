@@ -0,0 +1,89 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+)
+
+type GlossaryRepository struct {
+	collection *mongo.Collection
+}
+
+func NewGlossaryRepository(db *mongo.Database) domain.GlossaryRepository {
+	return &GlossaryRepository{collection: db.Collection("glossary_terms")}
+}
+
+func (r *GlossaryRepository) CreateTerm(ctx context.Context, term *domain.GlossaryTerm) error {
+	now := time.Now()
+	term.ID = primitive.NewObjectID()
+	term.CreatedAt, term.UpdatedAt = now, now
+	if _, err := r.collection.InsertOne(ctx, term); err != nil {
+		// The unique key index rejects a term that is already in the glossary
+		if mongo.IsDuplicateKeyError(err) {
+			return domain.ErrDuplicateGlossaryTerm
+		}
+		return fmt.Errorf("failed to create glossary term in MongoDB: %w", err)
+	}
+	return nil
+}
+
+func (r *GlossaryRepository) UpdateTerm(ctx context.Context, term *domain.GlossaryTerm) error {
+	term.UpdatedAt = time.Now()
+	update := bson.M{"$set": bson.M{
+		"term":         term.Term,
+		"key":          term.Key,
+		"translations": term.Translations,
+		"definitions":  term.Definitions,
+		"updatedBy":    term.UpdatedBy,
+		"updatedAt":    term.UpdatedAt,
+	}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": term.ID}, update, opts).Decode(term)
+	if err == mongo.ErrNoDocuments {
+		return domain.ErrGlossaryTermNotFound
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		return domain.ErrDuplicateGlossaryTerm
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update glossary term in MongoDB: %w", err)
+	}
+	return nil
+}
+
+func (r *GlossaryRepository) DeleteTerm(ctx context.Context, id string) error {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return domain.ErrGlossaryTermNotFound
+	}
+	res, err := r.collection.DeleteOne(ctx, bson.M{"_id": objID})
+	if err != nil {
+		return fmt.Errorf("failed to delete glossary term from MongoDB: %w", err)
+	}
+	if res.DeletedCount == 0 {
+		return domain.ErrGlossaryTermNotFound
+	}
+	return nil
+}
+
+func (r *GlossaryRepository) GetTerms(ctx context.Context) ([]domain.GlossaryTerm, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "key", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find glossary terms: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	terms := []domain.GlossaryTerm{}
+	if err := cursor.All(ctx, &terms); err != nil {
+		return nil, fmt.Errorf("failed to decode glossary terms: %w", err)
+	}
+	return terms, nil
+}
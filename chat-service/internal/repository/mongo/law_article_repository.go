@@ -0,0 +1,85 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+)
+
+type LawArticleRepository struct {
+	collection *mongo.Collection
+}
+
+func NewLawArticleRepository(db *mongo.Database) domain.LawArticleCache {
+	return &LawArticleRepository{collection: db.Collection("law_articles")}
+}
+
+type lawArticleDocument struct {
+	ID            string    `bson:"_id"` // source and article number, so re-retrieved articles replace their copy
+	Source        string    `bson:"source"`
+	ArticleNumber string    `bson:"articleNumber"`
+	Content       string    `bson:"content"`
+	Topics        []string  `bson:"topics,omitempty"`
+	UpdatedAt     time.Time `bson:"updatedAt"`
+}
+
+func (r *LawArticleRepository) SaveArticles(ctx context.Context, articles []domain.RAGSource) error {
+	var models []mongo.WriteModel
+	now := time.Now()
+	for _, a := range articles {
+		if a.Content == "" {
+			continue
+		}
+		doc := lawArticleDocument{
+			ID:            a.Source + "|" + a.ArticleNumber,
+			Source:        a.Source,
+			ArticleNumber: a.ArticleNumber,
+			Content:       a.Content,
+			Topics:        a.Topics,
+			UpdatedAt:     now,
+		}
+		models = append(models, mongo.NewReplaceOneModel().SetFilter(bson.M{"_id": doc.ID}).SetReplacement(doc).SetUpsert(true))
+	}
+	if len(models) == 0 {
+		return nil
+	}
+	if _, err := r.collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false)); err != nil {
+		return fmt.Errorf("failed to cache law articles in MongoDB: %w", err)
+	}
+	return nil
+}
+
+// SearchArticles relies on the text index over content, source and topics created by EnsureIndexes.
+func (r *LawArticleRepository) SearchArticles(ctx context.Context, query string, k int) ([]domain.RAGSource, error) {
+	score := bson.M{"$meta": "textScore"}
+	opts := options.Find().
+		SetProjection(bson.M{"score": score}).
+		SetSort(bson.D{{Key: "score", Value: score}}).
+		SetLimit(int64(k))
+	cursor, err := r.collection.Find(ctx, bson.M{"$text": bson.M{"$search": query}}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search law articles in MongoDB: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var docs []lawArticleDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to decode law articles: %w", err)
+	}
+	articles := make([]domain.RAGSource, len(docs))
+	for i, doc := range docs {
+		articles[i] = domain.RAGSource{
+			Content:       doc.Content,
+			Source:        doc.Source,
+			ArticleNumber: doc.ArticleNumber,
+			Topics:        doc.Topics,
+		}
+	}
+	return articles, nil
+}
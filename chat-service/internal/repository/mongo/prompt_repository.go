@@ -0,0 +1,133 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+)
+
+// maxVersionInsertAttempts bounds retries when two admins create a version of the same prompt at once.
+const maxVersionInsertAttempts = 3
+
+type PromptRepository struct {
+	versions *mongo.Collection
+	rollouts *mongo.Collection
+}
+
+func NewPromptRepository(db *mongo.Database) domain.PromptRepository {
+	return &PromptRepository{
+		versions: db.Collection("prompt_versions"),
+		rollouts: db.Collection("prompt_rollouts"),
+	}
+}
+
+func (r *PromptRepository) CreateVersion(ctx context.Context, v *domain.PromptVersion) error {
+	if v.CreatedAt.IsZero() {
+		v.CreatedAt = time.Now()
+	}
+	for attempt := 1; ; attempt++ {
+		var latest domain.PromptVersion
+		opts := options.FindOne().SetSort(bson.D{{Key: "version", Value: -1}}).SetProjection(bson.M{"version": 1})
+		err := r.versions.FindOne(ctx, bson.M{"name": v.Name}, opts).Decode(&latest)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return fmt.Errorf("failed to find latest prompt version: %w", err)
+		}
+		v.ID = primitive.NewObjectID()
+		v.Version = latest.Version + 1
+		_, err = r.versions.InsertOne(ctx, v)
+		if err == nil {
+			return nil
+		}
+		// The unique (name, version) index rejects a number another replica took in the meantime
+		if !mongo.IsDuplicateKeyError(err) || attempt == maxVersionInsertAttempts {
+			return fmt.Errorf("failed to create prompt version in MongoDB: %w", err)
+		}
+	}
+}
+
+func (r *PromptRepository) GetVersions(ctx context.Context, name string, versions []int) ([]domain.PromptVersion, error) {
+	filter := bson.M{"name": name}
+	if versions != nil {
+		filter["version"] = bson.M{"$in": versions}
+	}
+	cursor, err := r.versions.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "version", Value: -1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find prompt versions: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	result := []domain.PromptVersion{}
+	if err := cursor.All(ctx, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode prompt versions: %w", err)
+	}
+	return result, nil
+}
+
+func (r *PromptRepository) SaveRollout(ctx context.Context, rollout *domain.PromptRollout) error {
+	if rollout.ID.IsZero() {
+		rollout.ID = primitive.NewObjectID()
+	}
+	if rollout.CreatedAt.IsZero() {
+		rollout.CreatedAt = time.Now()
+	}
+	if _, err := r.rollouts.InsertOne(ctx, rollout); err != nil {
+		return fmt.Errorf("failed to save prompt rollout in MongoDB: %w", err)
+	}
+	return nil
+}
+
+func (r *PromptRepository) GetActiveRollouts(ctx context.Context) ([]domain.PromptRollout, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"rolledBackAt": bson.M{"$exists": false}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "name", Value: 1}, {Key: "createdAt", Value: -1}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$name", "rollout": bson.M{"$first": "$$ROOT"}}}},
+		{{Key: "$replaceRoot", Value: bson.M{"newRoot": "$rollout"}}},
+	}
+	cursor, err := r.rollouts.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate active prompt rollouts: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	result := []domain.PromptRollout{}
+	if err := cursor.All(ctx, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode prompt rollouts: %w", err)
+	}
+	return result, nil
+}
+
+func (r *PromptRepository) GetRollouts(ctx context.Context, name string, limit int) ([]domain.PromptRollout, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}).SetLimit(int64(limit))
+	cursor, err := r.rollouts.Find(ctx, bson.M{"name": name}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find prompt rollouts: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	result := []domain.PromptRollout{}
+	if err := cursor.All(ctx, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode prompt rollouts: %w", err)
+	}
+	return result, nil
+}
+
+func (r *PromptRepository) RollBack(ctx context.Context, name, by string) error {
+	filter := bson.M{"name": name, "rolledBackAt": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"rolledBackAt": time.Now(), "rolledBackBy": by}}
+	opts := options.FindOneAndUpdate().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+	err := r.rollouts.FindOneAndUpdate(ctx, filter, update, opts).Err()
+	if err == mongo.ErrNoDocuments {
+		return domain.ErrNoPromptRollout
+	}
+	if err != nil {
+		return fmt.Errorf("failed to roll back prompt in MongoDB: %w", err)
+	}
+	return nil
+}
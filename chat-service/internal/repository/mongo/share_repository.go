@@ -0,0 +1,97 @@
+package mongo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+)
+
+type ShareLinkRepository struct {
+	collection *mongo.Collection
+}
+
+func NewShareLinkRepository(db *mongo.Database) domain.ShareLinkRepository {
+	return &ShareLinkRepository{collection: db.Collection("share_links")}
+}
+
+func (r *ShareLinkRepository) CreateShareLink(ctx context.Context, link *domain.ShareLink) error {
+	if link.ID.IsZero() {
+		link.ID = primitive.NewObjectID()
+	}
+	if _, err := r.collection.InsertOne(ctx, link); err != nil {
+		return fmt.Errorf("failed to create share link in MongoDB: %w", err)
+	}
+	return nil
+}
+
+func (r *ShareLinkRepository) GetShareLinkByToken(ctx context.Context, token string) (*domain.ShareLink, error) {
+	var link domain.ShareLink
+	err := r.collection.FindOne(ctx, bson.M{"token": token}).Decode(&link)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, domain.ErrShareLinkNotFound
+		}
+		return nil, fmt.Errorf("failed to get share link from MongoDB: %w", err)
+	}
+	return &link, nil
+}
+
+// activeFilter matches links that are neither revoked nor expired at now.
+func activeFilter(now time.Time) bson.M {
+	return bson.M{
+		"revokedAt": bson.M{"$exists": false},
+		"$or": bson.A{
+			bson.M{"expiresAt": bson.M{"$exists": false}},
+			bson.M{"expiresAt": bson.M{"$gt": now}},
+		},
+	}
+}
+
+func (r *ShareLinkRepository) GetActiveShareLinksByUserID(ctx context.Context, userID string) ([]domain.ShareLink, error) {
+	filter := activeFilter(time.Now())
+	filter["userId"] = userID
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find share links by user ID: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	links := []domain.ShareLink{}
+	if err := cursor.All(ctx, &links); err != nil {
+		return nil, fmt.Errorf("failed to decode share links: %w", err)
+	}
+	return links, nil
+}
+
+func (r *ShareLinkRepository) RevokeShareLink(ctx context.Context, userID, id string) error {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return domain.ErrShareLinkNotFound
+	}
+	filter := activeFilter(time.Now())
+	filter["_id"] = objID
+	filter["userId"] = userID
+	res, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"revokedAt": time.Now()}})
+	if err != nil {
+		return fmt.Errorf("failed to revoke share link in MongoDB: %w", err)
+	}
+	if res.MatchedCount == 0 {
+		return domain.ErrShareLinkNotFound
+	}
+	return nil
+}
+
+func (r *ShareLinkRepository) DeleteShareLinksBySessionID(ctx context.Context, sessionID string) error {
+	if _, err := r.collection.DeleteMany(ctx, bson.M{"sessionId": sessionID}); err != nil {
+		return fmt.Errorf("failed to delete share links from MongoDB: %w", err)
+	}
+	return nil
+}
@@ -29,6 +29,10 @@ func (r *quizRepository) quizzesCollection() *mongo.Collection {
 	return r.db.Collection("quizzes")
 }
 
+func (r *quizRepository) quizAttemptsCollection() *mongo.Collection {
+	return r.db.Collection("quiz_attempts")
+}
+
 // --- Category Methods ---
 
 func (r *quizRepository) CreateCategory(ctx context.Context, category *domain.QuizCategory) error {
@@ -44,7 +48,7 @@ func (r *quizRepository) GetCategoryByID(ctx context.Context, id primitive.Objec
 	err := r.quizCategoriesCollection().FindOne(ctx, bson.M{"_id": id}).Decode(&category)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, errors.New("category not found")
+			return nil, domain.ErrCategoryNotFound
 		}
 		return nil, err
 	}
@@ -113,6 +117,9 @@ func (r *quizRepository) CreateQuiz(ctx context.Context, quiz *domain.Quiz) erro
 	if err != nil {
 		return err
 	}
+	if quiz.IsDraft() {
+		return nil // counted when published
+	}
 	// Increment total_quizzes in category
 	_, err = r.quizCategoriesCollection().UpdateOne(ctx, bson.M{"_id": quiz.CategoryID}, bson.M{"$inc": bson.M{"total_quizzes": 1}})
 	return err
@@ -123,7 +130,7 @@ func (r *quizRepository) GetQuizByID(ctx context.Context, id primitive.ObjectID)
 	err := r.quizzesCollection().FindOne(ctx, bson.M{"_id": id}).Decode(&quiz)
 	if err != nil {
 		if errors.Is(err, mongo.ErrNoDocuments) {
-			return nil, errors.New("quiz not found")
+			return nil, domain.ErrQuizNotFound
 		}
 		return nil, err
 	}
@@ -135,7 +142,7 @@ func (r *quizRepository) GetQuizzesByCategoryID(ctx context.Context, categoryID
 	findOptions.SetSkip((page - 1) * limit)
 	findOptions.SetLimit(limit)
 
-	filter := bson.M{"category_id": categoryID}
+	filter := bson.M{"category_id": categoryID, "status": bson.M{"$ne": domain.QuizStatusDraft}}
 	cursor, err := r.quizzesCollection().Find(ctx, filter, findOptions)
 	if err != nil {
 		return nil, 0, err
@@ -159,9 +166,10 @@ func (r *quizRepository) UpdateQuiz(ctx context.Context, quiz *domain.Quiz) erro
 	quiz.UpdatedAt = time.Now()
 	update := bson.M{
 		"$set": bson.M{
-			"name":        quiz.Name,
-			"description": quiz.Description,
-			"updated_at":  quiz.UpdatedAt,
+			"name":           quiz.Name,
+			"description":    quiz.Description,
+			"pass_threshold": quiz.PassThreshold,
+			"updated_at":     quiz.UpdatedAt,
 		},
 	}
 	_, err := r.quizzesCollection().UpdateOne(ctx, bson.M{"_id": quiz.ID}, update)
@@ -178,11 +186,58 @@ func (r *quizRepository) DeleteQuiz(ctx context.Context, id primitive.ObjectID)
 	if err != nil {
 		return err
 	}
+	if quiz.IsDraft() {
+		return nil
+	}
 	// Decrement total_quizzes in category
 	_, err = r.quizCategoriesCollection().UpdateOne(ctx, bson.M{"_id": quiz.CategoryID}, bson.M{"$inc": bson.M{"total_quizzes": -1}})
 	return err
 }
 
+func (r *quizRepository) GetDraftQuizzes(ctx context.Context, page, limit int64) ([]*domain.Quiz, int64, error) {
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "created_at", Value: -1}})
+	findOptions.SetSkip((page - 1) * limit)
+	findOptions.SetLimit(limit)
+
+	filter := bson.M{"status": domain.QuizStatusDraft}
+	cursor, err := r.quizzesCollection().Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var quizzes []*domain.Quiz
+	if err = cursor.All(ctx, &quizzes); err != nil {
+		return nil, 0, err
+	}
+
+	total, err := r.quizzesCollection().CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return quizzes, total, nil
+}
+
+func (r *quizRepository) PublishQuiz(ctx context.Context, id primitive.ObjectID) error {
+	update := bson.M{"$set": bson.M{"status": domain.QuizStatusPublished, "updated_at": time.Now()}}
+	res, err := r.quizzesCollection().UpdateOne(ctx, bson.M{"_id": id, "status": domain.QuizStatusDraft}, update)
+	if err != nil {
+		return err
+	}
+	if res.ModifiedCount == 0 {
+		return nil
+	}
+	// Drafts are not counted in total_quizzes until they are published
+	quiz, err := r.GetQuizByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	_, err = r.quizCategoriesCollection().UpdateOne(ctx, bson.M{"_id": quiz.CategoryID}, bson.M{"$inc": bson.M{"total_quizzes": 1}})
+	return err
+}
+
 // --- Question Methods ---
 
 func (r *quizRepository) AddQuestionToQuiz(ctx context.Context, quizID primitive.ObjectID, question *domain.Question) error {
@@ -231,3 +286,73 @@ func (r *quizRepository) DeleteQuestionFromQuiz(ctx context.Context, quizID, que
 	_, err := r.quizzesCollection().UpdateOne(ctx, bson.M{"_id": quizID}, update)
 	return err
 }
+
+// --- Attempt Methods ---
+
+func (r *quizRepository) CreateAttempt(ctx context.Context, attempt *domain.QuizAttempt) error {
+	attempt.ID = primitive.NewObjectID()
+	_, err := r.quizAttemptsCollection().InsertOne(ctx, attempt)
+	return err
+}
+
+func (r *quizRepository) GetAttemptsByUser(ctx context.Context, userID string, quizID primitive.ObjectID, page, limit int64) ([]*domain.QuizAttempt, int64, error) {
+	findOptions := options.Find()
+	findOptions.SetSort(bson.D{{Key: "submitted_at", Value: -1}})
+	findOptions.SetSkip((page - 1) * limit)
+	findOptions.SetLimit(limit)
+
+	filter := bson.M{"user_id": userID}
+	if !quizID.IsZero() {
+		filter["quiz_id"] = quizID
+	}
+	cursor, err := r.quizAttemptsCollection().Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var attempts []*domain.QuizAttempt
+	if err = cursor.All(ctx, &attempts); err != nil {
+		return nil, 0, err
+	}
+
+	total, err := r.quizAttemptsCollection().CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return attempts, total, nil
+}
+
+func (r *quizRepository) GetQuizProgressByUser(ctx context.Context, userID string, categoryID primitive.ObjectID) ([]*domain.QuizProgress, error) {
+	match := bson.M{"user_id": userID}
+	if !categoryID.IsZero() {
+		match["category_id"] = categoryID
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$sort", Value: bson.D{{Key: "submitted_at", Value: 1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":             "$quiz_id",
+			"quiz_name":       bson.M{"$last": "$quiz_name"},
+			"category_id":     bson.M{"$last": "$category_id"},
+			"attempts":        bson.M{"$sum": 1},
+			"best_score":      bson.M{"$max": "$score"},
+			"best_percentage": bson.M{"$max": "$percentage"},
+			"passed":          bson.M{"$max": "$passed"},
+			"last_attempt_at": bson.M{"$last": "$submitted_at"},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "last_attempt_at", Value: -1}}}},
+	}
+	cursor, err := r.quizAttemptsCollection().Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var progress []*domain.QuizProgress
+	if err = cursor.All(ctx, &progress); err != nil {
+		return nil, err
+	}
+	return progress, nil
+}
@@ -96,11 +96,25 @@ func (r *RedisSessionRepository) UpdateSession(ctx context.Context, session *dom
 	return nil
 }
 
-func (r *RedisSessionRepository) GetSessionsByUserID(ctx context.Context, userID string, page, limit int) ([]*domain.Session, int, error) {
+func (r *RedisSessionRepository) GetSessionsByUserID(ctx context.Context, userID string, archived bool, page, limit int) ([]*domain.Session, int, error) {
 	// Not efficient for Redis. This method is handled by MongoDB repository.
 	return nil, 0, fmt.Errorf("GetSessionsByUserID not implemented for Redis repository (use MongoDB for this)")
 }
 
+func (r *RedisSessionRepository) GetSessionIDsByUserID(ctx context.Context, userID string) ([]string, error) {
+	return nil, fmt.Errorf("GetSessionIDsByUserID not implemented for Redis repository (use MongoDB for this)")
+}
+
+func (r *RedisSessionRepository) DeleteSession(ctx context.Context, id string) error {
+	if err := r.client.Del(ctx, sessionKey(id)).Err(); err != nil {
+		return fmt.Errorf("failed to delete session from Redis: %w", err)
+	}
+	if err := r.client.SRem(ctx, activeUserSessionsKey, id).Err(); err != nil {
+		return fmt.Errorf("failed to remove session ID from active set: %w", err)
+	}
+	return nil
+}
+
 func (r *RedisSessionRepository) SetSessionTTL(ctx context.Context, sessionID string, ttl time.Duration) error {
 	_, err := r.client.Expire(ctx, sessionKey(sessionID), ttl).Result()
 	if err != nil {
@@ -128,6 +142,27 @@ func (r *RedisSessionRepository) RemoveUserSessionID(ctx context.Context, sessio
 	return err
 }
 
+func deletedSessionKey(sessionID string) string {
+	return fmt.Sprintf("deleted_session:%s", sessionID)
+}
+
+// MarkSessionDeleted keeps the tombstone as long as the session's chat history could have lived in Redis
+func (r *RedisSessionRepository) MarkSessionDeleted(ctx context.Context, sessionID string) error {
+	ttl := time.Duration(r.cfg.SessionTTLSeconds) * time.Second
+	if err := r.client.Set(ctx, deletedSessionKey(sessionID), 1, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to mark session %s as deleted in Redis: %w", sessionID, err)
+	}
+	return nil
+}
+
+func (r *RedisSessionRepository) IsSessionDeleted(ctx context.Context, sessionID string) (bool, error) {
+	n, err := r.client.Exists(ctx, deletedSessionKey(sessionID)).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check whether session %s was deleted: %w", sessionID, err)
+	}
+	return n > 0, nil
+}
+
 // ------------------------- CHAT REPOSITORY --------------------------------
 
 type RedisChatRepository struct {
@@ -143,6 +178,10 @@ func chatHistoryKey(sessionID string) string {
 	return fmt.Sprintf("chat_history:%s", sessionID)
 }
 
+func chatSummaryKey(sessionID string) string {
+	return fmt.Sprintf("chat_summary:%s", sessionID)
+}
+
 func (r *RedisChatRepository) GetChatHistory(ctx context.Context, sessionID string, limit int) ([]domain.ChatEntry, error) {
 	var cmds []string
 	var err error
@@ -161,19 +200,30 @@ func (r *RedisChatRepository) GetChatHistory(ctx context.Context, sessionID stri
 
 	var history []domain.ChatEntry
 	for _, cmd := range cmds {
-		entry := domain.ChatEntry{}
-		if err := json.Unmarshal([]byte(cmd), &entry); err != nil {
+		entry, err := decodeChatEntry(cmd)
+		if err != nil {
 			return nil, fmt.Errorf("failed to unmarshal chat entry from Redis: %w", err)
 		}
-		// Ensure MongoID and ID are populated from unmarshalled values
-		if !entry.MongoID.IsZero() {
-			entry.ID = entry.MongoID.Hex()
-		}
 		history = append(history, entry)
 	}
 	return history, nil
 }
 
+// decodeChatEntry unmarshals a stored entry and restores its MongoID from the public ID,
+// since MongoID is not part of the JSON representation.
+func decodeChatEntry(data string) (domain.ChatEntry, error) {
+	entry := domain.ChatEntry{}
+	if err := json.Unmarshal([]byte(data), &entry); err != nil {
+		return entry, err
+	}
+	if entry.ID != "" {
+		if objID, err := primitive.ObjectIDFromHex(entry.ID); err == nil {
+			entry.MongoID = objID
+		}
+	}
+	return entry, nil
+}
+
 func (r *RedisChatRepository) SaveChatEntry(ctx context.Context, entry *domain.ChatEntry) error {
 	// For new entries, generate a MongoDB ObjectID now. This ID will be used when saving to MongoDB.
 	if entry.MongoID.IsZero() {
@@ -194,10 +244,6 @@ func (r *RedisChatRepository) SaveChatEntry(ctx context.Context, entry *domain.C
 	// Append to list
 	_, err = r.client.RPush(ctx, chatHistoryKey(entry.SessionID), data).Result()
 	if err != nil {
-		// If context was canceled, return a more specific error
-		if err == context.Canceled {
-			return fmt.Errorf("failed to save chat entry to Redis: context canceled (client disconnected or request timed out)")
-		}
 		return fmt.Errorf("failed to save chat entry to Redis: %w", err)
 	}
 
@@ -206,6 +252,10 @@ func (r *RedisChatRepository) SaveChatEntry(ctx context.Context, entry *domain.C
 	if err != nil {
 		return fmt.Errorf("failed to set TTL for chat history in Redis: %w", err)
 	}
+	// Keep the session's summary alive as long as its history
+	if err := r.client.Expire(ctx, chatSummaryKey(entry.SessionID), time.Duration(r.cfg.SessionTTLSeconds)*time.Second).Err(); err != nil {
+		log.Printf("Warning: Failed to refresh TTL for chat summary in Redis: %v", err)
+	}
 
 	return nil
 }
@@ -222,11 +272,11 @@ func (r *RedisChatRepository) GetUnsyncedChatEntries(ctx context.Context, sessio
 
 	var unsyncedEntries []domain.ChatEntry
 	for _, cmd := range cmds {
-		entry := domain.ChatEntry{}
-		if err := json.Unmarshal([]byte(cmd), &entry); err != nil {
+		entry, err := decodeChatEntry(cmd)
+		if err != nil {
 			return nil, fmt.Errorf("failed to unmarshal chat entry for sync: %w", err)
 		}
-		if !entry.SyncedToDB {
+		if !entry.SyncedToDB && !entry.MongoID.IsZero() {
 			unsyncedEntries = append(unsyncedEntries, entry)
 		}
 	}
@@ -234,54 +284,149 @@ func (r *RedisChatRepository) GetUnsyncedChatEntries(ctx context.Context, sessio
 }
 
 func (r *RedisChatRepository) MarkChatEntriesAsSynced(ctx context.Context, sessionID string, entryMongoIDs []string) error {
-	// This is an inefficient operation for a Redis List as it requires fetching, modifying, and re-writing.
-	// For production, consider a different Redis data structure (e.g., Hashes for entries + a List of IDs).
-
-	pipe := r.client.Pipeline()
-	defer pipe.Close()
-
-	// 1. Get all elements
-	cmds, err := pipe.LRange(ctx, chatHistoryKey(sessionID), 0, -1).Result()
-	if err != nil {
-		return fmt.Errorf("failed to get chat history for marking sync: %w", err)
+	toMark := make(map[string]struct{}, len(entryMongoIDs))
+	for _, id := range entryMongoIDs {
+		toMark[id] = struct{}{}
 	}
+	key := chatHistoryKey(sessionID)
 
-	updatedList := make([]interface{}, 0, len(cmds))
-	for _, cmd := range cmds {
-		entry := domain.ChatEntry{}
-		if err := json.Unmarshal([]byte(cmd), &entry); err != nil {
-			// Log error, but try to continue
-			log.Printf("Warning: Failed to unmarshal chat entry during sync marking: %v", err)
-			updatedList = append(updatedList, cmd) // Keep original if unmarshal fails
-			continue
+	// Entries are only ever appended, so their indexes are stable. WATCH the list so that a
+	// concurrent append aborts the transaction instead of being overwritten, and update the
+	// matching elements in place with LSET, which also preserves the list's TTL.
+	markFn := func(tx *redis.Tx) error {
+		cmds, err := tx.LRange(ctx, key, 0, -1).Result()
+		if err != nil {
+			return fmt.Errorf("failed to get chat history for marking sync: %w", err)
 		}
 
-		// Check if this entry's MongoID is in the list of IDs to mark as synced
-		for _, mongoIDToSync := range entryMongoIDs {
-			if entry.MongoID.Hex() == mongoIDToSync {
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, cmd := range cmds {
+				entry, err := decodeChatEntry(cmd)
+				if err != nil {
+					log.Printf("Warning: Failed to unmarshal chat entry during sync marking: %v", err)
+					continue
+				}
+				if _, ok := toMark[entry.ID]; !ok || entry.SyncedToDB {
+					continue
+				}
 				entry.SyncedToDB = true
-				break
+				updatedData, err := json.Marshal(entry)
+				if err != nil {
+					log.Printf("Warning: Failed to marshal chat entry during sync marking: %v", err)
+					continue
+				}
+				pipe.LSet(ctx, key, int64(i), updatedData)
 			}
+			return nil
+		})
+		return err
+	}
+
+	var err error
+	for attempt := 0; attempt < 3; attempt++ {
+		err = r.client.Watch(ctx, markFn, key)
+		if err != redis.TxFailedErr {
+			break
 		}
-		updatedData, err := json.Marshal(entry)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to mark chat entries as synced in Redis: %w", err)
+	}
+	return nil
+}
+
+func (r *RedisChatRepository) GetSummary(ctx context.Context, sessionID string) (*domain.ChatEntry, error) {
+	data, err := r.client.Get(ctx, chatSummaryKey(sessionID)).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat summary from Redis: %w", err)
+	}
+	summary, err := decodeChatEntry(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal chat summary from Redis: %w", err)
+	}
+	return &summary, nil
+}
+
+func (r *RedisChatRepository) SaveSummary(ctx context.Context, summary *domain.ChatEntry) error {
+	if summary.MongoID.IsZero() {
+		summary.MongoID = primitive.NewObjectID()
+		summary.ID = summary.MongoID.Hex()
+	}
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return fmt.Errorf("failed to marshal chat summary for Redis: %w", err)
+	}
+	ttl := time.Duration(r.cfg.SessionTTLSeconds) * time.Second
+	if err := r.client.Set(ctx, chatSummaryKey(summary.SessionID), data, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to save chat summary to Redis: %w", err)
+	}
+	return nil
+}
+
+func (r *RedisChatRepository) DeleteChatHistory(ctx context.Context, sessionID string) error {
+	if err := r.client.Del(ctx, chatHistoryKey(sessionID), chatSummaryKey(sessionID)).Err(); err != nil {
+		return fmt.Errorf("failed to delete chat history from Redis: %w", err)
+	}
+	return nil
+}
+
+func (r *RedisChatRepository) SearchChatEntries(ctx context.Context, sessionIDs []string, query string, limit int) ([]domain.ChatSearchResult, error) {
+	return nil, fmt.Errorf("SearchChatEntries not implemented for Redis repository (use MongoDB for this)")
+}
+
+// SetFeedback updates the stored answer in place, so the rating travels with it when the sync worker
+// copies the entry to MongoDB.
+func (r *RedisChatRepository) SetFeedback(ctx context.Context, sessionID, entryID string, feedback *domain.AnswerFeedback) error {
+	key := chatHistoryKey(sessionID)
+	found := false
+	setFn := func(tx *redis.Tx) error {
+		cmds, err := tx.LRange(ctx, key, 0, -1).Result()
 		if err != nil {
-			log.Printf("Warning: Failed to marshal chat entry during sync marking: %v", err)
-			updatedList = append(updatedList, cmd) // Keep original if marshal fails
-			continue
+			return fmt.Errorf("failed to get chat history for feedback: %w", err)
+		}
+		for i, cmd := range cmds {
+			entry, err := decodeChatEntry(cmd)
+			if err != nil || entry.ID != entryID || entry.Type != domain.MessageTypeLLM {
+				continue
+			}
+			found = true
+			entry.Feedback = feedback
+			updatedData, err := json.Marshal(entry)
+			if err != nil {
+				return fmt.Errorf("failed to marshal chat entry with feedback: %w", err)
+			}
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.LSet(ctx, key, int64(i), updatedData)
+				return nil
+			})
+			return err
 		}
-		updatedList = append(updatedList, updatedData)
+		return nil
 	}
 
-	// 2. Delete the old list
-	pipe.Del(ctx, chatHistoryKey(sessionID))
-	// 3. Push the updated elements
-	if len(updatedList) > 0 {
-		pipe.RPush(ctx, chatHistoryKey(sessionID), updatedList...)
+	var err error
+	for attempt := 0; attempt < 3; attempt++ {
+		err = r.client.Watch(ctx, setFn, key)
+		if err != redis.TxFailedErr {
+			break
+		}
 	}
-
-	_, err = pipe.Exec(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to mark chat entries as synced in Redis: %w", err)
+		return fmt.Errorf("failed to save feedback to Redis: %w", err)
+	}
+	if !found {
+		return domain.ErrChatEntryNotFound
 	}
 	return nil
 }
+
+func (r *RedisChatRepository) GetFeedbackReport(ctx context.Context, filter domain.FeedbackReportFilter) (*domain.FeedbackReport, error) {
+	return nil, fmt.Errorf("GetFeedbackReport not implemented for Redis repository (use MongoDB for this)")
+}
+
+func (r *RedisChatRepository) GetPromptFeedbackStats(ctx context.Context, prompt string, from, to time.Time) ([]domain.PromptVersionStats, error) {
+	return nil, fmt.Errorf("GetPromptFeedbackStats not implemented for Redis repository (use MongoDB for this)")
+}
@@ -0,0 +1,67 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+)
+
+// releaseLockScript deletes the lock only if it is still held by the caller's token.
+var releaseLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// refreshLockScript extends the lock TTL only if it is still held by the caller's token.
+var refreshLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+type RedisLock struct {
+	client *redis.Client
+}
+
+func NewRedisLock(client *redis.Client) domain.DistributedLock {
+	return &RedisLock{client: client}
+}
+
+func lockKey(key string) string {
+	return fmt.Sprintf("lock:%s", key)
+}
+
+func (l *RedisLock) Acquire(ctx context.Context, key string, ttl time.Duration) (string, bool, error) {
+	token := uuid.NewString()
+	ok, err := l.client.SetNX(ctx, lockKey(key), token, ttl).Result()
+	if err != nil {
+		return "", false, fmt.Errorf("failed to acquire lock %s: %w", key, err)
+	}
+	if !ok {
+		return "", false, nil
+	}
+	return token, true, nil
+}
+
+func (l *RedisLock) Refresh(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	res, err := refreshLockScript.Run(ctx, l.client, []string{lockKey(key)}, token, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return false, fmt.Errorf("failed to refresh lock %s: %w", key, err)
+	}
+	return res == 1, nil
+}
+
+func (l *RedisLock) Release(ctx context.Context, key, token string) error {
+	if _, err := releaseLockScript.Run(ctx, l.client, []string{lockKey(key)}, token).Result(); err != nil {
+		return fmt.Errorf("failed to release lock %s: %w", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+)
+
+// consumeQuotaScript increments every counter by ARGV[1] only if none would exceed its limit.
+// For key i, ARGV[2i] is the limit (0 = unlimited) and ARGV[2i+1] the period end in ms since epoch.
+// It returns the 1-based index of the first exceeded limit, or 0 on success.
+var consumeQuotaScript = redis.NewScript(`
+local amount = tonumber(ARGV[1])
+for i = 1, #KEYS do
+	local limit = tonumber(ARGV[i * 2])
+	if limit > 0 then
+		local used = tonumber(redis.call("GET", KEYS[i]) or "0")
+		if used + amount > limit then
+			return i
+		end
+	end
+end
+for i = 1, #KEYS do
+	redis.call("INCRBY", KEYS[i], amount)
+	redis.call("PEXPIREAT", KEYS[i], ARGV[i * 2 + 1])
+end
+return 0
+`)
+
+// consumeQuotaUpToScript increments every counter by ARGV[1], or by as much as the tightest limit still
+// allows, with the same arguments as consumeQuotaScript. It returns the 1-based index of the limit that
+// cut the amount short, or 0 if all of it was consumed.
+var consumeQuotaUpToScript = redis.NewScript(`
+local amount = tonumber(ARGV[1])
+local exceeded = 0
+for i = 1, #KEYS do
+	local limit = tonumber(ARGV[i * 2])
+	if limit > 0 then
+		local left = math.max(limit - tonumber(redis.call("GET", KEYS[i]) or "0"), 0)
+		if left < amount then
+			amount = left
+			exceeded = i
+		end
+	end
+end
+for i = 1, #KEYS do
+	redis.call("INCRBY", KEYS[i], amount)
+	redis.call("PEXPIREAT", KEYS[i], ARGV[i * 2 + 1])
+end
+return exceeded
+`)
+
+// refundQuotaScript decrements every counter by ARGV[1], stopping at zero. Counters that no longer
+// exist are left alone, so a refund after the period ended does nothing.
+var refundQuotaScript = redis.NewScript(`
+local amount = tonumber(ARGV[1])
+for i = 1, #KEYS do
+	local used = tonumber(redis.call("GET", KEYS[i]) or "0")
+	if used > 0 then
+		redis.call("DECRBY", KEYS[i], math.min(used, amount))
+	end
+end
+return 0
+`)
+
+type RedisQuotaRepository struct {
+	client *redis.Client
+}
+
+func NewRedisQuotaRepository(client *redis.Client) domain.QuotaRepository {
+	return &RedisQuotaRepository{client: client}
+}
+
+// Counters are keyed by the end of their period, so a new period starts from zero automatically
+func quotaKey(subject string, limit domain.QuotaLimit) string {
+	return fmt.Sprintf("quota:%s:%s:%s:%d", subject, limit.Kind, limit.Period, limit.ResetAt.Unix())
+}
+
+func (r *RedisQuotaRepository) Consume(ctx context.Context, subject string, amount int64, limits []domain.QuotaLimit) (*domain.QuotaLimit, error) {
+	return r.run(ctx, consumeQuotaScript, subject, amount, limits)
+}
+
+func (r *RedisQuotaRepository) ConsumeUpTo(ctx context.Context, subject string, amount int64, limits []domain.QuotaLimit) (*domain.QuotaLimit, error) {
+	return r.run(ctx, consumeQuotaUpToScript, subject, amount, limits)
+}
+
+func (r *RedisQuotaRepository) Refund(ctx context.Context, subject string, amount int64, limits []domain.QuotaLimit) error {
+	if len(limits) == 0 {
+		return nil
+	}
+	keys := make([]string, len(limits))
+	for i, limit := range limits {
+		keys[i] = quotaKey(subject, limit)
+	}
+	if err := refundQuotaScript.Run(ctx, r.client, keys, amount).Err(); err != nil {
+		return fmt.Errorf("failed to refund quota in Redis: %w", err)
+	}
+	return nil
+}
+
+// run executes a consume script and returns the limit it reports as exceeded, if any
+func (r *RedisQuotaRepository) run(ctx context.Context, script *redis.Script, subject string, amount int64, limits []domain.QuotaLimit) (*domain.QuotaLimit, error) {
+	if len(limits) == 0 {
+		return nil, nil
+	}
+	keys := make([]string, len(limits))
+	args := make([]interface{}, 0, 1+2*len(limits))
+	args = append(args, amount)
+	for i, limit := range limits {
+		keys[i] = quotaKey(subject, limit)
+		args = append(args, limit.Limit, limit.ResetAt.UnixMilli())
+	}
+
+	exceeded, err := script.Run(ctx, r.client, keys, args...).Int()
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume quota in Redis: %w", err)
+	}
+	if exceeded > 0 {
+		return &limits[exceeded-1], nil
+	}
+	return nil, nil
+}
+
+func (r *RedisQuotaRepository) Usage(ctx context.Context, subject string, limits []domain.QuotaLimit) ([]domain.QuotaUsage, error) {
+	if len(limits) == 0 {
+		return nil, nil
+	}
+	keys := make([]string, len(limits))
+	for i, limit := range limits {
+		keys[i] = quotaKey(subject, limit)
+	}
+	values, err := r.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quota usage from Redis: %w", err)
+	}
+
+	usage := make([]domain.QuotaUsage, len(limits))
+	for i, limit := range limits {
+		var used int64
+		if v, ok := values[i].(string); ok {
+			used, _ = strconv.ParseInt(v, 10, 64)
+		}
+		u := domain.QuotaUsage{
+			Kind:      limit.Kind,
+			Period:    limit.Period,
+			Used:      used,
+			Limit:     limit.Limit,
+			Unlimited: limit.Limit == 0,
+			ResetAt:   limit.ResetAt,
+		}
+		if !u.Unlimited {
+			u.Remaining = limit.Limit - used
+			if u.Remaining < 0 {
+				u.Remaining = 0
+			}
+		}
+		usage[i] = u
+	}
+	return usage, nil
+}
@@ -0,0 +1,180 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+)
+
+// maxSimilarityCandidates bounds how many recent answers FindSimilar compares against.
+const maxSimilarityCandidates = 500
+
+type RedisResponseCache struct {
+	client *redis.Client
+}
+
+func NewRedisResponseCache(client *redis.Client) domain.ResponseCache {
+	return &RedisResponseCache{client: client}
+}
+
+func responseCacheKey(key string) string {
+	return fmt.Sprintf("response_cache:%s", key)
+}
+
+// Sorted set (scored by creation time) of cached answers per language and plan tier, used for similarity lookups
+func responseCacheIndexKey(language, planTier string) string {
+	return fmt.Sprintf("response_cache_index:%s:%s", language, planTier)
+}
+
+// Set of cached answers citing a law document, used for invalidation on re-ingestion
+func responseCacheSourceKey(source string) string {
+	return fmt.Sprintf("response_cache_source:%s", source)
+}
+
+func (r *RedisResponseCache) Get(ctx context.Context, key string) (*domain.CachedAnswer, error) {
+	data, err := r.client.Get(ctx, responseCacheKey(key)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get cached answer from Redis: %w", err)
+	}
+	answer := &domain.CachedAnswer{}
+	if err := json.Unmarshal(data, answer); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal cached answer: %w", err)
+	}
+	return answer, nil
+}
+
+func (r *RedisResponseCache) FindSimilar(ctx context.Context, language, planTier string, embedding []float32, minSimilarity float64) (*domain.CachedAnswer, error) {
+	if len(embedding) == 0 {
+		return nil, nil
+	}
+	indexKey := responseCacheIndexKey(language, planTier)
+	keys, err := r.client.ZRevRange(ctx, indexKey, 0, maxSimilarityCandidates-1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response cache index: %w", err)
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	redisKeys := make([]string, len(keys))
+	for i, k := range keys {
+		redisKeys[i] = responseCacheKey(k)
+	}
+	values, err := r.client.MGet(ctx, redisKeys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cached answers: %w", err)
+	}
+
+	var best *domain.CachedAnswer
+	bestScore := minSimilarity
+	var expired []interface{}
+	for i, v := range values {
+		data, ok := v.(string)
+		if !ok {
+			expired = append(expired, keys[i])
+			continue
+		}
+		candidate := &domain.CachedAnswer{}
+		if err := json.Unmarshal([]byte(data), candidate); err != nil {
+			continue
+		}
+		if score := cosineSimilarity(embedding, candidate.Embedding); score >= bestScore {
+			best, bestScore = candidate, score
+		}
+	}
+	if len(expired) > 0 {
+		r.client.ZRem(ctx, indexKey, expired...)
+	}
+	return best, nil
+}
+
+func (r *RedisResponseCache) Put(ctx context.Context, answer *domain.CachedAnswer, ttl time.Duration) error {
+	data, err := json.Marshal(answer)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cached answer: %w", err)
+	}
+
+	pipe := r.client.TxPipeline()
+	pipe.Set(ctx, responseCacheKey(answer.Key), data, ttl)
+	indexKey := responseCacheIndexKey(answer.Language, answer.PlanTier)
+	pipe.ZAdd(ctx, indexKey, &redis.Z{Score: float64(answer.CreatedAt.Unix()), Member: answer.Key})
+	pipe.ZRemRangeByScore(ctx, indexKey, "-inf", fmt.Sprintf("%d", time.Now().Add(-ttl).Unix()))
+	pipe.Expire(ctx, indexKey, ttl)
+	for _, source := range answer.Sources {
+		pipe.SAdd(ctx, responseCacheSourceKey(source.Source), answer.Key)
+		pipe.Expire(ctx, responseCacheSourceKey(source.Source), ttl)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to cache answer in Redis: %w", err)
+	}
+	return nil
+}
+
+func (r *RedisResponseCache) InvalidateSource(ctx context.Context, source string) (int, error) {
+	sourceKey := responseCacheSourceKey(source)
+	keys, err := r.client.SMembers(ctx, sourceKey).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read cached answers for source %s: %w", source, err)
+	}
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	redisKeys := make([]string, len(keys))
+	for i, k := range keys {
+		redisKeys[i] = responseCacheKey(k)
+	}
+	pipe := r.client.TxPipeline()
+	delCmd := pipe.Del(ctx, redisKeys...)
+	pipe.Del(ctx, sourceKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to invalidate cached answers for source %s: %w", source, err)
+	}
+	return int(delCmd.Val()), nil
+}
+
+func (r *RedisResponseCache) InvalidateAll(ctx context.Context) (int, error) {
+	deleted := 0
+	iter := r.client.Scan(ctx, 0, "response_cache*", 100).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		n, err := r.client.Del(ctx, key).Result()
+		if err != nil {
+			return deleted, fmt.Errorf("failed to invalidate response cache: %w", err)
+		}
+		if strings.HasPrefix(key, "response_cache:") {
+			deleted += int(n)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return deleted, fmt.Errorf("failed to scan response cache: %w", err)
+	}
+	return deleted, nil
+}
+
+// cosineSimilarity returns the cosine of the angle between a and b, or 0 if they are not comparable.
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,121 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+)
+
+// maxStreamEventsPerRead bounds one replay batch; readers simply read again for the rest.
+const maxStreamEventsPerRead = 200
+
+type RedisStreamBuffer struct {
+	client *redis.Client
+	reader *redis.Client // blocking reads hold a connection while they wait, so they get their own pool
+}
+
+func NewRedisStreamBuffer(client, reader *redis.Client) domain.StreamBuffer {
+	return &RedisStreamBuffer{client: client, reader: reader}
+}
+
+// Redis stream of a query's events. Entry IDs are "<event ID>-0", so they double as Last-Event-IDs.
+func streamEventsKey(sessionID, messageID string) string {
+	return fmt.Sprintf("chat_stream:%s:%s", sessionID, messageID)
+}
+
+func streamInfoKey(messageID string) string {
+	return fmt.Sprintf("chat_stream_info:%s", messageID)
+}
+
+func streamListenerKey(messageID string) string {
+	return fmt.Sprintf("chat_stream_listener:%s", messageID)
+}
+
+func (r *RedisStreamBuffer) AppendStreamEvents(ctx context.Context, info *domain.StreamInfo, events []domain.StreamEvent, ttl time.Duration) error {
+	infoData, err := json.Marshal(info)
+	if err != nil {
+		return fmt.Errorf("failed to marshal stream info: %w", err)
+	}
+	key := streamEventsKey(info.SessionID, info.MessageID)
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, event := range events {
+			pipe.XAdd(ctx, &redis.XAddArgs{
+				Stream: key,
+				ID:     fmt.Sprintf("%d-0", event.ID),
+				Values: map[string]interface{}{"event": event.Event, "data": string(event.Data)},
+			})
+		}
+		pipe.Expire(ctx, key, ttl)
+		pipe.Set(ctx, streamInfoKey(info.MessageID), infoData, ttl)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to append stream events to Redis: %w", err)
+	}
+	return nil
+}
+
+func (r *RedisStreamBuffer) GetStreamInfo(ctx context.Context, messageID string) (*domain.StreamInfo, error) {
+	data, err := r.client.Get(ctx, streamInfoKey(messageID)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, domain.ErrStreamNotFound
+		}
+		return nil, fmt.Errorf("failed to get stream info from Redis: %w", err)
+	}
+	info := &domain.StreamInfo{}
+	if err := json.Unmarshal(data, info); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal stream info: %w", err)
+	}
+	return info, nil
+}
+
+func (r *RedisStreamBuffer) ReadStreamEvents(ctx context.Context, info *domain.StreamInfo, afterID int64, block time.Duration) ([]domain.StreamEvent, error) {
+	streams, err := r.reader.XRead(ctx, &redis.XReadArgs{
+		Streams: []string{streamEventsKey(info.SessionID, info.MessageID), fmt.Sprintf("%d-0", afterID)},
+		Count:   maxStreamEventsPerRead,
+		Block:   block,
+	}).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read stream events from Redis: %w", err)
+	}
+
+	var events []domain.StreamEvent
+	for _, stream := range streams {
+		for _, msg := range stream.Messages {
+			id, err := strconv.ParseInt(strings.TrimSuffix(msg.ID, "-0"), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid stream entry ID %q: %w", msg.ID, err)
+			}
+			event, _ := msg.Values["event"].(string)
+			data, _ := msg.Values["data"].(string)
+			events = append(events, domain.StreamEvent{ID: id, Event: event, Data: json.RawMessage(data)})
+		}
+	}
+	return events, nil
+}
+
+func (r *RedisStreamBuffer) SetStreamListener(ctx context.Context, messageID string, ttl time.Duration) error {
+	if err := r.client.Set(ctx, streamListenerKey(messageID), 1, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to set stream listener in Redis: %w", err)
+	}
+	return nil
+}
+
+func (r *RedisStreamBuffer) HasStreamListener(ctx context.Context, messageID string) (bool, error) {
+	n, err := r.client.Exists(ctx, streamListenerKey(messageID)).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check stream listener in Redis: %w", err)
+	}
+	return n > 0, nil
+}
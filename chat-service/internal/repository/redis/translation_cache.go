@@ -0,0 +1,45 @@
+package redis
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+)
+
+type RedisTranslationCache struct {
+	client *redis.Client
+}
+
+func NewRedisTranslationCache(client *redis.Client) domain.TranslationCache {
+	return &RedisTranslationCache{client: client}
+}
+
+// Keyed on a hash of the text, since sentences can be long
+func translationCacheKey(text, targetLang string) string {
+	sum := sha256.Sum256([]byte(text))
+	return fmt.Sprintf("translation:%s:%s", targetLang, hex.EncodeToString(sum[:]))
+}
+
+func (r *RedisTranslationCache) Get(ctx context.Context, text, targetLang string) (string, error) {
+	translated, err := r.client.Get(ctx, translationCacheKey(text, targetLang)).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to get translation from Redis: %w", err)
+	}
+	return translated, nil
+}
+
+func (r *RedisTranslationCache) Put(ctx context.Context, text, targetLang, translated string, ttl time.Duration) error {
+	if err := r.client.Set(ctx, translationCacheKey(text, targetLang), translated, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to cache translation in Redis: %w", err)
+	}
+	return nil
+}
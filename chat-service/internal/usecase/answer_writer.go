@@ -0,0 +1,214 @@
+package usecase
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxSentenceRunes bounds how much text is held back waiting for a sentence to end.
+const maxSentenceRunes = 400
+
+// maxHeldForRedaction bounds how much English text is held back in case it turns out to be personal data.
+const maxHeldForRedaction = 64
+
+// piiTailRe matches the end of streamed text that could still turn into personal data once more arrives:
+// the last word, and a run of digits before it such as the start of a phone number.
+var piiTailRe = regexp.MustCompile(`[+\d][\d\s+-]*\S*$|\S*$`)
+
+// answerWriter streams answer text to the client. English text goes out as the model produces it; for other
+// languages the text is buffered to sentence boundaries and each sentence is translated in one call. Personal
+// data the model repeats is redacted before it is sent or translated. The writer keeps the text it delivered,
+// so citations can point at the sentences the client actually saw.
+type answerWriter struct {
+	s         *ChatService
+	ctx       context.Context
+	language  string
+	pending   sentenceBuffer
+	held      string // English text that may still be the start of personal data
+	delivered strings.Builder
+	resChan   chan<- ChatResponseChunk
+}
+
+func (s *ChatService) newAnswerWriter(ctx context.Context, language string, resChan chan<- ChatResponseChunk) *answerWriter {
+	return &answerWriter{s: s, ctx: ctx, language: language, resChan: resChan}
+}
+
+// Write sends or buffers text. It returns the context's error once the request is cancelled.
+func (w *answerWriter) Write(text string) error {
+	if w.language == "en" {
+		w.held += text
+		cut := piiTailRe.FindStringIndex(w.held)[0]
+		if len(w.held)-cut > maxHeldForRedaction {
+			cut = len(w.held) - maxHeldForRedaction
+		}
+		out := w.held[:cut]
+		w.held = w.held[cut:]
+		return w.send(w.redact(out))
+	}
+	for _, sentence := range w.pending.Write(text) {
+		if err := w.emitSentence(sentence); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush sends the buffered rest of the answer.
+func (w *answerWriter) Flush() error {
+	if w.language == "en" {
+		out := w.held
+		w.held = ""
+		return w.send(w.redact(out))
+	}
+	return w.emitSentence(w.pending.Flush())
+}
+
+// redact removes personal data from answer text, which the model writes in English.
+func (w *answerWriter) redact(text string) string {
+	return w.s.guardrails.Redact("en", text)
+}
+
+// emitSentence translates a sentence and sends it with its surrounding whitespace, so line breaks survive.
+// Citation markers travel as placeholders. A sentence that fails to translate is sent in English rather than
+// dropped.
+func (w *answerWriter) emitSentence(sentence string) error {
+	sentence = w.redact(sentence)
+	text := strings.TrimSpace(sentence)
+	if text == "" {
+		return nil
+	}
+	protected, markers := protectCitations(text)
+	translated, err := w.s.translator.Translate(w.ctx, protected, w.language)
+	if err != nil || translated == "" {
+		if w.ctx.Err() != nil {
+			return w.ctx.Err()
+		}
+		log.Printf("Warning: Failed to translate answer sentence, sending it untranslated: %v", err)
+		translated = text
+	} else {
+		translated = restoreCitations(strings.TrimSpace(translated), markers)
+	}
+	leading := sentence[:strings.Index(sentence, text)]
+	trailing := sentence[len(leading)+len(text):]
+	return w.send(leading + strings.TrimSpace(translated) + trailing)
+}
+
+func (w *answerWriter) send(text string) error {
+	if text == "" {
+		return nil
+	}
+	if !send(w.ctx, w.resChan, ChatResponseChunk{Text: text}) {
+		return w.ctx.Err()
+	}
+	w.delivered.WriteString(text)
+	return nil
+}
+
+// Delivered returns the text sent to the client so far.
+func (w *answerWriter) Delivered() string {
+	return w.delivered.String()
+}
+
+// sentenceBuffer collects streamed text and releases it a sentence at a time. A sentence ends at a line
+// break, or at ., !, ? or the Ethiopic full stop and question mark once whitespace follows. A citation
+// marker right after the full stop ("... terminated. [2]") stays with its sentence.
+type sentenceBuffer struct {
+	text string
+}
+
+// Write adds text and returns the sentences it completed, each with its trailing whitespace.
+func (b *sentenceBuffer) Write(text string) []string {
+	b.text += text
+	var sentences []string
+	for {
+		end := sentenceEnd(b.text)
+		if end < 0 && utf8.RuneCountInString(b.text) > maxSentenceRunes {
+			// No sentence end in sight, e.g. a long list; cut at the last space rather than wait
+			end = strings.LastIndexFunc(b.text, unicode.IsSpace) + 1
+		}
+		if end <= 0 {
+			return sentences
+		}
+		sentences = append(sentences, b.text[:end])
+		b.text = b.text[end:]
+	}
+}
+
+// Flush returns whatever is left.
+func (b *sentenceBuffer) Flush() string {
+	text := b.text
+	b.text = ""
+	return text
+}
+
+// splitSentences splits a complete text into sentences the way a sentenceBuffer releases them.
+func splitSentences(text string) []string {
+	var b sentenceBuffer
+	sentences := b.Write(text)
+	if rest := b.Flush(); rest != "" {
+		sentences = append(sentences, rest)
+	}
+	return sentences
+}
+
+// Abbreviations common in legal answers that end in a full stop without ending the sentence
+var sentenceAbbreviations = map[string]bool{
+	"art": true, "arts": true, "no": true, "proc": true, "procl": true, "sub": true, "para": true,
+	"cap": true, "vol": true, "e.g": true, "i.e": true, "cf": true, "vs": true, "mr": true, "mrs": true, "dr": true,
+}
+
+// sentenceEnd returns the byte offset just past the first complete sentence in text and the whitespace
+// after it, or -1 if no sentence is complete yet.
+func sentenceEnd(text string) int {
+	for i, r := range text {
+		switch r {
+		case '\n':
+			return i + len(skipSpace(text[i:]))
+		case '.', '!', '?', '።', '፧', '፨':
+			rest := strings.TrimLeft(text[i+utf8.RuneLen(r):], `"')]”’`)
+			if rest == "" || !unicode.IsSpace(firstRune(rest)) {
+				continue
+			}
+			if r == '.' && isAbbreviation(text[:i]) {
+				continue
+			}
+			after := rest[len(skipSpace(rest)):]
+			if after == "" || (strings.HasPrefix(after, "[") && !strings.Contains(after, "]")) {
+				return -1 // a citation marker may still follow
+			}
+			if marker := leadingMarkerRe.FindString(after); marker != "" {
+				end := len(text) - len(after) + len(marker)
+				return end + len(skipSpace(text[end:]))
+			}
+			return len(text) - len(rest) + len(skipSpace(rest))
+		}
+	}
+	return -1
+}
+
+// skipSpace returns the leading whitespace of s.
+func skipSpace(s string) string {
+	return s[:len(s)-len(strings.TrimLeftFunc(s, unicode.IsSpace))]
+}
+
+func firstRune(s string) rune {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r
+}
+
+// isAbbreviation reports whether the word before a full stop is a number or a known abbreviation.
+func isAbbreviation(before string) bool {
+	word := before[strings.LastIndexFunc(before, unicode.IsSpace)+1:]
+	word = strings.TrimLeft(word, `"'([“‘`)
+	if word == "" {
+		return false
+	}
+	if strings.IndexFunc(word, func(r rune) bool { return !unicode.IsDigit(r) }) < 0 {
+		return true
+	}
+	return sentenceAbbreviations[strings.ToLower(word)]
+}
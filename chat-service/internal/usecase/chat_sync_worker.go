@@ -0,0 +1,289 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/config"
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+)
+
+// Prometheus metrics for the chat history sync worker. Registered in main.
+var (
+	ChatSyncedEntriesCounter = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "chat_history_synced_entries_total",
+		Help: "Number of chat entries synced from Redis to MongoDB",
+	})
+	ChatSyncFailedEntriesCounter = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "chat_history_sync_failed_entries_total",
+		Help: "Number of chat entries that failed to sync from Redis to MongoDB",
+	})
+	ChatSyncLaggingEntriesGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "chat_history_sync_lagging_entries",
+		Help: "Number of chat entries older than one sync interval that are still not in MongoDB",
+	})
+)
+
+const (
+	chatSyncLockKey         = "chat_history_sync"
+	chatSyncShutdownTimeout = 15 * time.Second
+	chatSyncRestartDelay    = 5 * time.Second
+)
+
+// syncRetryState tracks a session whose last sync attempt failed.
+type syncRetryState struct {
+	attempts    int
+	nextAttempt time.Time
+	lagging     int
+}
+
+// ChatSyncWorker periodically copies account holders' chat history from Redis to MongoDB.
+type ChatSyncWorker struct {
+	cfg           *config.Config
+	sessionRepo   domain.SessionRepository // Redis
+	chatRepo      domain.ChatRepository    // Redis
+	mongoChatRepo domain.ChatRepository    // MongoDB
+	lock          domain.DistributedLock
+	retries       map[string]*syncRetryState
+}
+
+func NewChatSyncWorker(
+	cfg *config.Config,
+	sessionRepo domain.SessionRepository, // Redis
+	chatRepo domain.ChatRepository, // Redis
+	mongoChatRepo domain.ChatRepository, // MongoDB
+	lock domain.DistributedLock,
+) *ChatSyncWorker {
+	return &ChatSyncWorker{
+		cfg:           cfg,
+		sessionRepo:   sessionRepo,
+		chatRepo:      chatRepo,
+		mongoChatRepo: mongoChatRepo,
+		lock:          lock,
+		retries:       make(map[string]*syncRetryState),
+	}
+}
+
+// Run supervises the sync loop until ctx is cancelled, restarting it if it panics.
+// On cancellation a final cycle flushes whatever is still pending before Run returns.
+func (w *ChatSyncWorker) Run(ctx context.Context) {
+	log.Printf("Starting chat history sync from Redis to MongoDB with interval: %s", w.cfg.ChatHistorySyncInterval)
+	restart := true
+	for restart && !w.loop(ctx) {
+		select {
+		case <-ctx.Done():
+			restart = false
+		case <-time.After(chatSyncRestartDelay):
+		}
+	}
+
+	log.Println("Flushing pending chat history before shutdown...")
+	flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), chatSyncShutdownTimeout)
+	defer cancel()
+	if !w.runCycle(flushCtx, true) {
+		log.Println("Skipped the shutdown flush: the sync lock is not available, so pending chat history is left to the replica holding it.")
+	}
+	log.Println("Chat history sync stopped.")
+}
+
+// loop runs sync cycles on every tick. It reports false if it was interrupted by a panic.
+func (w *ChatSyncWorker) loop(ctx context.Context) (stopped bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Chat history sync panicked, restarting in %s: %v", chatSyncRestartDelay, r)
+			stopped = false
+		}
+	}()
+
+	ticker := time.NewTicker(w.cfg.ChatHistorySyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return true
+		case <-ticker.C:
+			w.runCycle(ctx, false)
+		}
+	}
+}
+
+// runCycle syncs every active user session while holding the distributed sync lock, and reports whether it got
+// the lock. When ignoreBackoff is set, sessions waiting for a retry are attempted immediately.
+func (w *ChatSyncWorker) runCycle(ctx context.Context, ignoreBackoff bool) bool {
+	lockTTL := w.cfg.ChatHistorySyncLockTTL
+	token, acquired, err := w.lock.Acquire(ctx, chatSyncLockKey, lockTTL)
+	if err != nil {
+		log.Printf("Error acquiring chat history sync lock: %v", err)
+		return false
+	}
+	if !acquired {
+		log.Println("Skipping chat history sync cycle: another replica holds the lock.")
+		return false
+	}
+	defer func() {
+		releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 2*time.Second)
+		defer cancel()
+		if err := w.lock.Release(releaseCtx, chatSyncLockKey, token); err != nil {
+			log.Printf("Warning: %v", err)
+		}
+	}()
+
+	// A single slow session can outlast the lock, so it is refreshed in the background for the whole cycle
+	ctx, cancel := context.WithCancel(ctx)
+	refreshed := make(chan struct{})
+	go func() {
+		defer close(refreshed)
+		w.keepLock(ctx, cancel, token, lockTTL)
+	}()
+	defer func() {
+		cancel()
+		<-refreshed
+	}()
+
+	log.Println("Running chat history sync cycle...")
+	sessionIDs, err := w.sessionRepo.GetUserSessionIDs(ctx)
+	if err != nil {
+		log.Printf("Error fetching active user session IDs for sync: %v", err)
+		return true
+	}
+
+	now := time.Now()
+	synced := 0
+	for _, sessionID := range sessionIDs {
+		if ctx.Err() != nil {
+			break
+		}
+		if state, ok := w.retries[sessionID]; ok && !ignoreBackoff && now.Before(state.nextAttempt) {
+			continue
+		}
+
+		entries, err := w.syncSession(ctx, sessionID)
+		if err != nil {
+			log.Printf("Error syncing chat history for session %s: %v", sessionID, err)
+			ChatSyncFailedEntriesCounter.Add(float64(len(entries)))
+			w.scheduleRetry(sessionID, entries, now)
+			continue
+		}
+		delete(w.retries, sessionID)
+		if len(entries) > 0 {
+			ChatSyncedEntriesCounter.Add(float64(len(entries)))
+			synced += len(entries)
+		}
+	}
+
+	lagging := 0
+	for _, state := range w.retries {
+		lagging += state.lagging
+	}
+	ChatSyncLaggingEntriesGauge.Set(float64(lagging))
+	log.Printf("Chat history sync cycle completed: %d entries synced, %d sessions pending retry.", synced, len(w.retries))
+	return true
+}
+
+// keepLock refreshes the sync lock every third of its TTL until ctx is done, and cancels the cycle if the lock is lost.
+func (w *ChatSyncWorker) keepLock(ctx context.Context, cancel context.CancelFunc, token string, ttl time.Duration) {
+	ticker := time.NewTicker(max(ttl/3, time.Second))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			held, err := w.lock.Refresh(ctx, chatSyncLockKey, token, ttl)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil || !held {
+				log.Printf("Aborting chat history sync cycle: sync lock was lost (err: %v)", err)
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// syncSession upserts a session's unsynced entries into MongoDB and marks them as synced in Redis.
+// The entries it attempted are returned even on failure so they can be accounted for.
+func (w *ChatSyncWorker) syncSession(ctx context.Context, sessionID string) ([]domain.ChatEntry, error) {
+	entries, err := w.chatRepo.GetUnsyncedChatEntries(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unsynced chat entries from Redis: %w", err)
+	}
+
+	if len(entries) == 0 {
+		// Nothing left to sync; once the session itself has expired from Redis, stop tracking it.
+		if _, err := w.sessionRepo.GetSessionByID(ctx, sessionID); err != nil {
+			if err := w.sessionRepo.RemoveUserSessionID(ctx, sessionID); err != nil {
+				log.Printf("Warning: Failed to remove expired session %s from the active set: %v", sessionID, err)
+			}
+		}
+		return nil, nil
+	}
+
+	// BulkSaveChatEntries upserts by entry ID, so re-syncing an entry is harmless.
+	if err := w.mongoChatRepo.BulkSaveChatEntries(ctx, entries); err != nil {
+		return entries, err
+	}
+	// A delete that ran while the entries were being written has already cleared MongoDB, so clear it again
+	if deleted, err := discardIfDeleted(ctx, w.sessionRepo, w.chatRepo, w.mongoChatRepo, sessionID); err != nil || deleted {
+		return nil, err
+	}
+
+	syncedIDs := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		syncedIDs = append(syncedIDs, entry.MongoID.Hex())
+	}
+	if err := w.chatRepo.MarkChatEntriesAsSynced(ctx, sessionID, syncedIDs); err != nil {
+		// The entries are already in MongoDB; they will simply be upserted again next cycle.
+		return entries, err
+	}
+	return entries, nil
+}
+
+// scheduleRetry backs a failing session off exponentially, in multiples of the sync interval.
+func (w *ChatSyncWorker) scheduleRetry(sessionID string, entries []domain.ChatEntry, now time.Time) {
+	state, ok := w.retries[sessionID]
+	if !ok {
+		state = &syncRetryState{}
+		w.retries[sessionID] = state
+	}
+	state.attempts++
+
+	backoff := w.cfg.ChatHistorySyncInterval
+	for i := 1; i < state.attempts && backoff < w.cfg.ChatHistorySyncMaxRetry; i++ {
+		backoff *= 2
+	}
+	if backoff > w.cfg.ChatHistorySyncMaxRetry {
+		backoff = w.cfg.ChatHistorySyncMaxRetry
+	}
+	state.nextAttempt = now.Add(backoff)
+
+	state.lagging = 0
+	for _, entry := range entries {
+		if now.Sub(entry.CreatedAt) > w.cfg.ChatHistorySyncInterval {
+			state.lagging++
+		}
+	}
+}
+
+// discardIfDeleted removes what background writes put back for a session that has since been deleted:
+// its Redis history, its MongoDB entries and summary, and its place in the active session set. It reports
+// whether the session was deleted.
+func discardIfDeleted(ctx context.Context, sessionRepo domain.SessionRepository, chatRepo, mongoChatRepo domain.ChatRepository, sessionID string) (bool, error) {
+	deleted, err := sessionRepo.IsSessionDeleted(ctx, sessionID)
+	if err != nil || !deleted {
+		return false, err
+	}
+	if err := chatRepo.DeleteChatHistory(ctx, sessionID); err != nil {
+		return true, err
+	}
+	if err := mongoChatRepo.DeleteChatHistory(ctx, sessionID); err != nil {
+		return true, err
+	}
+	return true, sessionRepo.RemoveUserSessionID(ctx, sessionID)
+}
@@ -0,0 +1,70 @@
+package usecase
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/config"
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+)
+
+// lostLock grants the lock once and reports it lost on the first refresh.
+type lostLock struct {
+	refreshes atomic.Int32
+	released  atomic.Bool
+}
+
+func (l *lostLock) Acquire(ctx context.Context, key string, ttl time.Duration) (string, bool, error) {
+	return "token", true, nil
+}
+
+func (l *lostLock) Refresh(ctx context.Context, key, token string, ttl time.Duration) (bool, error) {
+	l.refreshes.Add(1)
+	return false, nil
+}
+
+func (l *lostLock) Release(ctx context.Context, key, token string) error {
+	l.released.Store(true)
+	return nil
+}
+
+// activeSessions lists one session as active.
+type activeSessions struct {
+	domain.SessionRepository
+}
+
+func (activeSessions) GetUserSessionIDs(ctx context.Context) ([]string, error) {
+	return []string{"session-1"}, nil
+}
+
+// stalledChats never returns from reading a session's entries until ctx is done.
+type stalledChats struct {
+	domain.ChatRepository
+}
+
+func (stalledChats) GetUnsyncedChatEntries(ctx context.Context, sessionID string) ([]domain.ChatEntry, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestSyncCycleStopsWhenTheLockIsLostMidSession(t *testing.T) {
+	cfg := &config.Config{ChatHistorySyncInterval: time.Minute, ChatHistorySyncMaxRetry: time.Hour, ChatHistorySyncLockTTL: 3 * time.Second}
+	lock := &lostLock{}
+	w := NewChatSyncWorker(cfg, activeSessions{}, stalledChats{}, stalledChats{}, lock)
+
+	done := make(chan bool)
+	go func() { done <- w.runCycle(context.Background(), false) }()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("sync cycle kept running on a session after the lock was lost")
+	}
+	if lock.refreshes.Load() == 0 {
+		t.Error("lock was never refreshed during the cycle")
+	}
+	if !lock.released.Load() {
+		t.Error("lock was not released after the cycle")
+	}
+}
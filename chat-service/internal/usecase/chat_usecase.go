@@ -2,16 +2,17 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"github.com/LAWGEN/lawgen-backend/chat-service/internal/config"
 	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
-	"github.com/LAWGEN/lawgen-backend/chat-service/internal/util"
 )
 
 type ChatService struct {
@@ -22,23 +23,40 @@ type ChatService struct {
 	mongoChatRepo    domain.ChatRepository    // MongoDB for persistent chat history
 	llmService       domain.LLMService
 	ragService       domain.RAGService
+	responseCache    domain.ResponseCache // Optional; nil disables caching
+	embedder         domain.Embedder      // Optional; nil limits the cache to exact matches
+	quotaRepo        domain.QuotaRepository
+	shareRepo        domain.ShareLinkRepository // MongoDB
+	analyticsRepo    domain.AnalyticsRepository
+	translator       domain.TranslationService
+	streamBuffer     domain.StreamBuffer // Redis; keeps stream events for clients that reconnect
+	prompts          *PromptService
+	guardrails       *Guardrails
+	glossary         *GlossaryService
 }
 
 type QueryRequest struct {
 	SessionID string // Can be empty for new sessions
 	UserID    string // Empty for guests
 	PlanID    string // Provided by middleware
+	ClientIP  string // Meters visitors' quotas, since they have no UserID
 	Message   string
 	Language  string
+	MessageID string // ID to give the answer, so the caller knows it up front; generated when empty
 }
 
 type ChatResponseChunk struct {
 	Text               string
 	Sources            []domain.RAGSource // Only in the final chunk
+	Citations          []domain.Citation  // Only in the final chunk; markers index into Sources
 	IsComplete         bool
 	Error              error
-	SessionID          string   // New session ID if created
-	SuggestedQuestions []string // For no-result scenarios
+	SessionID          string             // New session ID if created
+	MessageID          string             // Only in the final chunk; ID of the stored answer, e.g. for feedback
+	Disclaimer         string             // Only in the final chunk of generated answers
+	Degraded           bool               // Sent before the answer when it is based on cached articles because the RAG service is down
+	RetrievedSources   []domain.RAGSource // Sent while retrieving, before generation: the sources found so far, in citation order
+	SuggestedQuestions []string           // For no-result scenarios
 }
 
 func NewChatService(
@@ -49,6 +67,16 @@ func NewChatService(
 	mongoChatRepo domain.ChatRepository, // MongoDB
 	llmService domain.LLMService,
 	ragService domain.RAGService,
+	responseCache domain.ResponseCache,
+	embedder domain.Embedder,
+	quotaRepo domain.QuotaRepository,
+	shareRepo domain.ShareLinkRepository,
+	analyticsRepo domain.AnalyticsRepository,
+	translator domain.TranslationService,
+	streamBuffer domain.StreamBuffer,
+	prompts *PromptService,
+	guardrails *Guardrails,
+	glossary *GlossaryService,
 ) *ChatService {
 	return &ChatService{
 		cfg:              cfg,
@@ -58,12 +86,25 @@ func NewChatService(
 		mongoChatRepo:    mongoChatRepo,
 		llmService:       llmService,
 		ragService:       ragService,
+		responseCache:    responseCache,
+		embedder:         embedder,
+		quotaRepo:        quotaRepo,
+		shareRepo:        shareRepo,
+		analyticsRepo:    analyticsRepo,
+		translator:       translator,
+		streamBuffer:     streamBuffer,
+		prompts:          prompts,
+		guardrails:       guardrails,
+		glossary:         glossary,
 	}
 }
 
 // ProcessQuery orchestrates the entire chat flow
 func (s *ChatService) ProcessQuery(ctx context.Context, req QueryRequest) (<-chan ChatResponseChunk, error) {
 	resChan := make(chan ChatResponseChunk)
+	if req.MessageID == "" {
+		req.MessageID = primitive.NewObjectID().Hex()
+	}
 
 	go func() {
 		defer close(resChan)
@@ -73,10 +114,52 @@ func (s *ChatService) ProcessQuery(ctx context.Context, req QueryRequest) (<-cha
 	return resChan, nil
 }
 
+// send delivers a chunk, or gives up once the request is cancelled so that no goroutine stays blocked on
+// a client that went away. It reports whether the chunk was delivered.
+func send(ctx context.Context, resChan chan<- ChatResponseChunk, chunk ChatResponseChunk) bool {
+	select {
+	case resChan <- chunk:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
 func (s *ChatService) processQueryInternal(ctx context.Context, req QueryRequest, resChan chan<- ChatResponseChunk) {
-	// 1. Determine UserParams from PlanID
+	// 1. Detect the message's language, which decides whether and from what the query is translated
+	detected := detectLanguage(req.Message)
+	queryLanguage := req.Language
+	if detected.Confident {
+		queryLanguage = detected.Language
+	}
+
+	// Redact personal data before the message is stored or sent to the LLM, and screen out emergencies
+	// and questions that aren't about Ethiopian law; those get a canned answer without using the quota
+	req.Message = s.guardrails.Redact(queryLanguage, req.Message)
+	verdict := s.guardrails.Classify(queryLanguage, req.Message)
+
+	// Determine UserParams from PlanID; the plan's usage quotas are enforced once the session is known
 	userParams := domain.GetUserParamsFromPlanID(req.PlanID)
-	isGuest := !userParams.SaveHistory // If history is not saved, it's a guest session
+	metered := false
+	meter := func() bool {
+		if verdict != domain.VerdictAllowed {
+			return true
+		}
+		if err := s.consumeQueryQuota(ctx, req); err != nil {
+			send(ctx, resChan, ChatResponseChunk{Error: err})
+			return false
+		}
+		metered = true
+		return true
+	}
+	// A query that ends without an answer, refused or failed, gives its quota back
+	refund := func() {
+		if metered {
+			s.refundQueryQuota(ctx, req)
+			metered = false
+		}
+	}
+	isGuest := req.UserID == "" || !userParams.SaveHistory // Visitors have no account to persist history for
 
 	// 2. Retrieve or create session
 	var session *domain.Session
@@ -84,6 +167,12 @@ func (s *ChatService) processQueryInternal(ctx context.Context, req QueryRequest
 
 	if req.SessionID == "" {
 		// New session
+		if !meter() {
+			return
+		}
+		if req.Language == "" {
+			req.Language = resolveLanguage(detected, "")
+		}
 		session = &domain.Session{
 			ID:           uuid.NewString(), // Temp ID for now, MongoID will be generated by repo
 			UserID:       req.UserID,
@@ -91,28 +180,32 @@ func (s *ChatService) processQueryInternal(ctx context.Context, req QueryRequest
 			CreatedAt:    time.Now(),
 			LastActiveAt: time.Now(),
 			IsGuest:      isGuest,
-			Title:        "New Chat", // Default title
+			Title:        defaultSessionTitle, // Replaced by a generated title after the first answer
 		}
 		if err := s.sessionRepo.CreateSession(ctx, session); err != nil { // Create in Redis
-			resChan <- ChatResponseChunk{Error: fmt.Errorf("failed to create session in Redis: %w", err)}
+			refund()
+			send(ctx, resChan, ChatResponseChunk{Error: fmt.Errorf("failed to create session in Redis: %w", err)})
 			return
 		}
 		// If for an account holder, also create in MongoDB
-		if userParams.SaveHistory {
+		if !isGuest {
 			if err := s.mongoSessionRepo.CreateSession(ctx, session); err != nil {
 				log.Printf("Warning: Failed to create session %s in MongoDB: %v", session.ID, err)
 				// Don't fail the entire request, but log it
 			}
 		}
-		req.SessionID = session.ID                          // Use the newly created ID
-		resChan <- ChatResponseChunk{SessionID: session.ID} // Send session ID to client
+		req.SessionID = session.ID // Use the newly created ID
+		// Send session ID to client
+		if !send(ctx, resChan, ChatResponseChunk{SessionID: session.ID}) {
+			return
+		}
 	} else {
 		session, err = s.sessionRepo.GetSessionByID(ctx, req.SessionID) // Try Redis first
 		if err != nil {
-			if userParams.SaveHistory { // If account holder, try MongoDB if not in Redis
+			if !isGuest { // If account holder, try MongoDB if not in Redis
 				session, err = s.mongoSessionRepo.GetSessionByID(ctx, req.SessionID)
 				if err != nil {
-					resChan <- ChatResponseChunk{Error: fmt.Errorf("session not found: %w", err)}
+					send(ctx, resChan, ChatResponseChunk{Error: fmt.Errorf("session not found: %w", err)})
 					return
 				}
 				// Re-cache in Redis
@@ -120,13 +213,25 @@ func (s *ChatService) processQueryInternal(ctx context.Context, req QueryRequest
 					log.Printf("Warning: Failed to re-cache session %s in Redis: %v", session.ID, err)
 				}
 			} else { // Guest session expired from Redis
-				resChan <- ChatResponseChunk{Error: fmt.Errorf("guest session expired or not found: %w", err)}
+				send(ctx, resChan, ChatResponseChunk{Error: fmt.Errorf("guest session expired or not found: %w", err)})
 				return
 			}
 		}
+		// Only the owner may continue a session; a guest session has no owner and is only open to visitors
+		if session.UserID != req.UserID {
+			send(ctx, resChan, ChatResponseChunk{Error: domain.ErrSessionAccessDenied})
+			return
+		}
+		if !meter() {
+			return
+		}
 
-		// Update session last active time and language (if changed)
+		// Update session last active time and language (if changed); without one from the client, the
+		// detected language is used
 		session.LastActiveAt = time.Now()
+		if req.Language == "" {
+			req.Language = resolveLanguage(detected, session.Language)
+		}
 		if req.Language != "" && session.Language != req.Language {
 			session.Language = req.Language
 		}
@@ -146,42 +251,135 @@ func (s *ChatService) processQueryInternal(ctx context.Context, req QueryRequest
 	if err := s.chatRepo.SaveChatEntry(ctx, &userChatEntry); err != nil { // Save to Redis
 		log.Printf("Warning: Failed to save user chat entry to Redis: %v", err)
 	}
+	if verdict != domain.VerdictAllowed {
+		GuardrailVerdictsCounter.WithLabelValues(string(verdict)).Inc()
+		s.answerCanned(ctx, req, session.ID, verdict, resChan)
+		return
+	}
+
+	// The versions of the prompts behind this answer, so feedback can be compared across versions
+	promptVersions := map[string]int{}
+	render := func(name string, data interface{}) (string, error) {
+		prompt, version, err := s.prompts.Render(ctx, name, session.ID, data)
+		if err == nil {
+			promptVersions[name] = version
+		}
+		return prompt, err
+	}
 
-	// 3. Language Conversion (if needed)
+	// 3. Language Conversion (if needed), from the detected language rather than the one the answer is in
 	processedQuery := req.Message
-	if req.Language != "en" { // Assuming RAG and LLM primarily work in English
-		var err error
-		processedQuery, err = s.llmService.Translate(ctx, req.Message, "en")
+	if queryLanguage == "" {
+		queryLanguage = req.Language
+	}
+	if queryLanguage != "en" { // Assuming RAG and LLM primarily work in English
+		// Glossary terms travel as placeholders and come back as their approved English equivalents
+		protected := s.glossary.protect(ctx, req.Message, queryLanguage, "en")
+		converterPrompt, err := render(PromptConverter, ConverterPromptData{
+			Text:     protected.Text,
+			From:     languageName(queryLanguage, detected.Confident && detected.Latin),
+			To:       languageName("en", false),
+			Glossary: protected.legend(),
+		})
+		if err == nil {
+			processedQuery, err = s.llmService.Translate(ctx, converterPrompt, protected.Text, "en")
+		}
 		if err != nil {
-			resChan <- ChatResponseChunk{Error: fmt.Errorf("failed to translate message: %w", err)}
+			refund()
+			send(ctx, resChan, ChatResponseChunk{Error: fmt.Errorf("failed to translate message: %w", err)})
+			return
+		}
+		var complete bool
+		if processedQuery, complete = protected.restore(processedQuery); !complete {
+			log.Printf("Warning: Query translation lost glossary placeholders: %q", processedQuery)
+		}
+		// The English rules catch what the rules of the query's language missed; like every canned
+		// answer, that one doesn't use the quota
+		if verdict = s.guardrails.Classify("en", processedQuery); verdict != domain.VerdictAllowed {
+			refund()
+			GuardrailVerdictsCounter.WithLabelValues(string(verdict)).Inc()
+			s.answerCanned(ctx, req, session.ID, verdict, resChan)
 			return
 		}
 	}
+	GuardrailVerdictsCounter.WithLabelValues("allowed").Inc()
 
-	// 4. Refine Query for RAG
-	refinementPrompt := strings.ReplaceAll(s.cfg.LLMPromptRefine, "{{.Query}}", processedQuery)
-	refinedQuery, err := s.llmService.Generate(ctx, refinementPrompt, nil) // No history for refinement
-	log.Printf("\n### Refinement Service\nPrompt: %s\nResult: %s\nError: %v\n", refinementPrompt, refinedQuery, err)
-	if err != nil {
-		log.Printf("Warning: Failed to refine query, falling back to original: %v", err)
-		refinedQuery = processedQuery // Fallback
+	// 4. Retrieve Chat History for Context (Sliding Window), wide enough for the query rewrite's turns
+	historyLimit := userParams.ContextWindow
+	if historyLimit > 0 && s.cfg.QueryRewriteTurns >= historyLimit {
+		historyLimit = s.cfg.QueryRewriteTurns + 1 // the history ends with the current query
 	}
-
-	// 5. Retrieve Chat History for Context (Sliding Window)
-	chatHistory, err := s.chatRepo.GetChatHistory(ctx, session.ID, userParams.ContextWindow) // Limit is num of PAIRS
+	chatHistory, err := s.chatRepo.GetChatHistory(ctx, session.ID, historyLimit) // Limit is num of PAIRS
 	if err != nil {
 		log.Printf("Warning: Failed to retrieve chat history from Redis: %v", err)
 		chatHistory = []domain.ChatEntry{} // Continue with empty history
 	}
 
+	// 5. Rewrite the query for RAG as standalone queries, resolving follow-ups against the latest turns
+	queries := s.rewriteQuery(ctx, render, processedQuery, chatHistory, userChatEntry.ID)
+	refinedQuery := queries[0]
+	var rewrittenQueries []string
+	if len(queries) > 1 {
+		rewrittenQueries = queries
+	}
+	cacheQuery := strings.Join(queries, "\n") // a split question is cached under all of its queries
+	// The answer prompt keeps the plan's context window
+	if keep := 2 * userParams.ContextWindow; keep > 0 && len(chatHistory) > keep {
+		chatHistory = chatHistory[len(chatHistory)-keep:]
+	}
+	// Earlier turns that fell out of the window live on in the session summary
+	var summary *domain.ChatEntry
+	if userParams.MaxSummaryWords > 0 {
+		summary = s.getSummary(ctx, session.ID, isGuest)
+	}
+
+	// Serve repeated questions from the response cache, skipping retrieval and generation
+	useCache := s.responseCache != nil && s.cfg.ResponseCacheEnabled && !hasPriorTurns(chatHistory, userChatEntry.ID)
+	var queryEmbedding []float32
+	if useCache {
+		var cached *domain.CachedAnswer
+		cached, queryEmbedding = s.lookupCachedAnswer(ctx, cacheQuery, req.Language, req.PlanID)
+		if cached != nil {
+			log.Printf("Serving answer for session %s from response cache (key %s)", session.ID, cached.Key)
+			answer := s.newAnswerWriter(ctx, req.Language, resChan)
+			if err := answer.Write(cached.Answer); err != nil {
+				return // client went away
+			}
+			if err := answer.Flush(); err != nil {
+				return
+			}
+			// The answer keeps the version of the answer prompt it was generated with
+			for name, version := range cached.PromptVersions {
+				promptVersions[name] = version
+			}
+			s.completeAnswer(ctx, domain.ChatEntry{
+				ID:               req.MessageID,
+				SessionID:        session.ID,
+				Content:          cached.Answer,
+				Sources:          cached.Sources,
+				Citations:        cached.Citations,
+				RefinedQuery:     refinedQuery,
+				RewrittenQueries: rewrittenQueries,
+				PromptVersions:   promptVersions,
+				Disclaimer:       s.localizedDisclaimer(ctx, req.Language),
+			}, answer.Delivered(), resChan)
+			s.titleSession(ctx, session, isGuest, req.Message, cached.Answer)
+			return
+		}
+	}
+
 	// 6. RAG Retrieval
-	ragResult, err := s.ragService.Retrieve(ctx, refinedQuery, userParams.MaxReferences)
-	log.Printf("\n### RAG Service\nQuery: %s\nResult: %+v\nError: %v\n", refinedQuery, ragResult, err)
+	ragResult, err := s.retrieveAll(ctx, queries, userParams.MaxReferences, resChan)
+	log.Printf("\n### RAG Service\nQueries: %q\nResult: %+v\nError: %v\n", queries, ragResult, err)
 	if err != nil {
 		// Log the real error for debugging
 		log.Printf("RAG retrieval error: %v", err)
+		refund()
 		// Send a generic error to the user
-		resChan <- ChatResponseChunk{Error: fmt.Errorf("Sorry, the legal document retrieval service is temporarily unavailable. Please try again later.")}
+		send(ctx, resChan, ChatResponseChunk{Error: fmt.Errorf("Sorry, the legal document retrieval service is temporarily unavailable. Please try again later.")})
+		return
+	}
+	if ragResult.Degraded && !send(ctx, resChan, ChatResponseChunk{Degraded: true}) {
 		return
 	}
 
@@ -189,127 +387,182 @@ func (s *ChatService) processQueryInternal(ctx context.Context, req QueryRequest
 	var llmAnswerBuilder strings.Builder
 
 	if len(ragResult.Results) == 0 {
-		// No RAG results, use LLM to suggest related questions
-		suggestionsPrompt := strings.ReplaceAll(s.cfg.LLMPromptNoResult, "{{.Query}}", processedQuery)
-		suggestionsStr, err := s.llmService.Generate(ctx, suggestionsPrompt, nil)
+		// No RAG results, use LLM to suggest related questions. Without an answer the query isn't charged
+		refund()
+		var suggestionsStr string
+		suggestionsPrompt, err := render(PromptNoResult, NoResultPromptData{Query: processedQuery})
+		if err == nil {
+			suggestionsStr, err = s.llmService.Generate(ctx, suggestionsPrompt, nil)
+		}
 		log.Printf("\n### LLM No-Result Suggestions\nPrompt: %s\nResult: %s\nError: %v\n", suggestionsPrompt, suggestionsStr, err)
 		if err != nil {
 			log.Printf("Error generating no-result suggestions: %v", err)
 			suggestionsStr = "Please try rephrasing your question."
 		}
-		resChan <- ChatResponseChunk{
+		send(ctx, resChan, ChatResponseChunk{
 			Text:               "I couldn't find information related to your question.",
 			IsComplete:         true,
 			SuggestedQuestions: strings.Split(suggestionsStr, "\n"),
-		}
+		})
 		return
 	}
 
-	// Build collected document references for LLM prompt
-	var collectedDocsBuilder strings.Builder
-	for _, source := range ragResult.Results {
-		collectedDocsBuilder.WriteString(fmt.Sprintf("[Source: %s, Article: %s]\n%s\n", source.Source, source.ArticleNumber, source.Content))
-	}
+	// Only the sources the answer may reference are shown to the LLM, numbered for inline citations
+	promptSources := s.filterSources(ragResult.Results, userParams.MaxReferences)
+	collectedDocs := formatSourcesForPrompt(promptSources)
 
-	// Prepare history for LLM prompt
+	// Prepare history for LLM prompt, prefixed with the summary of earlier turns
 	var historyBuilder strings.Builder
-	for _, entry := range chatHistory {
-		if entry.Type == domain.MessageTypeUser {
-			historyBuilder.WriteString(fmt.Sprintf("User: %s\n", entry.Content))
-		} else if entry.Type == domain.MessageTypeLLM {
-			historyBuilder.WriteString(fmt.Sprintf("Assistant: %s\n", entry.Content))
-		}
+	if summary != nil && summary.Content != "" {
+		historyBuilder.WriteString(fmt.Sprintf("Summary of earlier conversation: %s\n", summary.Content))
+	}
+	historyBuilder.WriteString(formatTurns(chatHistory))
+
+	// Build the final prompt from the answer template version serving this session
+	finalLLMPrompt, err := render(PromptAnswer, AnswerPromptData{
+		RAGResults:  collectedDocs,
+		ChatHistory: historyBuilder.String(),
+		Query:       processedQuery,
+		MaxWords:    userParams.MaxAnswerWords,
+		MaxRefs:     userParams.MaxReferences,
+	})
+	if err != nil {
+		refund()
+		send(ctx, resChan, ChatResponseChunk{Error: err})
+		return
 	}
-
-	// Build the final prompt using the template from config
-	finalLLMPrompt := s.cfg.LLMPromptAnswer
-	finalLLMPrompt = strings.ReplaceAll(finalLLMPrompt, "{{.RAGResults}}", collectedDocsBuilder.String())
-	finalLLMPrompt = strings.ReplaceAll(finalLLMPrompt, "{{.ChatHistory}}", historyBuilder.String())
-	finalLLMPrompt = strings.ReplaceAll(finalLLMPrompt, "{{.Query}}", processedQuery)
-	finalLLMPrompt = strings.ReplaceAll(finalLLMPrompt, "{{.MaxWords}}", fmt.Sprintf("%d", userParams.MaxAnswerWords))
-	finalLLMPrompt = strings.ReplaceAll(finalLLMPrompt, "{{.MaxRefs}}", fmt.Sprintf("%d", userParams.MaxReferences))
 	log.Printf("\n### LLM Service\nPrompt: %s\n", finalLLMPrompt)
 
-	// Stream LLM response word-by-word with minimal latency, translating each chunk if needed
+	// Forward LLM chunks as they arrive, translating them if needed
 	llmStream, err := s.llmService.StreamGenerate(ctx, finalLLMPrompt, chatHistory, userParams.MaxAnswerWords)
 	if err != nil {
-		resChan <- ChatResponseChunk{Error: fmt.Errorf("failed to stream LLM response: %w", err)}
+		log.Printf("LLM stream error: %v", err)
+		refund()
+		send(ctx, resChan, ChatResponseChunk{Error: llmUserError(err)})
 		return
 	}
+	// Citation markers pointing at sources the model was never given are stripped as the text streams
+	stripper := &citationStripper{numSources: len(promptSources)}
+	answer := s.newAnswerWriter(ctx, req.Language, resChan)
 	for chunk := range llmStream {
 		if chunk.Error != nil {
-			resChan <- ChatResponseChunk{Error: fmt.Errorf("LLM stream error: %w", chunk.Error)}
+			log.Printf("LLM stream error: %v", chunk.Error)
+			refund()
+			send(ctx, resChan, ChatResponseChunk{Error: llmUserError(chunk.Error)})
 			return
 		}
 		if chunk.Done {
 			break
 		}
-		words := strings.Fields(chunk.Chunk)
-		for _, word := range words {
-			outText := word + " "
-			// If user requested non-English, translate each chunk using HTTP API
-			if req.Language != "en" {
-				translated, err := util.TranslateText(s.cfg.TranslateApiUrl, outText, req.Language)
-				if err == nil && translated != "" {
-					outText = translated + " "
-				}
-			}
-			time.Sleep(30 * time.Millisecond)
-			resChan <- ChatResponseChunk{Text: outText}
+		if err := answer.Write(stripper.Write(chunk.Chunk)); err != nil {
+			return // client went away
 		}
 		llmAnswerBuilder.WriteString(chunk.Chunk)
 		log.Printf("\n### LLM Stream Chunk\nChunk: %s\n", chunk.Chunk)
 	}
+	if ctx.Err() != nil {
+		return // the stream was cut short because the client went away
+	}
+	if err := answer.Write(stripper.Flush()); err != nil {
+		return
+	}
+	if err := answer.Flush(); err != nil {
+		return
+	}
 
-	// ...existing code above...
-
-	// 8. Post-processing and strict enforcement
-	finalAnswer := s.enforceLimits(llmAnswerBuilder.String(), userParams.MaxAnswerWords)
-	finalSources := s.filterSources(ragResult.Results, userParams.MaxReferences)
+	// 8. Post-processing and strict enforcement; personal data the model repeated is not stored
+	finalAnswer := s.enforceLimits(stripInvalidCitations(llmAnswerBuilder.String(), len(promptSources)), userParams.MaxAnswerWords)
+	finalAnswer = s.guardrails.Redact("en", finalAnswer) // the model answers in English
+	finalSources := promptSources
+	citations := buildCitations(finalAnswer, finalSources)
 
-	// Send final chunk with sources and completion signal
-	resChan <- ChatResponseChunk{
-		Sources:    finalSources,
-		IsComplete: true,
+	// Answers from the fallback retriever are not cached, so full answers replace them once the service is back
+	if useCache && finalAnswer != "" && !ragResult.Degraded {
+		s.storeCachedAnswer(ctx, cacheQuery, req.Language, req.PlanID, queryEmbedding, finalAnswer, finalSources, citations, promptVersions[PromptAnswer])
 	}
 
-	// 9. Store LLM's Answer
-	llmChatEntry := domain.ChatEntry{
-		SessionID: session.ID,
-		Type:      domain.MessageTypeLLM,
-		Content:   finalAnswer,
-		Sources:   finalSources,
-		CreatedAt: time.Now(),
+	// 9. Store the answer and finish the stream
+	s.completeAnswer(ctx, domain.ChatEntry{
+		ID:               req.MessageID,
+		SessionID:        session.ID,
+		Content:          finalAnswer,
+		Sources:          finalSources,
+		Citations:        citations,
+		RefinedQuery:     refinedQuery,
+		RewrittenQueries: rewrittenQueries,
+		Prompt:           finalLLMPrompt,
+		PromptVersions:   promptVersions,
+		Disclaimer:       s.localizedDisclaimer(ctx, req.Language),
+	}, answer.Delivered(), resChan)
+	s.titleSession(ctx, session, isGuest, req.Message, finalAnswer)
+
+	// 10. Roll turns leaving the context window into the summary, off the response path
+	go func() {
+		summaryCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+		defer cancel()
+		s.summarizeOlderTurns(summaryCtx, session.ID, isGuest, userParams, summary)
+	}()
+}
+
+// completeAnswer stores the answer in Redis and sends the final chunk with sources and citations.
+// Account holders' entries are copied to MongoDB by the ChatSyncWorker.
+func (s *ChatService) completeAnswer(ctx context.Context, llmChatEntry domain.ChatEntry, delivered string, resChan chan<- ChatResponseChunk) {
+	llmChatEntry.Type = domain.MessageTypeLLM
+	if id, err := primitive.ObjectIDFromHex(llmChatEntry.ID); err == nil {
+		llmChatEntry.MongoID = id // keeps the ID the caller was told
 	}
-	// Only save history for guests in Redis (in-memory)
-	if session.IsGuest {
-		if err := s.chatRepo.SaveChatEntry(ctx, &llmChatEntry); err != nil {
-			// If context was canceled, try saving after stream is done
-			if err.Error() == "failed to save chat entry to Redis: context canceled (client disconnected or request timed out)" {
-				// Try saving again with a new context
-				go func() {
-					saveCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-					defer cancel()
-					if err2 := s.chatRepo.SaveChatEntry(saveCtx, &llmChatEntry); err2 != nil {
-						log.Printf("Warning: Final save to Redis after stream also failed: %v", err2)
-					} else {
-						log.Printf("Info: Final save to Redis after stream succeeded for guest session %s", session.ID)
-					}
-				}()
-			} else {
-				log.Printf("Warning: Failed to save LLM chat entry to Redis: %v", err)
-			}
-		}
+	llmChatEntry.CreatedAt = time.Now()
+	// The client may already have disconnected, so don't let its cancellation drop the answer
+	saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 2*time.Second)
+	defer cancel()
+	if err := s.chatRepo.SaveChatEntry(saveCtx, &llmChatEntry); err != nil {
+		log.Printf("Warning: Failed to save LLM chat entry to Redis: %v", err)
 	}
+
+	// Send final chunk with sources and completion signal. The stored citations point into the stored English
+	// answer; the client gets them for the text it was sent, which may be a translation.
+	send(ctx, resChan, ChatResponseChunk{
+		Sources:    llmChatEntry.Sources,
+		Citations:  buildCitations(delivered, llmChatEntry.Sources),
+		MessageID:  llmChatEntry.ID,
+		Disclaimer: llmChatEntry.Disclaimer,
+		IsComplete: true,
+	})
+}
+
+// llmUserError turns an LLM provider failure into an error message that is safe to show the user.
+func llmUserError(err error) error {
+	switch {
+	case errors.Is(err, domain.ErrLLMRateLimited):
+		return errors.New("The assistant is handling too many requests right now. Please try again in a moment.")
+	case errors.Is(err, domain.ErrLLMSafetyBlocked):
+		return errors.New("Sorry, I can't answer that question. Please rephrase it and try again.")
+	case errors.Is(err, domain.ErrLLMContextTooLong):
+		return errors.New("This conversation is too long for me to answer. Please start a new chat.")
+	case errors.Is(err, domain.ErrLLMUnavailable):
+		return errors.New("Sorry, the assistant is temporarily unavailable. Please try again later.")
+	}
+	return fmt.Errorf("LLM stream error: %w", err)
 }
 
-// enforceLimits ensures the response adheres to word limits
+// enforceLimits ensures the response adheres to word limits. A citation marker split across words, such
+// as "[1, 3]", is kept whole rather than cut in half.
 func (s *ChatService) enforceLimits(text string, maxWords int) string {
-	words := strings.Fields(text)
-	if len(words) > maxWords {
-		return strings.Join(words[:maxWords], " ") + "..."
+	words := wordRe.FindAllStringIndex(text, -1)
+	if len(words) <= maxWords {
+		return text
+	}
+	if maxWords <= 0 {
+		return "..."
 	}
-	return text
+	cut := words[maxWords-1][1]
+	for _, marker := range citationMarkerRe.FindAllStringIndex(text, -1) {
+		if marker[0] < cut && cut < marker[1] {
+			cut = marker[1]
+			break
+		}
+	}
+	return strings.Join(strings.Fields(text[:cut]), " ") + "..."
 }
 
 // filterSources ensures the number of references adheres to limits
@@ -320,9 +573,9 @@ func (s *ChatService) filterSources(sources []domain.RAGSource, maxRefs int) []d
 	return sources
 }
 
-// ListSessions retrieves sessions for a user from MongoDB
-func (s *ChatService) ListSessions(ctx context.Context, userID string, page, limit int) ([]*domain.Session, int, error) {
-	return s.mongoSessionRepo.GetSessionsByUserID(ctx, userID, page, limit)
+// ListSessions retrieves a user's active or archived sessions from MongoDB
+func (s *ChatService) ListSessions(ctx context.Context, userID string, archived bool, page, limit int) ([]*domain.Session, int, error) {
+	return s.mongoSessionRepo.GetSessionsByUserID(ctx, userID, archived, page, limit)
 }
 
 // GetSession retrieves a session for a user from MongoDB (for listing/details, not active use)
@@ -332,68 +585,12 @@ func (s *ChatService) GetSession(ctx context.Context, sessionID string) (*domain
 
 // ListMessages retrieves messages for a session from MongoDB
 func (s *ChatService) ListMessages(ctx context.Context, sessionID string, limit int) ([]domain.ChatEntry, error) {
-	return s.mongoChatRepo.GetChatHistory(ctx, sessionID, limit)
-}
-
-// SyncChatHistoryFromRedisToDB is a background job function to sync account holder's chat history
-func (s *ChatService) SyncChatHistoryFromRedisToDB(ctx context.Context) {
-	ticker := time.NewTicker(s.cfg.ChatHistorySyncInterval)
-	defer ticker.Stop()
-
-	log.Printf("Starting chat history sync from Redis to MongoDB with interval: %s", s.cfg.ChatHistorySyncInterval)
-
-	for {
-		select {
-		case <-ctx.Done():
-			log.Println("Chat history sync stopped.")
-			return
-		case <-ticker.C:
-			log.Println("Running chat history sync cycle...")
-			sessionIDs, err := s.sessionRepo.GetUserSessionIDs(ctx) // Get all active user sessions from Redis Set
-			if err != nil {
-				log.Printf("Error fetching active user session IDs for sync: %v", err)
-				continue
-			}
-
-			if len(sessionIDs) == 0 {
-				log.Println("No active user sessions to sync.")
-				continue
-			}
-
-			for _, sessionID := range sessionIDs {
-				// 1. Get unsynced chat entries for this session from Redis
-				unsyncedEntries, err := s.chatRepo.GetUnsyncedChatEntries(ctx, sessionID)
-				if err != nil {
-					log.Printf("Error getting unsynced chat entries for session %s from Redis: %v", sessionID, err)
-					continue
-				}
-
-				if len(unsyncedEntries) == 0 {
-					// log.Printf("No unsynced entries for session %s.", sessionID) // Too verbose
-					continue
-				}
-
-				// 2. Bulk save these unsynced entries to MongoDB
-				err = s.mongoChatRepo.BulkSaveChatEntries(ctx, unsyncedEntries)
-				if err != nil {
-					log.Printf("Error bulk saving unsynced chat entries for session %s to MongoDB: %v", sessionID, err)
-					continue
-				}
-				log.Printf("Synced %d chat entries for session %s to MongoDB.", len(unsyncedEntries), sessionID)
-
-				// 3. Mark these entries as synced in Redis
-				var syncedMongoIDs []string
-				for _, entry := range unsyncedEntries {
-					syncedMongoIDs = append(syncedMongoIDs, entry.MongoID.Hex())
-				}
-				err = s.chatRepo.MarkChatEntriesAsSynced(ctx, sessionID, syncedMongoIDs)
-				if err != nil {
-					log.Printf("Error marking chat entries as synced in Redis for session %s: %v", sessionID, err)
-					// This is a critical point. If marking fails, these will be re-synced.
-					// Consider robust error handling or a separate retry mechanism.
-				}
-			}
-			log.Println("Chat history sync cycle completed.")
-		}
+	entries, err := s.mongoChatRepo.GetChatHistory(ctx, sessionID, limit)
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		entries[i] = entries[i].WithoutTrace()
 	}
+	return entries, nil
 }
@@ -0,0 +1,628 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/client"
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/config"
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+)
+
+const testAnswer = "An employer must pay severance to a dismissed employee [1]. The notice period is one month for workers employed under a year [2]."
+
+var testSources = []domain.RAGSource{
+	{Content: "Severance pay is due when a contract is terminated...", Source: "Labour Proclamation No. 1156/2019", ArticleNumber: "39"},
+	{Content: "The period of notice shall be one month...", Source: "Labour Proclamation No. 1156/2019", ArticleNumber: "35"},
+}
+
+// memSessionRepo keeps sessions in memory. Methods the chat flow doesn't use panic through the nil interface.
+type memSessionRepo struct {
+	domain.SessionRepository
+	mu       sync.Mutex
+	sessions map[string]domain.Session
+	deleted  map[string]bool
+}
+
+func newMemSessionRepo() *memSessionRepo {
+	return &memSessionRepo{sessions: map[string]domain.Session{}, deleted: map[string]bool{}}
+}
+
+func (r *memSessionRepo) GetSessionByID(ctx context.Context, id string) (*domain.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	session, ok := r.sessions[id]
+	if !ok {
+		return nil, domain.ErrSessionNotFound
+	}
+	return &session, nil
+}
+
+func (r *memSessionRepo) CreateSession(ctx context.Context, session *domain.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sessions[session.ID] = *session
+	return nil
+}
+
+func (r *memSessionRepo) UpdateSession(ctx context.Context, session *domain.Session) error {
+	return r.CreateSession(ctx, session)
+}
+
+func (r *memSessionRepo) RemoveUserSessionID(ctx context.Context, sessionID string) error {
+	return nil
+}
+
+func (r *memSessionRepo) MarkSessionDeleted(ctx context.Context, sessionID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deleted[sessionID] = true
+	return nil
+}
+
+func (r *memSessionRepo) IsSessionDeleted(ctx context.Context, sessionID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.deleted[sessionID], nil
+}
+
+// memChatRepo keeps chat entries and summaries in memory.
+type memChatRepo struct {
+	domain.ChatRepository
+	mu        sync.Mutex
+	entries   map[string][]domain.ChatEntry
+	summaries map[string]domain.ChatEntry
+}
+
+func newMemChatRepo() *memChatRepo {
+	return &memChatRepo{entries: map[string][]domain.ChatEntry{}, summaries: map[string]domain.ChatEntry{}}
+}
+
+func (r *memChatRepo) GetChatHistory(ctx context.Context, sessionID string, limit int) ([]domain.ChatEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entries := r.entries[sessionID]
+	if limit > 0 && len(entries) > 2*limit {
+		entries = entries[len(entries)-2*limit:]
+	}
+	return append([]domain.ChatEntry(nil), entries...), nil
+}
+
+func (r *memChatRepo) SaveChatEntry(ctx context.Context, entry *domain.ChatEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if entry.MongoID.IsZero() {
+		entry.MongoID = primitive.NewObjectID()
+		entry.ID = entry.MongoID.Hex()
+	}
+	r.entries[entry.SessionID] = append(r.entries[entry.SessionID], *entry)
+	return nil
+}
+
+func (r *memChatRepo) GetSummary(ctx context.Context, sessionID string) (*domain.ChatEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	summary, ok := r.summaries[sessionID]
+	if !ok {
+		return nil, nil
+	}
+	return &summary, nil
+}
+
+func (r *memChatRepo) SaveSummary(ctx context.Context, summary *domain.ChatEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.summaries[summary.SessionID] = *summary
+	return nil
+}
+
+func (r *memChatRepo) DeleteChatHistory(ctx context.Context, sessionID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.entries, sessionID)
+	delete(r.summaries, sessionID)
+	return nil
+}
+
+type fakeRAG struct {
+	result domain.RAGResult
+	err    error
+}
+
+func (r *fakeRAG) Retrieve(ctx context.Context, query string, k int) (*domain.RAGResult, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	result := r.result
+	return &result, ctx.Err()
+}
+
+func (r *fakeRAG) Close() error {
+	return nil
+}
+
+// memQuotaRepo counts usage in memory, per subject and limit.
+type memQuotaRepo struct {
+	mu   sync.Mutex
+	used map[string]int64
+}
+
+func newMemQuotaRepo() *memQuotaRepo {
+	return &memQuotaRepo{used: map[string]int64{}}
+}
+
+func memQuotaKey(subject string, limit domain.QuotaLimit) string {
+	return fmt.Sprintf("%s:%s:%s", subject, limit.Kind, limit.Period)
+}
+
+func (r *memQuotaRepo) Consume(ctx context.Context, subject string, amount int64, limits []domain.QuotaLimit) (*domain.QuotaLimit, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, limit := range limits {
+		if limit.Limit > 0 && r.used[memQuotaKey(subject, limit)]+amount > limit.Limit {
+			return &limits[i], nil
+		}
+	}
+	for _, limit := range limits {
+		r.used[memQuotaKey(subject, limit)] += amount
+	}
+	return nil, nil
+}
+
+func (r *memQuotaRepo) ConsumeUpTo(ctx context.Context, subject string, amount int64, limits []domain.QuotaLimit) (*domain.QuotaLimit, error) {
+	return r.Consume(ctx, subject, amount, limits)
+}
+
+func (r *memQuotaRepo) Refund(ctx context.Context, subject string, amount int64, limits []domain.QuotaLimit) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, limit := range limits {
+		key := memQuotaKey(subject, limit)
+		r.used[key] = max(r.used[key]-amount, 0)
+	}
+	return nil
+}
+
+func (r *memQuotaRepo) Usage(ctx context.Context, subject string, limits []domain.QuotaLimit) ([]domain.QuotaUsage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	usage := make([]domain.QuotaUsage, len(limits))
+	for i, limit := range limits {
+		usage[i] = domain.QuotaUsage{Kind: limit.Kind, Period: limit.Period, Used: r.used[memQuotaKey(subject, limit)], Limit: limit.Limit}
+	}
+	return usage, nil
+}
+
+// fakeTranslator marks translated text with the target language, taking delay per call.
+type fakeTranslator struct {
+	delay time.Duration
+}
+
+func (t *fakeTranslator) Translate(ctx context.Context, text, targetLang string) (string, error) {
+	select {
+	case <-time.After(t.delay):
+		return fmt.Sprintf("[%s] %s", targetLang, text), nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func (t *fakeTranslator) Close() error {
+	return nil
+}
+
+type emptyPromptRepo struct {
+	domain.PromptRepository
+}
+
+func (emptyPromptRepo) GetActiveRollouts(ctx context.Context) ([]domain.PromptRollout, error) {
+	return nil, nil
+}
+
+type emptyGlossaryRepo struct {
+	domain.GlossaryRepository
+}
+
+func (emptyGlossaryRepo) GetTerms(ctx context.Context) ([]domain.GlossaryTerm, error) {
+	return nil, nil
+}
+
+// testChat is a ChatService on the fake LLM provider with in-memory repositories and a fake RAG service.
+type testChat struct {
+	*ChatService
+	llm        *client.FakeLLMClient
+	rag        *fakeRAG
+	quota      *memQuotaRepo
+	sessions   *memSessionRepo
+	chats      *memChatRepo
+	mongoChats *memChatRepo
+}
+
+func newTestChat(t *testing.T, translator domain.TranslationService) *testChat {
+	t.Helper()
+	cfg, err := config.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	llm := client.NewFakeLLM(
+		client.FakeResponse{Match: "standalone search query", Text: "severance pay on dismissal"},
+		client.FakeResponse{Match: "short title", Text: "Severance pay"},
+		client.FakeResponse{Text: testAnswer},
+	)
+	chats := newMemChatRepo()
+	prompts, err := NewPromptService(cfg, emptyPromptRepo{}, chats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	guardrails, err := NewGuardrails(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessions := newMemSessionRepo()
+	rag := &fakeRAG{result: domain.RAGResult{Results: testSources}}
+	mongoChats := newMemChatRepo()
+	quota := newMemQuotaRepo()
+	s := NewChatService(cfg, sessions, chats, newMemSessionRepo(), mongoChats, llm, rag, nil, nil, quota, nil, nil,
+		translator, nil, prompts, guardrails, NewGlossaryService(cfg, emptyGlossaryRepo{}))
+	return &testChat{ChatService: s, llm: llm, rag: rag, quota: quota, sessions: sessions, chats: chats, mongoChats: mongoChats}
+}
+
+// receive returns the next chunk, failing the test if none arrives in time.
+func receive(t *testing.T, chunks <-chan ChatResponseChunk) (ChatResponseChunk, bool) {
+	t.Helper()
+	select {
+	case chunk, ok := <-chunks:
+		return chunk, ok
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a response chunk")
+		return ChatResponseChunk{}, false
+	}
+}
+
+func TestProcessQueryStreamsAnswerWithSources(t *testing.T) {
+	chat := newTestChat(t, &fakeTranslator{})
+	chunks, err := chat.ProcessQuery(context.Background(), QueryRequest{
+		UserID:  "user-1",
+		PlanID:  string(domain.TierFree),
+		Message: "Can my employer dismiss me without severance pay?",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, _ := receive(t, chunks)
+	if first.SessionID == "" {
+		t.Fatalf("first chunk = %+v, want the new session ID", first)
+	}
+	var text strings.Builder
+	var textChunks int
+	var final ChatResponseChunk
+	for {
+		chunk, ok := receive(t, chunks)
+		if !ok {
+			break
+		}
+		if chunk.Error != nil {
+			t.Fatalf("unexpected error chunk: %v", chunk.Error)
+		}
+		if chunk.IsComplete {
+			final = chunk
+			continue
+		}
+		if chunk.Text != "" {
+			text.WriteString(chunk.Text)
+			textChunks++
+		}
+	}
+
+	if got := text.String(); got != testAnswer {
+		t.Errorf("streamed text = %q, want %q", got, testAnswer)
+	}
+	if textChunks < 2 {
+		t.Errorf("answer arrived in %d chunk(s), want it streamed", textChunks)
+	}
+	if !final.IsComplete || final.MessageID == "" {
+		t.Fatalf("final chunk = %+v, want completion with a message ID", final)
+	}
+	if len(final.Sources) != len(testSources) {
+		t.Errorf("final chunk has %d sources, want %d", len(final.Sources), len(testSources))
+	}
+	citedArticles := map[int]string{}
+	for _, c := range final.Citations {
+		citedArticles[c.Marker] = c.ArticleNumber
+	}
+	if citedArticles[1] != "39" || citedArticles[2] != "35" {
+		t.Errorf("citations = %+v, want [1] -> article 39 and [2] -> article 35", final.Citations)
+	}
+
+	history, _ := chat.chats.GetChatHistory(context.Background(), first.SessionID, 0)
+	if len(history) != 2 || history[0].Type != domain.MessageTypeUser || history[1].Type != domain.MessageTypeLLM {
+		t.Fatalf("stored history = %+v, want the question and the answer", history)
+	}
+	if history[1].ID != final.MessageID || history[1].RefinedQuery != "severance pay on dismissal" {
+		t.Errorf("stored answer = %+v, want ID %s and the refined query", history[1], final.MessageID)
+	}
+}
+
+func TestProcessQueryCitesTranslatedSentences(t *testing.T) {
+	chat := newTestChat(t, &fakeTranslator{})
+	chunks, err := chat.ProcessQuery(context.Background(), QueryRequest{
+		UserID:   "user-1",
+		PlanID:   string(domain.TierFree),
+		Language: "am",
+		Message:  "Can my employer dismiss me without severance pay?",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var text strings.Builder
+	var final ChatResponseChunk
+	for {
+		chunk, ok := receive(t, chunks)
+		if !ok {
+			break
+		}
+		if chunk.Error != nil {
+			t.Fatalf("unexpected error chunk: %v", chunk.Error)
+		}
+		if chunk.IsComplete {
+			final = chunk
+		}
+		text.WriteString(chunk.Text)
+	}
+
+	if len(final.Citations) != 2 {
+		t.Fatalf("citations = %+v, want [1] and [2]", final.Citations)
+	}
+	for _, c := range final.Citations {
+		marker := fmt.Sprintf("[%d]", c.Marker)
+		for _, sentence := range c.Sentences {
+			if !strings.HasPrefix(sentence, "[am] ") || !strings.Contains(sentence, marker) {
+				t.Errorf("citation %s points at %q, want the translated sentence with its marker", marker, sentence)
+			}
+			if !strings.Contains(text.String(), sentence) {
+				t.Errorf("citation %s points at %q, which was never streamed", marker, sentence)
+			}
+		}
+	}
+}
+
+func TestProcessQueryRedactsStreamedPersonalData(t *testing.T) {
+	for _, language := range []string{"en", "am"} {
+		t.Run(language, func(t *testing.T) {
+			chat := newTestChat(t, &fakeTranslator{})
+			chat.llmService = client.NewFakeLLM(
+				client.FakeResponse{Match: "standalone search query", Text: "severance pay on dismissal"},
+				client.FakeResponse{Match: "short title", Text: "Severance pay"},
+				client.FakeResponse{Text: "Call the labour office on +251 911 234 567 or write to office@example.com [1]. It can help."},
+			)
+			chunks, err := chat.ProcessQuery(context.Background(), QueryRequest{
+				UserID:   "user-1",
+				PlanID:   string(domain.TierFree),
+				Language: language,
+				Message:  "Can my employer dismiss me without severance pay?",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var text strings.Builder
+			for {
+				chunk, ok := receive(t, chunks)
+				if !ok {
+					break
+				}
+				text.WriteString(chunk.Text)
+			}
+			streamed := text.String()
+			if strings.Contains(streamed, "911") || strings.Contains(streamed, "office@example.com") {
+				t.Errorf("streamed text %q contains personal data", streamed)
+			}
+			if !strings.Contains(streamed, "[PHONE]") || !strings.Contains(streamed, "[EMAIL] [1].") {
+				t.Errorf("streamed text = %q, want the phone number and email redacted", streamed)
+			}
+		})
+	}
+}
+
+func TestProcessQueryRejectsSessionsOfOthers(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		owner   string
+		userID  string
+		planID  domain.SubscriptionTier
+		isGuest bool
+	}{
+		{name: "another account's session", owner: "user-1", userID: "user-2", planID: domain.TierFree},
+		{name: "an account's session as a visitor", owner: "user-1", planID: domain.TierGuest},
+		{name: "a guest session as an account", isGuest: true, userID: "user-2", planID: domain.TierFree},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			chat := newTestChat(t, &fakeTranslator{})
+			session := domain.Session{ID: "session-1", UserID: tc.owner, IsGuest: tc.isGuest, Title: defaultSessionTitle}
+			chat.sessions.CreateSession(context.Background(), &session)
+
+			chunks, err := chat.ProcessQuery(context.Background(), QueryRequest{
+				SessionID: session.ID,
+				UserID:    tc.userID,
+				PlanID:    string(tc.planID),
+				ClientIP:  "192.0.2.1",
+				Message:   "Can my employer dismiss me without severance pay?",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			chunk, _ := receive(t, chunks)
+			if !errors.Is(chunk.Error, domain.ErrSessionAccessDenied) {
+				t.Fatalf("first chunk = %+v, want ErrSessionAccessDenied", chunk)
+			}
+			if history, _ := chat.chats.GetChatHistory(context.Background(), session.ID, 0); len(history) != 0 {
+				t.Errorf("stored %d entries in someone else's session", len(history))
+			}
+			if prompts := chat.llm.Prompts(); len(prompts) != 0 {
+				t.Errorf("the LLM was called %d time(s) for a rejected session", len(prompts))
+			}
+		})
+	}
+}
+
+func TestSummaryOfDeletedSessionIsDiscarded(t *testing.T) {
+	chat := newTestChat(t, &fakeTranslator{})
+	ctx := context.Background()
+	params := domain.GetUserParamsFromPlanID(string(domain.TierFree))
+	for i := 0; i < 2*params.ContextWindow+2; i++ {
+		chat.chats.SaveChatEntry(ctx, &domain.ChatEntry{SessionID: "session-1", Type: domain.MessageTypeUser, Content: "question", CreatedAt: time.Now()})
+	}
+	// The session is deleted while its summary is being generated
+	chat.sessions.MarkSessionDeleted(ctx, "session-1")
+
+	chat.summarizeOlderTurns(ctx, "session-1", false, params, nil)
+	if len(chat.llm.Prompts()) == 0 {
+		t.Fatal("the summary was never generated")
+	}
+	for name, repo := range map[string]*memChatRepo{"Redis": chat.chats, "MongoDB": chat.mongoChats} {
+		if summary, _ := repo.GetSummary(ctx, "session-1"); summary != nil {
+			t.Errorf("%s kept the summary of a deleted session: %+v", name, summary)
+		}
+	}
+}
+
+// queriesUsed returns how many queries the subject has used today.
+func (c *testChat) queriesUsed(t *testing.T, userID, clientIP string) int64 {
+	t.Helper()
+	usage, err := c.GetUsage(context.Background(), userID, clientIP, string(domain.TierFree))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, u := range usage {
+		if u.Kind == domain.QuotaQueries && u.Period == domain.QuotaDaily {
+			return u.Used
+		}
+	}
+	return 0
+}
+
+func TestRefusedQueriesDoNotUseQuota(t *testing.T) {
+	t.Run("someone else's session", func(t *testing.T) {
+		chat := newTestChat(t, &fakeTranslator{})
+		session := domain.Session{ID: "session-1", UserID: "user-1", Title: defaultSessionTitle}
+		chat.sessions.CreateSession(context.Background(), &session)
+
+		chunks, err := chat.ProcessQuery(context.Background(), QueryRequest{
+			SessionID: session.ID,
+			UserID:    "user-2",
+			PlanID:    string(domain.TierFree),
+			Message:   "Can my employer dismiss me without severance pay?",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if chunk, _ := receive(t, chunks); !errors.Is(chunk.Error, domain.ErrSessionAccessDenied) {
+			t.Fatalf("first chunk = %+v, want ErrSessionAccessDenied", chunk)
+		}
+		if used := chat.queriesUsed(t, "user-2", ""); used != 0 {
+			t.Errorf("a rejected session used %d queries, want 0", used)
+		}
+	})
+
+	t.Run("retrieval failure", func(t *testing.T) {
+		chat := newTestChat(t, &fakeTranslator{})
+		chat.rag.err = domain.ErrRAGUnavailable
+
+		chunks, err := chat.ProcessQuery(context.Background(), QueryRequest{
+			UserID:  "user-1",
+			PlanID:  string(domain.TierFree),
+			Message: "Can my employer dismiss me without severance pay?",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var failed bool
+		for {
+			chunk, ok := receive(t, chunks)
+			if !ok {
+				break
+			}
+			failed = failed || chunk.Error != nil
+		}
+		if !failed {
+			t.Fatal("want an error chunk when retrieval fails")
+		}
+		if used := chat.queriesUsed(t, "user-1", ""); used != 0 {
+			t.Errorf("a failed query used %d queries, want 0", used)
+		}
+	})
+
+	t.Run("no sources", func(t *testing.T) {
+		chat := newTestChat(t, &fakeTranslator{})
+		chat.rag.result = domain.RAGResult{}
+
+		chunks, err := chat.ProcessQuery(context.Background(), QueryRequest{
+			UserID:  "user-1",
+			PlanID:  string(domain.TierFree),
+			Message: "Can my employer dismiss me without severance pay?",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for {
+			chunk, ok := receive(t, chunks)
+			if !ok {
+				break
+			}
+			if chunk.Error != nil {
+				t.Fatalf("unexpected error chunk: %v", chunk.Error)
+			}
+		}
+		if used := chat.queriesUsed(t, "user-1", ""); used != 0 {
+			t.Errorf("a query without sources used %d queries, want 0", used)
+		}
+	})
+
+	t.Run("generation fails mid-stream", func(t *testing.T) {
+		chat := newTestChat(t, &fakeTranslator{})
+		chat.llmService = &brokenStreamLLM{FakeLLMClient: chat.llm}
+
+		chunks, err := chat.ProcessQuery(context.Background(), QueryRequest{
+			UserID:  "user-1",
+			PlanID:  string(domain.TierFree),
+			Message: "Can my employer dismiss me without severance pay?",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var failed bool
+		for {
+			chunk, ok := receive(t, chunks)
+			if !ok {
+				break
+			}
+			failed = failed || chunk.Error != nil
+		}
+		if !failed {
+			t.Fatal("want an error chunk when generation fails")
+		}
+		if used := chat.queriesUsed(t, "user-1", ""); used != 0 {
+			t.Errorf("a failed answer used %d queries, want 0", used)
+		}
+	})
+}
+
+// brokenStreamLLM streams the start of an answer and then fails.
+type brokenStreamLLM struct {
+	*client.FakeLLMClient
+}
+
+func (l *brokenStreamLLM) StreamGenerate(ctx context.Context, prompt string, history []domain.ChatEntry, maxWords int) (<-chan domain.LLMStreamResponse, error) {
+	out := make(chan domain.LLMStreamResponse, 2)
+	out <- domain.LLMStreamResponse{Chunk: "No. Your employer"}
+	out <- domain.LLMStreamResponse{Error: errors.New("upstream connection reset")}
+	close(out)
+	return out, nil
+}
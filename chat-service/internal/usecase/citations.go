@@ -0,0 +1,168 @@
+package usecase
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+)
+
+// citationMarkerRe matches inline citation markers such as [1] or [1, 3], with the space before them.
+var citationMarkerRe = regexp.MustCompile(`(\s?)\[(\d+(?:\s*,\s*\d+)*)\]`)
+
+// leadingMarkerRe matches a citation marker at the start of a text, such as one placed after a full stop.
+var leadingMarkerRe = regexp.MustCompile(`^\[\d+(?:\s*,\s*\d+)*\]`)
+
+// citationPlaceholderRe matches the placeholders citation markers travel as through translation. They
+// differ from the glossary's [[n]], which are added and restored inside the translator.
+var citationPlaceholderRe = regexp.MustCompile(`\[\[\s*C(\d+)\s*\]\]`)
+
+// strayPlaceholderRe matches a citation placeholder with the space before it, to remove it cleanly.
+var strayPlaceholderRe = regexp.MustCompile(`\s*\[\[\s*C\d*\s*\]\]`)
+
+// wordRe matches one word of an answer when counting it against the plan's word limit.
+var wordRe = regexp.MustCompile(`\S+`)
+
+// maxPendingCitation bounds how much streamed text is held back waiting for a "[" to be closed.
+const maxPendingCitation = 16
+
+// formatSourcesForPrompt numbers the sources so the model can cite them as [1], [2], ... How to cite them
+// is up to the answer prompt, so it is versioned with it.
+func formatSourcesForPrompt(sources []domain.RAGSource) string {
+	var b strings.Builder
+	for i, source := range sources {
+		b.WriteString(fmt.Sprintf("[%d] [Source: %s, Article: %s]\n%s\n", i+1, source.Source, source.ArticleNumber, source.Content))
+	}
+	return b.String()
+}
+
+// citedMarkers returns the marker numbers in a "1, 3" list that refer to one of numSources sources.
+func citedMarkers(list string, numSources int) []int {
+	var markers []int
+	for _, part := range strings.Split(list, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err == nil && n >= 1 && n <= numSources {
+			markers = append(markers, n)
+		}
+	}
+	return markers
+}
+
+// stripInvalidCitations removes markers pointing at sources the model was never given.
+func stripInvalidCitations(text string, numSources int) string {
+	return citationMarkerRe.ReplaceAllStringFunc(text, func(match string) string {
+		sub := citationMarkerRe.FindStringSubmatch(match)
+		markers := citedMarkers(sub[2], numSources)
+		if len(markers) == 0 {
+			return ""
+		}
+		parts := make([]string, len(markers))
+		for i, n := range markers {
+			parts[i] = strconv.Itoa(n)
+		}
+		return sub[1] + "[" + strings.Join(parts, ", ") + "]"
+	})
+}
+
+// buildCitations maps every valid marker in the answer to its source and the sentences citing it. The
+// sentences are split the way the answer was streamed, so they match what the client was shown.
+func buildCitations(answer string, sources []domain.RAGSource) []domain.Citation {
+	byMarker := make(map[int]*domain.Citation)
+	for _, sentence := range splitSentences(answer) {
+		sentence = strings.TrimSpace(sentence)
+		if sentence == "" {
+			continue
+		}
+		for _, sub := range citationMarkerRe.FindAllStringSubmatch(sentence, -1) {
+			for _, n := range citedMarkers(sub[2], len(sources)) {
+				c, ok := byMarker[n]
+				if !ok {
+					c = &domain.Citation{Marker: n, Source: sources[n-1].Source, ArticleNumber: sources[n-1].ArticleNumber}
+					byMarker[n] = c
+				}
+				if len(c.Sentences) == 0 || c.Sentences[len(c.Sentences)-1] != sentence {
+					c.Sentences = append(c.Sentences, sentence)
+				}
+			}
+		}
+	}
+
+	citations := make([]domain.Citation, 0, len(byMarker))
+	for _, c := range byMarker {
+		citations = append(citations, *c)
+	}
+	sort.Slice(citations, func(i, j int) bool { return citations[i].Marker < citations[j].Marker })
+	return citations
+}
+
+// citationStripper applies stripInvalidCitations to streamed text. A marker can be split across
+// chunks, so text from an unclosed "[" onwards is held back until it is closed or grows too long.
+type citationStripper struct {
+	numSources int
+	pending    string
+}
+
+// Write returns the part of the stream that is safe to forward to the client.
+func (c *citationStripper) Write(chunk string) string {
+	c.pending += chunk
+	cut := len(c.pending)
+	if i := strings.LastIndex(c.pending, "["); i >= 0 && !strings.Contains(c.pending[i:], "]") && len(c.pending)-i <= maxPendingCitation {
+		cut = i
+		if cut > 0 && c.pending[cut-1] == ' ' {
+			cut--
+		}
+	}
+	out := c.pending[:cut]
+	c.pending = c.pending[cut:]
+	return stripInvalidCitations(out, c.numSources)
+}
+
+// Flush returns whatever text is still held back at the end of the stream.
+func (c *citationStripper) Flush() string {
+	out := stripInvalidCitations(c.pending, c.numSources)
+	c.pending = ""
+	return out
+}
+
+// protectCitations replaces the citation markers of a sentence with numbered placeholders, so the translator
+// can neither drop nor renumber them. It returns the markers in placeholder order.
+func protectCitations(text string) (string, []string) {
+	var markers []string
+	protected := citationMarkerRe.ReplaceAllStringFunc(text, func(match string) string {
+		sub := citationMarkerRe.FindStringSubmatch(match)
+		markers = append(markers, match[len(sub[1]):])
+		return fmt.Sprintf("%s[[C%d]]", sub[1], len(markers))
+	})
+	return protected, markers
+}
+
+// restoreCitations puts the markers back into a translation of a protected sentence. If the translation lost
+// or mangled a placeholder, the leftovers are removed and all markers are put at the end of the sentence,
+// where the model places them anyway.
+func restoreCitations(translated string, markers []string) string {
+	if len(markers) == 0 {
+		return translated
+	}
+	seen := make([]bool, len(markers))
+	complete := true
+	restored := citationPlaceholderRe.ReplaceAllStringFunc(translated, func(placeholder string) string {
+		n, _ := strconv.Atoi(citationPlaceholderRe.FindStringSubmatch(placeholder)[1])
+		if n < 1 || n > len(markers) {
+			complete = false
+			return placeholder
+		}
+		seen[n-1] = true
+		return markers[n-1]
+	})
+	for _, ok := range seen {
+		complete = complete && ok
+	}
+	if complete {
+		return restored
+	}
+	stripped := strings.TrimSpace(strayPlaceholderRe.ReplaceAllString(translated, ""))
+	return stripped + " " + strings.Join(markers, " ")
+}
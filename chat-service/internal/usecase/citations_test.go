@@ -0,0 +1,50 @@
+package usecase
+
+import (
+	"testing"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+)
+
+func TestEnforceLimitsKeepsCitationMarkersWhole(t *testing.T) {
+	s := &ChatService{}
+	for _, tc := range []struct {
+		text     string
+		maxWords int
+		want     string
+	}{
+		{"Severance is due [1, 3]. Notice is one month [2].", 4, "Severance is due [1, 3]..."},
+		{"Severance is due [1, 3]. Notice is one month [2].", 3, "Severance is due..."},
+		{"Severance is due [1].", 10, "Severance is due [1]."},
+	} {
+		if got := s.enforceLimits(tc.text, tc.maxWords); got != tc.want {
+			t.Errorf("enforceLimits(%q, %d) = %q, want %q", tc.text, tc.maxWords, got, tc.want)
+		}
+	}
+}
+
+func TestBuildCitationsSplitsSentencesLikeTheStream(t *testing.T) {
+	sources := []domain.RAGSource{{Source: "Labour Proclamation", ArticleNumber: "39"}, {Source: "Labour Proclamation", ArticleNumber: "35"}}
+	citations := buildCitations("Severance is due under Art. 39 of Proc. No. 1156/2019 [1]. Notice is one month. [2] Ask a lawyer.", sources)
+	if len(citations) != 2 {
+		t.Fatalf("citations = %+v, want two", citations)
+	}
+	for i, want := range []string{"Severance is due under Art. 39 of Proc. No. 1156/2019 [1].", "Notice is one month. [2]"} {
+		if got := citations[i].Sentences; len(got) != 1 || got[0] != want {
+			t.Errorf("citation [%d] sentences = %q, want [%q]", citations[i].Marker, got, want)
+		}
+	}
+}
+
+func TestCitationMarkersSurviveTranslation(t *testing.T) {
+	protected, markers := protectCitations("Severance is due [1, 3]. See also [2].")
+	if protected != "Severance is due [[C1]]. See also [[C2]]." {
+		t.Fatalf("protected = %q", protected)
+	}
+	if got := restoreCitations("ካሳ ይከፈላል [[C1]]። ይመልከቱ [[C2]]።", markers); got != "ካሳ ይከፈላል [1, 3]። ይመልከቱ [2]።" {
+		t.Errorf("restored = %q", got)
+	}
+	if got := restoreCitations("ካሳ ይከፈላል [[C1]]። ይመልከቱ።", markers); got != "ካሳ ይከፈላል። ይመልከቱ። [1, 3] [2]" {
+		t.Errorf("restored after a lost placeholder = %q", got)
+	}
+}
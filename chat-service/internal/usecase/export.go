@@ -0,0 +1,112 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/export"
+)
+
+// ExportSession prepares one of the user's sessions for export as a brief in the given language.
+func (s *ChatService) ExportSession(ctx context.Context, userID, sessionID, language string) (*export.Brief, error) {
+	session, err := s.ownedSession(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return s.buildBrief(ctx, session, language)
+}
+
+// CreateExportLink signs a download link for one of the user's sessions that works without authentication
+// until it expires, or until the session is archived, deleted or changes hands.
+func (s *ChatService) CreateExportLink(ctx context.Context, userID, sessionID string, format export.Format, language string) (export.Link, string, error) {
+	if s.cfg.ExportLinkSecret == "" {
+		return export.Link{}, "", export.ErrLinksDisabled
+	}
+	session, err := s.ownedSession(ctx, userID, sessionID)
+	if err != nil {
+		return export.Link{}, "", err
+	}
+	if session.Archived {
+		return export.Link{}, "", fmt.Errorf("%w: archived sessions can't be shared", domain.ErrSessionAccessDenied)
+	}
+	link := export.Link{
+		SessionID: sessionID,
+		OwnerID:   session.UserID,
+		Format:    format,
+		Language:  language,
+		Expires:   time.Now().Add(s.cfg.ExportLinkTTL).Truncate(time.Second),
+	}
+	return link, link.Sign(s.cfg.ExportLinkSecret), nil
+}
+
+// ExportLinkedSession checks a download link's query values against the session it points to and prepares
+// that session's brief. Links to sessions that were archived, deleted or signed for another owner are invalid.
+func (s *ChatService) ExportLinkedSession(ctx context.Context, sessionID, format, language, expires, signature string) (export.Link, *export.Brief, error) {
+	if s.cfg.ExportLinkSecret == "" {
+		return export.Link{}, nil, export.ErrInvalidLink
+	}
+	session, err := s.mongoSessionRepo.GetSessionByID(ctx, sessionID)
+	if err != nil {
+		log.Printf("Export link for unknown session %s: %v", sessionID, err)
+		return export.Link{}, nil, export.ErrInvalidLink
+	}
+	link, err := export.VerifyLink(s.cfg.ExportLinkSecret, session.UserID, sessionID, format, language, expires, signature)
+	if err != nil {
+		return export.Link{}, nil, err
+	}
+	if session.Archived || session.UserID == "" {
+		return export.Link{}, nil, export.ErrInvalidLink
+	}
+	deleted, err := s.sessionRepo.IsSessionDeleted(ctx, sessionID)
+	if err != nil {
+		return export.Link{}, nil, err
+	}
+	if deleted {
+		return export.Link{}, nil, export.ErrInvalidLink
+	}
+	brief, err := s.buildBrief(ctx, session, link.Language)
+	if err != nil {
+		return export.Link{}, nil, err
+	}
+	return link, brief, nil
+}
+
+// buildBrief collects the session's synced messages. Answers are stored in English, so for other languages
+// they are translated; an answer that fails to translate is kept in English rather than dropped.
+func (s *ChatService) buildBrief(ctx context.Context, session *domain.Session, language string) (*export.Brief, error) {
+	entries, err := s.ListMessages(ctx, session.ID, 0)
+	if err != nil {
+		return nil, err
+	}
+	title := session.Title
+	if title == "" {
+		title = defaultSessionTitle
+	}
+	brief := &export.Brief{
+		Title:       title,
+		Language:    language,
+		StartedAt:   session.CreatedAt,
+		GeneratedAt: time.Now(),
+	}
+	for _, entry := range entries {
+		switch entry.Type {
+		case domain.MessageTypeUser:
+			brief.Messages = append(brief.Messages, export.Message{IsQuestion: true, Content: entry.Content})
+		case domain.MessageTypeLLM:
+			content := entry.Content
+			if language != "en" {
+				translated, err := s.translator.Translate(ctx, content, language)
+				if err != nil || translated == "" {
+					log.Printf("Warning: Failed to translate answer %s for export: %v", entry.ID, err)
+				} else {
+					content = translated
+				}
+			}
+			brief.Messages = append(brief.Messages, export.Message{Content: content, Sources: entry.Sources})
+		}
+	}
+	return brief, nil
+}
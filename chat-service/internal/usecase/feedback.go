@@ -0,0 +1,102 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+)
+
+const maxFeedbackReportLimit = 100
+
+// RateAnswer stores the user's rating on an answer in one of their sessions and records an analytics event.
+// The rating is written to Redis and MongoDB alike, since a recent answer may not have been synced yet.
+func (s *ChatService) RateAnswer(ctx context.Context, principal domain.Principal, sessionID, entryID string, rating domain.FeedbackRating, reason domain.FeedbackReason) (*domain.AnswerFeedback, error) {
+	switch {
+	case rating != domain.FeedbackUp && rating != domain.FeedbackDown:
+		return nil, fmt.Errorf("%w: rating must be %q or %q", domain.ErrInvalidFeedback, domain.FeedbackUp, domain.FeedbackDown)
+	case reason != "" && rating == domain.FeedbackUp:
+		return nil, fmt.Errorf("%w: a reason can only be given with a thumbs down", domain.ErrInvalidFeedback)
+	case reason != "" && !reason.Valid():
+		return nil, fmt.Errorf("%w: unknown reason %q", domain.ErrInvalidFeedback, reason)
+	}
+	if _, err := s.ownedSession(ctx, principal.UserID, sessionID); err != nil {
+		return nil, err
+	}
+
+	feedback := &domain.AnswerFeedback{Rating: rating, Reason: reason, CreatedAt: time.Now()}
+	redisErr := s.chatRepo.SetFeedback(ctx, sessionID, entryID, feedback)
+	if redisErr != nil && !errors.Is(redisErr, domain.ErrChatEntryNotFound) {
+		return nil, redisErr
+	}
+	mongoErr := s.mongoChatRepo.SetFeedback(ctx, sessionID, entryID, feedback)
+	if mongoErr != nil && !errors.Is(mongoErr, domain.ErrChatEntryNotFound) {
+		return nil, mongoErr
+	}
+	if redisErr != nil && mongoErr != nil {
+		return nil, domain.ErrChatEntryNotFound
+	}
+
+	s.recordFeedbackEvent(ctx, principal, sessionID, entryID, feedback)
+	return feedback, nil
+}
+
+func (s *ChatService) recordFeedbackEvent(ctx context.Context, principal domain.Principal, sessionID, entryID string, feedback *domain.AnswerFeedback) {
+	payload := domain.AnswerFeedbackAnalytic{
+		SessionID: sessionID,
+		EntryID:   entryID,
+		Rating:    feedback.Rating,
+		Reason:    feedback.Reason,
+		Age:       principal.Age,
+		Gender:    principal.Gender,
+	}
+	if entry := s.findAnswer(ctx, sessionID, entryID); entry != nil {
+		for _, source := range entry.Sources {
+			payload.Sources = append(payload.Sources, source.Source)
+		}
+		payload.PromptVersions = entry.PromptVersions
+	}
+	event := &domain.AnalyticsEvent{
+		EventType: domain.EventAnswerFeedback,
+		UserID:    principal.UserID,
+		Payload:   payload,
+		Timestamp: feedback.CreatedAt.Unix(),
+	}
+	if err := s.analyticsRepo.SaveEvent(ctx, event); err != nil {
+		log.Printf("Warning: Failed to record feedback event for answer %s: %v", entryID, err)
+	}
+}
+
+// findAnswer looks an entry up in the active history first and then in MongoDB.
+func (s *ChatService) findAnswer(ctx context.Context, sessionID, entryID string) *domain.ChatEntry {
+	for _, repo := range []domain.ChatRepository{s.chatRepo, s.mongoChatRepo} {
+		entries, err := repo.GetChatHistory(ctx, sessionID, 0)
+		if err != nil {
+			continue
+		}
+		for i := range entries {
+			if entries[i].ID == entryID {
+				return &entries[i]
+			}
+		}
+	}
+	return nil
+}
+
+// GetFeedbackReport returns the low-rated answers in the filter's time range with their prompts, sources
+// and refined queries.
+func (s *ChatService) GetFeedbackReport(ctx context.Context, filter domain.FeedbackReportFilter) (*domain.FeedbackReport, error) {
+	if filter.Reason != "" && !filter.Reason.Valid() {
+		return nil, fmt.Errorf("%w: unknown reason %q", domain.ErrInvalidFeedback, filter.Reason)
+	}
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Limit < 1 || filter.Limit > maxFeedbackReportLimit {
+		filter.Limit = 20
+	}
+	return s.mongoChatRepo.GetFeedbackReport(ctx, filter)
+}
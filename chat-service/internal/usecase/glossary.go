@@ -0,0 +1,518 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/config"
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+)
+
+const (
+	maxGlossaryTermLen       = 100
+	maxGlossaryDefinitionLen = 1000
+	maxGlossaryResults       = 20
+	glossaryReloadTimeout    = 3 * time.Second
+)
+
+var (
+	languageCodeRe = regexp.MustCompile(`^[a-z]{2,3}$`)
+	// Placeholders standing for glossary terms during translation, e.g. [[2]]; translators may add spaces inside
+	glossaryPlaceholderRe = regexp.MustCompile(`\[\[\s*(\d+)\s*\]\]`)
+)
+
+// defaultGlossaryTerms seed an empty glossary.
+var defaultGlossaryTerms = []domain.GlossaryTerm{
+	{Term: "proclamation", Translations: map[string]string{"am": "አዋጅ", "om": "labsii"},
+		Definitions: map[string]string{"en": "A law made by the federal parliament or a regional council."}},
+	{Term: "regulation", Translations: map[string]string{"am": "ደንብ", "om": "dambii"},
+		Definitions: map[string]string{"en": "Detailed rules issued by the Council of Ministers to implement a proclamation."}},
+	{Term: "directive", Translations: map[string]string{"am": "መመሪያ", "om": "qajeelfama"},
+		Definitions: map[string]string{"en": "Rules issued by a ministry or agency to implement a proclamation or regulation."}},
+	{Term: "constitution", Translations: map[string]string{"am": "ሕገ መንግሥት", "om": "heera mootummaa"},
+		Definitions: map[string]string{"en": "The supreme law of the country; any law contrary to it has no effect."}},
+	{Term: "court", Translations: map[string]string{"am": "ፍርድ ቤት", "om": "mana murtii"},
+		Definitions: map[string]string{"en": "A body that settles disputes and tries cases according to the law."}},
+	{Term: "woreda court", Translations: map[string]string{"am": "የወረዳ ፍርድ ቤት", "om": "mana murtii aanaa"},
+		Definitions: map[string]string{"en": "The regional first instance court of a woreda (district)."}},
+	{Term: "high court", Translations: map[string]string{"am": "ከፍተኛ ፍርድ ቤት", "om": "mana murtii olaanaa"},
+		Definitions: map[string]string{"en": "A court that hears serious cases at first instance and appeals from lower courts."}},
+	{Term: "supreme court", Translations: map[string]string{"am": "ጠቅላይ ፍርድ ቤት", "om": "mana murtii waliigalaa"},
+		Definitions: map[string]string{"en": "The highest court of the federal government or of a region."}},
+	{Term: "cassation", Translations: map[string]string{"am": "ሰበር", "om": "dhaddacha ijibbaataa"},
+		Definitions: map[string]string{"en": "Review of a final decision for a basic error of law; federal cassation decisions bind all courts."}},
+	{Term: "appeal", Translations: map[string]string{"am": "ይግባኝ", "om": "ol iyyannoo"},
+		Definitions: map[string]string{"en": "A request to a higher court to change the decision of a lower one."}},
+	{Term: "plaintiff", Translations: map[string]string{"am": "ከሳሽ", "om": "himataa"},
+		Definitions: map[string]string{"en": "The party who brings a civil case to court."}},
+	{Term: "defendant", Translations: map[string]string{"am": "ተከሳሽ", "om": "himatamaa"},
+		Definitions: map[string]string{"en": "The party a case is brought against."}},
+	{Term: "contract", Translations: map[string]string{"am": "ውል", "om": "waliigaltee"},
+		Definitions: map[string]string{"en": "An agreement that creates obligations the law enforces."}},
+	{Term: "advocate", Translations: map[string]string{"am": "ጠበቃ", "om": "abukaatoo"},
+		Definitions: map[string]string{"en": "A licensed lawyer who may represent parties in court."}},
+	{Term: "civil code", Translations: map[string]string{"am": "የፍትሐ ብሔር ሕግ", "om": "seera hariiroo hawaasaa"},
+		Definitions: map[string]string{"en": "The 1960 code governing persons, family, property, contracts and succession."}},
+	{Term: "criminal code", Translations: map[string]string{"am": "የወንጀል ሕግ", "om": "seera yakkaa"},
+		Definitions: map[string]string{"en": "The 2004 code defining crimes and their punishments."}},
+}
+
+// GlossaryService keeps the legal glossary: the approved equivalents of legal terms, which translations are
+// made to use, and their definitions, which the frontend shows as tooltips. The terms are cached and
+// reloaded from MongoDB every refresh interval.
+type GlossaryService struct {
+	repo    domain.GlossaryRepository
+	refresh time.Duration
+
+	reloadMu sync.Mutex // held by the request reloading the terms
+	mu       sync.RWMutex
+	index    *glossaryIndex
+	loadedAt time.Time
+}
+
+func NewGlossaryService(cfg *config.Config, repo domain.GlossaryRepository) *GlossaryService {
+	return &GlossaryService{
+		repo:    repo,
+		refresh: cfg.GlossaryRefreshInterval,
+		index:   buildGlossaryIndex(nil),
+	}
+}
+
+// SeedDefaults stores the built-in terms if the glossary is empty.
+func (s *GlossaryService) SeedDefaults(ctx context.Context) error {
+	terms, err := s.repo.GetTerms(ctx)
+	if err != nil || len(terms) > 0 {
+		return err
+	}
+	for _, term := range defaultGlossaryTerms {
+		term.Key = glossaryKey(term.Term)
+		// Another replica may be seeding at the same time
+		if err := s.repo.CreateTerm(ctx, &term); err != nil && !errors.Is(err, domain.ErrDuplicateGlossaryTerm) {
+			return err
+		}
+	}
+	s.invalidate()
+	return nil
+}
+
+// glossaryIndex finds the terms of the glossary in texts.
+type glossaryIndex struct {
+	terms    []domain.GlossaryTerm
+	matchers map[string]*glossaryMatcher // by the language of the texts searched
+}
+
+type glossaryMatcher struct {
+	re    *regexp.Regexp
+	terms map[string]int // glossaryKey of a term in the language -> index in glossaryIndex.terms
+}
+
+// glossaryMatch is an occurrence of a term in a text.
+type glossaryMatch struct {
+	start, end int
+	term       int
+}
+
+func buildGlossaryIndex(terms []domain.GlossaryTerm) *glossaryIndex {
+	keys := map[string]map[string]int{}
+	add := func(language, text string, i int) {
+		key := glossaryKey(text)
+		if key == "" {
+			return
+		}
+		if keys[language] == nil {
+			keys[language] = map[string]int{}
+		}
+		if _, ok := keys[language][key]; !ok {
+			keys[language][key] = i
+		}
+	}
+	for i, term := range terms {
+		add("en", term.Term, i)
+		for language, text := range term.Translations {
+			add(language, text, i)
+		}
+	}
+
+	index := &glossaryIndex{terms: terms, matchers: make(map[string]*glossaryMatcher, len(keys))}
+	for language, byKey := range keys {
+		sorted := make([]string, 0, len(byKey))
+		for key := range byKey {
+			sorted = append(sorted, key)
+		}
+		// Longer terms first, so "woreda court" wins over "court"
+		sort.Slice(sorted, func(i, j int) bool {
+			if len(sorted[i]) != len(sorted[j]) {
+				return len(sorted[i]) > len(sorted[j])
+			}
+			return sorted[i] < sorted[j]
+		})
+		patterns := make([]string, len(sorted))
+		for i, key := range sorted {
+			patterns[i] = termPattern(key)
+		}
+		re, err := regexp.Compile("(?i)" + strings.Join(patterns, "|"))
+		if err != nil {
+			log.Printf("Warning: Failed to compile the %s glossary terms: %v", language, err)
+			continue
+		}
+		index.matchers[language] = &glossaryMatcher{re: re, terms: byKey}
+	}
+	return index
+}
+
+// termPattern matches a term with any whitespace between its words. Latin-script terms only match whole
+// words; Ge'ez-script ones also match inside words, which take prefixes such as የ and በ.
+func termPattern(key string) string {
+	words := strings.Fields(key)
+	for i, word := range words {
+		words[i] = regexp.QuoteMeta(word)
+	}
+	pattern := strings.Join(words, `\s+`)
+	if isWordByte(key[0]) {
+		pattern = `\b` + pattern
+	}
+	if isWordByte(key[len(key)-1]) {
+		pattern += `\b`
+	}
+	return pattern
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || '0' <= b && b <= '9' || 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z'
+}
+
+func glossaryKey(text string) string {
+	return strings.ToLower(strings.Join(strings.Fields(text), " "))
+}
+
+// find returns the occurrences of terms in a text written in language, in order.
+func (ix *glossaryIndex) find(text, language string) []glossaryMatch {
+	m := ix.matchers[language]
+	if m == nil {
+		return nil
+	}
+	var matches []glossaryMatch
+	for _, loc := range m.re.FindAllStringIndex(text, -1) {
+		if i, ok := m.terms[glossaryKey(text[loc[0]:loc[1]])]; ok {
+			matches = append(matches, glossaryMatch{start: loc[0], end: loc[1], term: i})
+		}
+	}
+	return matches
+}
+
+// termIn returns a term in language, or "" if it has no approved equivalent there.
+func termIn(term domain.GlossaryTerm, language string) string {
+	if language == "en" {
+		return term.Term
+	}
+	return term.Translations[language]
+}
+
+// current returns the cached terms, reloading them first once they are older than the refresh interval.
+func (s *GlossaryService) current(ctx context.Context) *glossaryIndex {
+	s.reloadIfStale(ctx)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.index
+}
+
+// reloadIfStale works like PromptService.reloadIfStale: one request reloads while the others use the cache.
+func (s *GlossaryService) reloadIfStale(ctx context.Context) {
+	s.mu.RLock()
+	stale := time.Since(s.loadedAt) >= s.refresh
+	s.mu.RUnlock()
+	if !stale || !s.reloadMu.TryLock() {
+		return
+	}
+	defer s.reloadMu.Unlock()
+
+	reloadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), glossaryReloadTimeout)
+	defer cancel()
+	terms, err := s.repo.GetTerms(reloadCtx)
+	var index *glossaryIndex
+	if err == nil {
+		index = buildGlossaryIndex(terms)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadedAt = time.Now()
+	if err != nil {
+		log.Printf("Warning: Failed to reload the glossary, keeping the current terms: %v", err)
+		return
+	}
+	s.index = index
+}
+
+func (s *GlossaryService) invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadedAt = time.Time{}
+}
+
+// protectedText is a text whose glossary terms were replaced with numbered placeholders for translation.
+type protectedText struct {
+	Text        string
+	equivalents []string // the approved equivalent of placeholder [[i+1]]
+}
+
+// protect replaces the terms of text, written in from, that have an approved equivalent in to with placeholders.
+func (s *GlossaryService) protect(ctx context.Context, text, from, to string) protectedText {
+	p := protectedText{Text: text}
+	index := s.current(ctx)
+	matches := index.find(text, from)
+	if len(matches) == 0 {
+		return p
+	}
+	numbers := map[int]int{} // term -> placeholder, so a term repeated in the text keeps one number
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		equivalent := termIn(index.terms[m.term], to)
+		if equivalent == "" {
+			continue
+		}
+		n, ok := numbers[m.term]
+		if !ok {
+			p.equivalents = append(p.equivalents, equivalent)
+			n = len(p.equivalents)
+			numbers[m.term] = n
+		}
+		b.WriteString(text[last:m.start])
+		fmt.Fprintf(&b, "[[%d]]", n)
+		last = m.end
+	}
+	b.WriteString(text[last:])
+	p.Text = b.String()
+	return p
+}
+
+// legend lists what the placeholders stand for, as "[[1]] = equivalent" pairs separated by semicolons.
+func (p protectedText) legend() string {
+	pairs := make([]string, len(p.equivalents))
+	for i, equivalent := range p.equivalents {
+		pairs[i] = fmt.Sprintf("[[%d]] = %s", i+1, equivalent)
+	}
+	return strings.Join(pairs, "; ")
+}
+
+// restore replaces the placeholders in a translation of p.Text with the approved equivalents. It reports
+// false if the translation lost or mangled any placeholder.
+func (p protectedText) restore(translated string) (string, bool) {
+	seen := make([]bool, len(p.equivalents))
+	complete := true
+	restored := glossaryPlaceholderRe.ReplaceAllStringFunc(translated, func(placeholder string) string {
+		n, _ := strconv.Atoi(glossaryPlaceholderRe.FindStringSubmatch(placeholder)[1])
+		if n < 1 || n > len(p.equivalents) {
+			complete = false
+			return placeholder
+		}
+		seen[n-1] = true
+		return p.equivalents[n-1]
+	})
+	for _, ok := range seen {
+		complete = complete && ok
+	}
+	return restored, complete
+}
+
+// glossaryTranslator makes the AI service translate glossary terms to their approved equivalents.
+type glossaryTranslator struct {
+	next     domain.TranslationService
+	glossary *GlossaryService
+}
+
+// NewGlossaryTranslator wraps next, which translates from English, so glossary terms are sent as placeholders
+// and replaced with their approved equivalents afterwards. A translation that loses a placeholder is redone
+// without them.
+func NewGlossaryTranslator(next domain.TranslationService, glossary *GlossaryService) domain.TranslationService {
+	return &glossaryTranslator{next: next, glossary: glossary}
+}
+
+func (t *glossaryTranslator) Translate(ctx context.Context, text, targetLang string) (string, error) {
+	protected := t.glossary.protect(ctx, text, "en", targetLang)
+	if len(protected.equivalents) == 0 {
+		return t.next.Translate(ctx, text, targetLang)
+	}
+	translated, err := t.next.Translate(ctx, protected.Text, targetLang)
+	if err != nil {
+		return "", err
+	}
+	if restored, ok := protected.restore(translated); ok {
+		return restored, nil
+	}
+	log.Printf("Warning: Translation to %s lost glossary placeholders, translating without them", targetLang)
+	return t.next.Translate(ctx, text, targetLang)
+}
+
+func (t *glossaryTranslator) Close() error {
+	return t.next.Close()
+}
+
+// Lookup returns the terms found in a text written in language, in order of first appearance, for tooltips.
+func (s *GlossaryService) Lookup(ctx context.Context, language, text string) []domain.GlossaryDefinition {
+	language = glossaryLanguage(language)
+	index := s.current(ctx)
+	seen := map[int]bool{}
+	definitions := []domain.GlossaryDefinition{}
+	for _, m := range index.find(text, language) {
+		if !seen[m.term] {
+			seen[m.term] = true
+			definitions = append(definitions, glossaryDefinition(index.terms[m.term], language))
+		}
+	}
+	return definitions
+}
+
+// Search returns the terms whose name in language or in English contains query; an empty query lists the
+// first terms alphabetically.
+func (s *GlossaryService) Search(ctx context.Context, language, query string) []domain.GlossaryDefinition {
+	language = glossaryLanguage(language)
+	query = glossaryKey(query)
+	definitions := []domain.GlossaryDefinition{}
+	for _, term := range s.current(ctx).terms {
+		if len(definitions) == maxGlossaryResults {
+			break
+		}
+		if strings.Contains(term.Key, query) || strings.Contains(glossaryKey(termIn(term, language)), query) {
+			definitions = append(definitions, glossaryDefinition(term, language))
+		}
+	}
+	return definitions
+}
+
+func glossaryLanguage(language string) string {
+	if language == "" {
+		return "en"
+	}
+	return strings.ToLower(language)
+}
+
+// glossaryDefinition shows a term in language, falling back to English where it has no translation.
+func glossaryDefinition(term domain.GlossaryTerm, language string) domain.GlossaryDefinition {
+	name := termIn(term, language)
+	if name == "" {
+		name = term.Term
+	}
+	definition := term.Definitions[language]
+	if definition == "" {
+		definition = term.Definitions["en"]
+	}
+	return domain.GlossaryDefinition{
+		ID:           term.ID.Hex(),
+		Term:         name,
+		English:      term.Term,
+		Definition:   definition,
+		Translations: term.Translations,
+	}
+}
+
+// ListTerms returns the stored terms whose name or translations contain query, or all of them.
+func (s *GlossaryService) ListTerms(ctx context.Context, query string) ([]domain.GlossaryTerm, error) {
+	terms, err := s.repo.GetTerms(ctx)
+	if err != nil || query == "" {
+		return terms, err
+	}
+	query = glossaryKey(query)
+	matching := []domain.GlossaryTerm{}
+	for _, term := range terms {
+		match := strings.Contains(term.Key, query)
+		for _, translation := range term.Translations {
+			match = match || strings.Contains(glossaryKey(translation), query)
+		}
+		if match {
+			matching = append(matching, term)
+		}
+	}
+	return matching, nil
+}
+
+// CreateTerm validates and stores a new term.
+func (s *GlossaryService) CreateTerm(ctx context.Context, term domain.GlossaryTerm, createdBy string) (*domain.GlossaryTerm, error) {
+	if err := normalizeGlossaryTerm(&term); err != nil {
+		return nil, err
+	}
+	term.UpdatedBy = createdBy
+	if err := s.repo.CreateTerm(ctx, &term); err != nil {
+		return nil, err
+	}
+	s.invalidate()
+	return &term, nil
+}
+
+// UpdateTerm replaces a term's name, translations and definitions.
+func (s *GlossaryService) UpdateTerm(ctx context.Context, id string, term domain.GlossaryTerm, updatedBy string) (*domain.GlossaryTerm, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, domain.ErrGlossaryTermNotFound
+	}
+	if err := normalizeGlossaryTerm(&term); err != nil {
+		return nil, err
+	}
+	term.ID = objID
+	term.UpdatedBy = updatedBy
+	if err := s.repo.UpdateTerm(ctx, &term); err != nil {
+		return nil, err
+	}
+	s.invalidate()
+	return &term, nil
+}
+
+func (s *GlossaryService) DeleteTerm(ctx context.Context, id string) error {
+	if err := s.repo.DeleteTerm(ctx, id); err != nil {
+		return err
+	}
+	s.invalidate()
+	return nil
+}
+
+// normalizeGlossaryTerm trims a term's texts, drops empty ones and checks the rest.
+func normalizeGlossaryTerm(term *domain.GlossaryTerm) error {
+	term.Term = strings.Join(strings.Fields(term.Term), " ")
+	if term.Term == "" || len(term.Term) > maxGlossaryTermLen {
+		return fmt.Errorf("%w: the term must have between 1 and %d characters", domain.ErrInvalidGlossaryTerm, maxGlossaryTermLen)
+	}
+	term.Key = glossaryKey(term.Term)
+
+	translations := map[string]string{}
+	for language, text := range term.Translations {
+		language = strings.ToLower(strings.TrimSpace(language))
+		text = strings.Join(strings.Fields(text), " ")
+		switch {
+		case text == "":
+			continue
+		case !languageCodeRe.MatchString(language) || language == "en":
+			return fmt.Errorf("%w: %q is not a language code other than en", domain.ErrInvalidGlossaryTerm, language)
+		case len(text) > maxGlossaryTermLen:
+			return fmt.Errorf("%w: translations must have at most %d characters", domain.ErrInvalidGlossaryTerm, maxGlossaryTermLen)
+		}
+		translations[language] = text
+	}
+	term.Translations = translations
+
+	definitions := map[string]string{}
+	for language, text := range term.Definitions {
+		language = strings.ToLower(strings.TrimSpace(language))
+		text = strings.TrimSpace(text)
+		switch {
+		case text == "":
+			continue
+		case !languageCodeRe.MatchString(language):
+			return fmt.Errorf("%w: %q is not a language code", domain.ErrInvalidGlossaryTerm, language)
+		case len(text) > maxGlossaryDefinitionLen:
+			return fmt.Errorf("%w: definitions must have at most %d characters", domain.ErrInvalidGlossaryTerm, maxGlossaryDefinitionLen)
+		}
+		definitions[language] = text
+	}
+	term.Definitions = definitions
+	return nil
+}
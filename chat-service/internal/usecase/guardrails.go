@@ -0,0 +1,295 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/config"
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+)
+
+// GuardrailVerdictsCounter counts queries by guardrail verdict (allowed, emergency, out_of_scope). Registered in main.
+var GuardrailVerdictsCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "chat_guardrail_verdicts_total",
+		Help: "Number of queries by guardrail verdict",
+	},
+	[]string{"verdict"},
+)
+
+// defaultGuardrailLanguage keys the rules that apply to every language. Its texts are in English.
+const defaultGuardrailLanguage = "default"
+
+// guardrailRules are the rules of one language, as read from GUARDRAILS_PATH.
+type guardrailRules struct {
+	Disclaimer string       `json:"disclaimer"` // added to every generated answer
+	Emergency  cannedRules  `json:"emergency"`
+	OutOfScope cannedRules  `json:"out_of_scope"`
+	PII        []redactRule `json:"pii"`
+}
+
+// cannedRules route queries matching any of the patterns to a fixed response.
+type cannedRules struct {
+	Patterns []string `json:"patterns"` // case-insensitive regular expressions
+	Response string   `json:"response"`
+}
+
+type redactRule struct {
+	Pattern     string `json:"pattern"`
+	Replacement string `json:"replacement"`
+}
+
+// defaultGuardrailRules are used unless GUARDRAILS_PATH names a file.
+var defaultGuardrailRules = map[string]guardrailRules{
+	defaultGuardrailLanguage: {
+		Disclaimer: "This is general legal information, not legal advice. For advice on your situation, consult a licensed advocate.",
+		// Only someone in danger now, mostly in the first person; questions about crimes or past harm are
+		// legal questions and get an answer
+		Emergency: cannedRules{
+			Patterns: []string{
+				`\b(want|wants|going|about|planning|trying)\s+to\s+(kill|hurt|harm)\s+(myself|herself|himself)\b`,
+				`\b(i\s+am|i'm|i\s+feel|feeling)\s+suicidal\b`,
+				`\b(thinking\s+(of|about)|considering|planning)\s+(suicide|killing\s+myself)\b`,
+				`\b(i|we)\s+want\s+to\s+die\b`,
+				`\b(i\s+am|i'm|we\s+are|we're)\s+(being|getting)\s+(beaten|attacked|raped|kidnapped|abducted|stabbed|shot)\b`,
+				`\b(is|are|he's|she's|they're)\s+(threatening|going|trying|coming)\s+to\s+kill\s+(me|us)\b`,
+				`\b(i\s+am|i'm|we\s+are|we're)\s+in\s+(immediate\s+)?danger\b`,
+			},
+			Response: "If you or someone else is in immediate danger, please call the police on 991, an ambulance on 907 or the fire brigade on 939 right away. Once you are safe, I can explain the protections and remedies available under Ethiopian law.",
+		},
+		OutOfScope: cannedRules{
+			Patterns: []string{
+				`\b(recipe|recipes|horoscope|weather\s+forecast|lottery\s+numbers)\b`,
+				`\bwrite\s+(me\s+)?(a|an)?\s*(poem|song|story|essay|program|script)\b`,
+				`\b(bitcoin|crypto(currency)?)\s+price\b`,
+			},
+			Response: "I can only help with questions about Ethiopian law. Please ask about your rights, obligations or legal procedures in Ethiopia.",
+		},
+		PII: []redactRule{
+			{Pattern: `[a-z0-9._%+-]+@[a-z0-9.-]+\.[a-z]{2,}`, Replacement: "[EMAIL]"},
+			// Mobile and landline numbers, e.g. +251 911 234 567, 0911234567 or 011-551-2345
+			{Pattern: `(\+251|\b251|\b0)[\s-]?[1-9]\d([\s-]?\d){7}\b`, Replacement: "[PHONE]"},
+			// Fayda national ID numbers (12 or 16 digits), passports, then 10-digit TINs
+			{Pattern: `\b\d{4}[\s-]?\d{4}[\s-]?\d{4}([\s-]?\d{4})?\b`, Replacement: "[ID NUMBER]"},
+			{Pattern: `\be[pq]\d{7}\b`, Replacement: "[ID NUMBER]"},
+			{Pattern: `\b\d{10}\b`, Replacement: "[ID NUMBER]"},
+		},
+	},
+	"am": {
+		Disclaimer: "ይህ አጠቃላይ የሕግ መረጃ እንጂ የሕግ ምክር አይደለም። ስለ ጉዳይዎ ፈቃድ ካለው ጠበቃ ጋር ይማከሩ።",
+		Emergency: cannedRules{
+			Patterns: []string{
+				`ራሴን\s*(ማጥፋት|ላጠፋ|ልገድል|ልጎዳ)`,
+				`እየ(ተ)?ደበደ[በብ]`,
+				`ሊገድሉ?ኝ`,
+				`አደጋ\s*ላይ\s*ነኝ`,
+			},
+			Response: "እርስዎ ወይም ሌላ ሰው አፋጣኝ አደጋ ላይ ከሆናችሁ፣ እባክዎ ወዲያውኑ ለፖሊስ በ991፣ ለአምቡላንስ በ907 ወይም ለእሳት አደጋ መከላከያ በ939 ይደውሉ። ደህንነትዎ ከተጠበቀ በኋላ በኢትዮጵያ ሕግ ያሉትን ጥበቃዎችና መፍትሔዎች ላስረዳዎ እችላለሁ።",
+		},
+		OutOfScope: cannedRules{
+			Patterns: []string{`የምግብ\s*አሰራር`, `የአየር\s*ሁኔታ\s*ትንበያ`},
+			Response: "ልረዳዎ የምችለው ስለ ኢትዮጵያ ሕግ በሚነሱ ጥያቄዎች ብቻ ነው። እባክዎ በኢትዮጵያ ስላሉ መብቶችዎ፣ ግዴታዎችዎ ወይም የሕግ ሥነ ሥርዓቶች ይጠይቁ።",
+		},
+	},
+}
+
+// Guardrails screen queries before they reach the LLM and answers after it. Each language's rules add their
+// patterns to the default rules and override the default texts; languages without their own texts get the
+// English ones translated.
+type Guardrails struct {
+	rules map[string]*guardrailSet
+}
+
+// guardrailSet is the compiled rules of one language, merged with the default rules.
+type guardrailSet struct {
+	disclaimer guardrailText
+	emergency  cannedRule
+	outOfScope cannedRule
+	pii        []redaction
+}
+
+// guardrailText is a configured text; localized is false for English defaults that still need translating.
+type guardrailText struct {
+	text      string
+	localized bool
+}
+
+type cannedRule struct {
+	patterns []*regexp.Regexp
+	response guardrailText
+}
+
+type redaction struct {
+	re          *regexp.Regexp
+	replacement string
+}
+
+// NewGuardrails compiles the rules in cfg.GuardrailsPath, or the built-in rules if it is empty. A language in
+// the file replaces the built-in rules of that language.
+func NewGuardrails(cfg *config.Config) (*Guardrails, error) {
+	rules := make(map[string]guardrailRules, len(defaultGuardrailRules))
+	for language, r := range defaultGuardrailRules {
+		rules[language] = r
+	}
+	if cfg.GuardrailsPath != "" {
+		data, err := os.ReadFile(cfg.GuardrailsPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read guardrail rules: %w", err)
+		}
+		var custom map[string]guardrailRules
+		if err := json.Unmarshal(data, &custom); err != nil {
+			return nil, fmt.Errorf("failed to parse guardrail rules: %w", err)
+		}
+		for language, r := range custom {
+			rules[language] = r
+		}
+	}
+
+	base, err := compileGuardrails(&guardrailSet{}, rules[defaultGuardrailLanguage], false)
+	if err != nil {
+		return nil, fmt.Errorf("default guardrail rules: %w", err)
+	}
+	g := &Guardrails{rules: map[string]*guardrailSet{defaultGuardrailLanguage: base}}
+	for language, r := range rules {
+		if language == defaultGuardrailLanguage {
+			continue
+		}
+		set, err := compileGuardrails(base, r, true)
+		if err != nil {
+			return nil, fmt.Errorf("%s guardrail rules: %w", language, err)
+		}
+		g.rules[language] = set
+	}
+	return g, nil
+}
+
+// compileGuardrails adds r to a copy of base. localized says whether r's texts are in the language they serve.
+func compileGuardrails(base *guardrailSet, r guardrailRules, localized bool) (*guardrailSet, error) {
+	set := &guardrailSet{
+		disclaimer: base.disclaimer,
+		emergency:  cannedRule{patterns: append([]*regexp.Regexp(nil), base.emergency.patterns...), response: base.emergency.response},
+		outOfScope: cannedRule{patterns: append([]*regexp.Regexp(nil), base.outOfScope.patterns...), response: base.outOfScope.response},
+		pii:        append([]redaction(nil), base.pii...),
+	}
+	if r.Disclaimer != "" {
+		set.disclaimer = guardrailText{text: r.Disclaimer, localized: localized}
+	}
+	for _, c := range []struct {
+		rules  cannedRules
+		target *cannedRule
+	}{{r.Emergency, &set.emergency}, {r.OutOfScope, &set.outOfScope}} {
+		for _, pattern := range c.rules.Patterns {
+			re, err := regexp.Compile("(?i)" + pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+			}
+			c.target.patterns = append(c.target.patterns, re)
+		}
+		if c.rules.Response != "" {
+			c.target.response = guardrailText{text: c.rules.Response, localized: localized}
+		}
+	}
+	for _, rule := range r.PII {
+		re, err := regexp.Compile("(?i)" + rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PII pattern %q: %w", rule.Pattern, err)
+		}
+		set.pii = append(set.pii, redaction{re: re, replacement: rule.Replacement})
+	}
+	return set, nil
+}
+
+func (g *Guardrails) rulesFor(language string) *guardrailSet {
+	if set, ok := g.rules[language]; ok {
+		return set
+	}
+	return g.rules[defaultGuardrailLanguage]
+}
+
+// Redact replaces personal data such as phone, ID numbers and email addresses with placeholders.
+func (g *Guardrails) Redact(language, text string) string {
+	for _, r := range g.rulesFor(language).pii {
+		text = r.re.ReplaceAllLiteralString(text, r.replacement)
+	}
+	return text
+}
+
+// Classify reports whether a query is an emergency, out of scope or allowed. Emergencies take precedence.
+func (g *Guardrails) Classify(language, text string) domain.GuardrailVerdict {
+	set := g.rulesFor(language)
+	for _, rule := range []struct {
+		verdict domain.GuardrailVerdict
+		rule    cannedRule
+	}{{domain.VerdictEmergency, set.emergency}, {domain.VerdictOutOfScope, set.outOfScope}} {
+		for _, re := range rule.rule.patterns {
+			if re.MatchString(text) {
+				return rule.verdict
+			}
+		}
+	}
+	return domain.VerdictAllowed
+}
+
+// response returns the canned response for a verdict and whether it is already in the language.
+func (g *Guardrails) response(language string, verdict domain.GuardrailVerdict) (string, bool) {
+	set := g.rulesFor(language)
+	text := set.outOfScope.response
+	if verdict == domain.VerdictEmergency {
+		text = set.emergency.response
+	}
+	return text.text, text.localized || isEnglish(language)
+}
+
+func (g *Guardrails) disclaimer(language string) (string, bool) {
+	text := g.rulesFor(language).disclaimer
+	return text.text, text.localized || isEnglish(language)
+}
+
+func isEnglish(language string) bool {
+	return language == "en" || language == ""
+}
+
+// answerCanned answers an emergency or out-of-scope query with the guardrails' response, skipping retrieval
+// and generation.
+func (s *ChatService) answerCanned(ctx context.Context, req QueryRequest, sessionID string, verdict domain.GuardrailVerdict, resChan chan<- ChatResponseChunk) {
+	text, localized := s.guardrails.response(req.Language, verdict)
+	delivered := text
+	if localized {
+		if !send(ctx, resChan, ChatResponseChunk{Text: text}) {
+			return
+		}
+	} else {
+		answer := s.newAnswerWriter(ctx, req.Language, resChan)
+		if answer.Write(text) != nil || answer.Flush() != nil {
+			return
+		}
+		delivered = answer.Delivered()
+	}
+	s.completeAnswer(ctx, domain.ChatEntry{
+		ID:        req.MessageID,
+		SessionID: sessionID,
+		Content:   text,
+		Guardrail: verdict,
+	}, delivered, resChan)
+}
+
+// localizedDisclaimer returns the disclaimer for answers in language, translating the English one if the
+// language has none of its own.
+func (s *ChatService) localizedDisclaimer(ctx context.Context, language string) string {
+	text, localized := s.guardrails.disclaimer(language)
+	if text == "" || localized {
+		return text
+	}
+	translated, err := s.translator.Translate(ctx, text, language)
+	if err != nil || translated == "" {
+		log.Printf("Warning: Failed to translate disclaimer to %s, sending it in English: %v", language, err)
+		return text
+	}
+	return translated
+}
@@ -0,0 +1,37 @@
+package usecase
+
+import (
+	"testing"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/config"
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+)
+
+func TestGuardrailsClassify(t *testing.T) {
+	cfg, err := config.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	guardrails, err := NewGuardrails(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		query string
+		want  domain.GuardrailVerdict
+	}{
+		{"Is assisting suicide a crime in Ethiopia?", domain.VerdictAllowed},
+		{"I have been beaten by my employer, can I sue?", domain.VerdictAllowed},
+		{"Can a UK court judgment be enforced in Ethiopia?", domain.VerdictAllowed},
+		{"My husband threatened to kill me last year. Can I get a protection order?", domain.VerdictAllowed},
+		{"I am being beaten by my husband right now", domain.VerdictEmergency},
+		{"I want to kill myself", domain.VerdictEmergency},
+		{"I'm suicidal and don't know what to do", domain.VerdictEmergency},
+		{"He's going to kill me, he is outside the door", domain.VerdictEmergency},
+		{"Write me a poem about the constitution", domain.VerdictOutOfScope},
+	} {
+		if got := guardrails.Classify("en", tc.query); got != tc.want {
+			t.Errorf("Classify(%q) = %s, want %s", tc.query, got, tc.want)
+		}
+	}
+}
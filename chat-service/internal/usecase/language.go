@@ -0,0 +1,125 @@
+package usecase
+
+import (
+	"strings"
+	"unicode"
+)
+
+// languageDetection is the language a message is written in.
+type languageDetection struct {
+	Language  string // en, am or om; empty when the text has no letters
+	Latin     bool   // Amharic written in Latin letters
+	Confident bool   // false when nothing in the text points to one language, e.g. "ok" or a number
+}
+
+// Words that are common in one language and rare in the others. Latin-script Amharic has no fixed spelling,
+// so its list holds the usual variants.
+var (
+	englishWords = wordSet("the is are was what how why my of to and a an in can i do does for if with on who when which should have has be it that this about from or not will")
+	oromoWords   = wordSet("fi kan akka irratti keessatti keessa jechuun maal maaliif eenyu seera seerri seeraa mirga mirgi kiyya koo ani isaan inni ishee hin ni dha dhaa yoo garuu waan kana sana naaf akkamitti jira jiru qaba qabu hojii abbaa haadha lafa manni murtii itti irra wajjin ykn moo eessa yeroo barbaada barbaadu")
+	amharicWords = wordSet("nw min mindin mndn lemin lmn lemen endet indet ndet enie anchi esu esua yih yihe hig heg sew mebt mebet yichalal ychilal alebet alebign yelem yellem aydelem aydel kehone bemin silemin ena weyim ahun kezih betam efelgalehu ifelgalehu fetcha fitcha wurs balebete lije yemiyasfelig")
+	// Verb endings of Latin-script Amharic, e.g. yichalal, efelgalehu, yasfeligachewal
+	amharicSuffixes = []string{"alehu", "alew", "achew", "achewal", "alech", "ewal", "echalehu", "ilign", "elign"}
+)
+
+func wordSet(words string) map[string]bool {
+	set := map[string]bool{}
+	for _, w := range strings.Fields(words) {
+		set[w] = true
+	}
+	return set
+}
+
+// detectLanguage tells Ge'ez script, which is taken to be Amharic, from Latin-script English, Afaan Oromo and
+// Amharic. Latin text is scored on common words; Afaan Oromo also scores on the doubled vowels, dh and
+// apostrophes of its spelling.
+func detectLanguage(text string) languageDetection {
+	var ethiopic, latin int
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Ethiopic, r):
+			ethiopic++
+		case unicode.IsLetter(r) && r < unicode.MaxLatin1:
+			latin++
+		}
+	}
+	if ethiopic > 0 && ethiopic >= latin {
+		return languageDetection{Language: "am", Confident: true}
+	}
+	if latin == 0 {
+		return languageDetection{}
+	}
+
+	scores := map[string]int{}
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	for _, word := range words {
+		word = strings.Trim(word, "'")
+		switch {
+		case englishWords[word]:
+			scores["en"] += 2
+		case oromoWords[word]:
+			scores["om"] += 2
+		case amharicWords[word]:
+			scores["am"] += 2
+		}
+		for _, feature := range []string{"aa", "ii", "uu", "dh", "'"} {
+			if len(word) > 3 && strings.Contains(word, feature) {
+				scores["om"]++
+				break
+			}
+		}
+		for _, suffix := range amharicSuffixes {
+			if len(word) > len(suffix)+1 && strings.HasSuffix(word, suffix) {
+				scores["am"]++
+				break
+			}
+		}
+	}
+
+	best, bestScore, second := "en", 0, 0
+	for _, language := range []string{"en", "om", "am"} {
+		switch score := scores[language]; {
+		case score > bestScore:
+			best, bestScore, second = language, score, bestScore
+		case score > second:
+			second = score
+		}
+	}
+	return languageDetection{
+		Language:  best,
+		Latin:     best == "am",
+		Confident: bestScore > 0 && bestScore > second,
+	}
+}
+
+// resolveLanguage picks the language to answer in when the client didn't send one: the message's language
+// if it was detected with confidence, else the session's, else English.
+func resolveLanguage(detected languageDetection, sessionLanguage string) string {
+	switch {
+	case detected.Confident:
+		return detected.Language
+	case sessionLanguage != "":
+		return sessionLanguage
+	}
+	return "en"
+}
+
+// languageName names a language in translation prompts.
+func languageName(language string, latin bool) string {
+	switch language {
+	case "en":
+		return "English"
+	case "am":
+		if latin {
+			return "Amharic written in Latin letters"
+		}
+		return "Amharic"
+	case "om":
+		return "Afaan Oromo"
+	case "ti":
+		return "Tigrinya"
+	}
+	return language
+}
@@ -0,0 +1,417 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/config"
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+)
+
+// Names of the LLM prompts, as used in the admin API and in ChatEntry.PromptVersions.
+const (
+	PromptRefine         = "refine"
+	PromptAnswer         = "answer"
+	PromptNoResult       = "no_result"
+	PromptConverter      = "converter"
+	PromptSummarize      = "summarize"
+	PromptTitle          = "title"
+	PromptQuizGeneration = "quiz_generation"
+)
+
+const (
+	maxPromptVariants    = 5
+	maxPromptRollouts    = 20 // rollouts listed in a prompt's details
+	maxPromptTemplateLen = 32 << 10
+	promptReloadTimeout  = 3 * time.Second
+)
+
+// The data each prompt is rendered with. A template may only use the fields of its prompt's data.
+type (
+	RefinePromptData struct {
+		Query      string
+		History    string // the latest turns of the conversation, empty for a first question
+		MaxQueries int    // how many queries the question may be split into
+	}
+	AnswerPromptData struct {
+		RAGResults  string
+		ChatHistory string
+		Query       string
+		MaxWords    int
+		MaxRefs     int
+	}
+	NoResultPromptData struct {
+		Query string
+	}
+	ConverterPromptData struct {
+		Text     string
+		From     string // name of the text's language, e.g. Afaan Oromo
+		To       string // name of the language to translate to
+		Glossary string // what the placeholders of glossary terms in Text stand for, "[[1]] = translation; ..."
+	}
+	SummarizePromptData struct {
+		Summary  string
+		Turns    string
+		MaxWords int
+	}
+	TitlePromptData struct {
+		Query  string
+		Answer string
+	}
+	QuizPromptData struct {
+		NumQuestions int
+		Passages     string
+	}
+)
+
+// promptVariant is a parsed version serving a share of a prompt's traffic.
+type promptVariant struct {
+	version int
+	weight  int
+	tmpl    *template.Template
+}
+
+type promptDefinition struct {
+	source string      // the default template from the environment, version 0
+	data   interface{} // zero value of the prompt's data, used to validate templates
+	tmpl   *template.Template
+}
+
+// PromptService renders the LLM prompts. Every prompt has a default template from the environment; admins
+// can store new versions in MongoDB, publish one or split the traffic between several to compare their
+// answer feedback, and roll back to the previous rollout.
+type PromptService struct {
+	repo     domain.PromptRepository
+	chatRepo domain.ChatRepository // MongoDB, for the feedback per version
+	refresh  time.Duration
+	prompts  map[string]*promptDefinition
+
+	reloadMu sync.Mutex // held by the request reloading the rollouts
+	mu       sync.RWMutex
+	variants map[string][]promptVariant // active rollouts; prompts without one use their default
+	loadedAt time.Time
+}
+
+// NewPromptService parses the default templates and fails if any of them is invalid.
+func NewPromptService(cfg *config.Config, repo domain.PromptRepository, chatRepo domain.ChatRepository) (*PromptService, error) {
+	s := &PromptService{
+		repo:     repo,
+		chatRepo: chatRepo,
+		refresh:  cfg.PromptRefreshInterval,
+		prompts: map[string]*promptDefinition{
+			PromptRefine:         {source: cfg.LLMPromptRefine, data: RefinePromptData{}},
+			PromptAnswer:         {source: cfg.LLMPromptAnswer, data: AnswerPromptData{}},
+			PromptNoResult:       {source: cfg.LLMPromptNoResult, data: NoResultPromptData{}},
+			PromptConverter:      {source: cfg.LLMPromptConverter, data: ConverterPromptData{}},
+			PromptSummarize:      {source: cfg.LLMPromptSummarize, data: SummarizePromptData{}},
+			PromptTitle:          {source: cfg.LLMPromptTitle, data: TitlePromptData{}},
+			PromptQuizGeneration: {source: cfg.LLMPromptQuizGeneration, data: QuizPromptData{}},
+		},
+	}
+	for name, def := range s.prompts {
+		tmpl, err := parsePrompt(name, def.source, def.data)
+		if err != nil {
+			return nil, fmt.Errorf("default %s prompt: %w", name, err)
+		}
+		def.tmpl = tmpl
+	}
+	return s, nil
+}
+
+// parsePrompt parses a template and checks that it renders with the prompt's data.
+func parsePrompt(name, source string, data interface{}) (*template.Template, error) {
+	if strings.TrimSpace(source) == "" {
+		return nil, fmt.Errorf("%w: the template is empty", domain.ErrInvalidPrompt)
+	}
+	if len(source) > maxPromptTemplateLen {
+		return nil, fmt.Errorf("%w: the template is longer than %d bytes", domain.ErrInvalidPrompt, maxPromptTemplateLen)
+	}
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidPrompt, err)
+	}
+	// Executing catches references to fields the prompt's data doesn't have
+	if err := tmpl.Execute(io.Discard, data); err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidPrompt, err)
+	}
+	return tmpl, nil
+}
+
+// Render renders a prompt and returns the version used. key makes the choice between split variants sticky,
+// e.g. a session ID so a conversation keeps the same variant; an empty key picks one at random. A stored
+// version that fails to render falls back to the default.
+func (s *PromptService) Render(ctx context.Context, name, key string, data interface{}) (string, int, error) {
+	def, ok := s.prompts[name]
+	if !ok {
+		return "", 0, fmt.Errorf("%w: %s", domain.ErrUnknownPrompt, name)
+	}
+	if variant := s.pick(ctx, name, key); variant != nil {
+		var b strings.Builder
+		err := variant.tmpl.Execute(&b, data)
+		if err == nil {
+			return b.String(), variant.version, nil
+		}
+		log.Printf("Warning: Failed to render version %d of the %s prompt, using the default: %v", variant.version, name, err)
+	}
+	var b strings.Builder
+	if err := def.tmpl.Execute(&b, data); err != nil {
+		return "", 0, fmt.Errorf("failed to render %s prompt: %w", name, err)
+	}
+	return b.String(), 0, nil
+}
+
+// pick chooses the variant serving this request, or nil for the default.
+func (s *PromptService) pick(ctx context.Context, name, key string) *promptVariant {
+	s.reloadIfStale(ctx)
+	s.mu.RLock()
+	variants := s.variants[name]
+	s.mu.RUnlock()
+
+	total := 0
+	for _, v := range variants {
+		total += v.weight
+	}
+	if total == 0 {
+		return nil
+	}
+	var n int
+	if key == "" {
+		n = rand.Intn(total)
+	} else {
+		h := fnv.New32a()
+		h.Write([]byte(name + ":" + key))
+		n = int(h.Sum32() % uint32(total))
+	}
+	for i := range variants {
+		if n < variants[i].weight {
+			if variants[i].version == 0 {
+				return nil
+			}
+			return &variants[i]
+		}
+		n -= variants[i].weight
+	}
+	return nil
+}
+
+// reloadIfStale reloads the active rollouts once they are older than the refresh interval. Only one request
+// reloads at a time; the others keep using the rollouts already loaded.
+func (s *PromptService) reloadIfStale(ctx context.Context) {
+	s.mu.RLock()
+	stale := time.Since(s.loadedAt) >= s.refresh
+	s.mu.RUnlock()
+	if !stale || !s.reloadMu.TryLock() {
+		return
+	}
+	defer s.reloadMu.Unlock()
+
+	reloadCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), promptReloadTimeout)
+	defer cancel()
+	variants, err := s.load(reloadCtx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// A failed reload keeps the previous rollouts until the next interval rather than retrying on every request
+	s.loadedAt = time.Now()
+	if err != nil {
+		log.Printf("Warning: Failed to reload prompt rollouts, keeping the current ones: %v", err)
+		return
+	}
+	s.variants = variants
+}
+
+func (s *PromptService) load(ctx context.Context) (map[string][]promptVariant, error) {
+	rollouts, err := s.repo.GetActiveRollouts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string][]promptVariant, len(rollouts))
+	for _, rollout := range rollouts {
+		def, ok := s.prompts[rollout.Name]
+		if !ok {
+			continue
+		}
+		var stored []int
+		for _, v := range rollout.Variants {
+			if v.Version != 0 {
+				stored = append(stored, v.Version)
+			}
+		}
+		templates := map[int]*template.Template{}
+		if len(stored) > 0 {
+			versions, err := s.repo.GetVersions(ctx, rollout.Name, stored)
+			if err != nil {
+				return nil, err
+			}
+			for _, version := range versions {
+				tmpl, err := parsePrompt(rollout.Name, version.Template, def.data)
+				if err != nil {
+					log.Printf("Warning: Skipping version %d of the %s prompt: %v", version.Version, rollout.Name, err)
+					continue
+				}
+				templates[version.Version] = tmpl
+			}
+		}
+		var variants []promptVariant
+		for _, v := range rollout.Variants {
+			tmpl := def.tmpl
+			if v.Version != 0 {
+				if tmpl = templates[v.Version]; tmpl == nil {
+					continue
+				}
+			}
+			variants = append(variants, promptVariant{version: v.Version, weight: v.Weight, tmpl: tmpl})
+		}
+		result[rollout.Name] = variants
+	}
+	return result, nil
+}
+
+// invalidate makes the next render reload the rollouts. Other replicas pick changes up within the refresh interval.
+func (s *PromptService) invalidate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadedAt = time.Time{}
+}
+
+// ListPrompts returns every prompt with the variants serving it.
+func (s *PromptService) ListPrompts(ctx context.Context) ([]domain.PromptStatus, error) {
+	rollouts, err := s.repo.GetActiveRollouts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	active := make(map[string][]domain.PromptVariant, len(rollouts))
+	for _, rollout := range rollouts {
+		active[rollout.Name] = rollout.Variants
+	}
+	names := make([]string, 0, len(s.prompts))
+	for name := range s.prompts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	statuses := make([]domain.PromptStatus, 0, len(names))
+	for _, name := range names {
+		statuses = append(statuses, s.status(name, active[name]))
+	}
+	return statuses, nil
+}
+
+// GetPrompt returns a prompt with its versions and latest rollouts.
+func (s *PromptService) GetPrompt(ctx context.Context, name string) (*domain.PromptDetails, error) {
+	if _, ok := s.prompts[name]; !ok {
+		return nil, domain.ErrUnknownPrompt
+	}
+	versions, err := s.repo.GetVersions(ctx, name, nil)
+	if err != nil {
+		return nil, err
+	}
+	rollouts, err := s.repo.GetRollouts(ctx, name, maxPromptRollouts)
+	if err != nil {
+		return nil, err
+	}
+	var variants []domain.PromptVariant
+	for _, rollout := range rollouts {
+		if rollout.RolledBackAt == nil {
+			variants = rollout.Variants
+			break
+		}
+	}
+	return &domain.PromptDetails{PromptStatus: s.status(name, variants), Versions: versions, Rollouts: rollouts}, nil
+}
+
+func (s *PromptService) status(name string, variants []domain.PromptVariant) domain.PromptStatus {
+	if len(variants) == 0 {
+		variants = []domain.PromptVariant{{Version: 0, Weight: 100}}
+	}
+	return domain.PromptStatus{Name: name, Default: s.prompts[name].source, Variants: variants}
+}
+
+// CreateVersion validates and stores a new version of a prompt. It serves no traffic until it is published.
+func (s *PromptService) CreateVersion(ctx context.Context, name, source, note, createdBy string) (*domain.PromptVersion, error) {
+	def, ok := s.prompts[name]
+	if !ok {
+		return nil, domain.ErrUnknownPrompt
+	}
+	if _, err := parsePrompt(name, source, def.data); err != nil {
+		return nil, err
+	}
+	version := &domain.PromptVersion{Name: name, Template: source, Note: strings.TrimSpace(note), CreatedBy: createdBy}
+	if err := s.repo.CreateVersion(ctx, version); err != nil {
+		return nil, err
+	}
+	return version, nil
+}
+
+// Publish sends all of a prompt's traffic to one version; version 0 goes back to the default.
+func (s *PromptService) Publish(ctx context.Context, name string, version int, publishedBy string) (*domain.PromptRollout, error) {
+	return s.SplitTraffic(ctx, name, []domain.PromptVariant{{Version: version, Weight: 100}}, publishedBy)
+}
+
+// SplitTraffic shares a prompt's traffic between versions in proportion to their weights.
+func (s *PromptService) SplitTraffic(ctx context.Context, name string, variants []domain.PromptVariant, createdBy string) (*domain.PromptRollout, error) {
+	if _, ok := s.prompts[name]; !ok {
+		return nil, domain.ErrUnknownPrompt
+	}
+	if len(variants) == 0 || len(variants) > maxPromptVariants {
+		return nil, fmt.Errorf("%w: give between 1 and %d variants", domain.ErrInvalidPrompt, maxPromptVariants)
+	}
+	seen := map[int]bool{}
+	var stored []int
+	for _, v := range variants {
+		if v.Weight < 1 || v.Weight > 100 {
+			return nil, fmt.Errorf("%w: weights must be between 1 and 100", domain.ErrInvalidPrompt)
+		}
+		if v.Version < 0 || seen[v.Version] {
+			return nil, fmt.Errorf("%w: each variant must name a different version", domain.ErrInvalidPrompt)
+		}
+		seen[v.Version] = true
+		if v.Version != 0 {
+			stored = append(stored, v.Version)
+		}
+	}
+	if len(stored) > 0 {
+		found, err := s.repo.GetVersions(ctx, name, stored)
+		if err != nil {
+			return nil, err
+		}
+		if len(found) != len(stored) {
+			return nil, domain.ErrPromptVersionNotFound
+		}
+	}
+
+	rollout := &domain.PromptRollout{Name: name, Variants: variants, CreatedBy: createdBy}
+	if err := s.repo.SaveRollout(ctx, rollout); err != nil {
+		return nil, err
+	}
+	s.invalidate()
+	return rollout, nil
+}
+
+// RollBack retires a prompt's active rollout, restoring the one before it or else the default.
+func (s *PromptService) RollBack(ctx context.Context, name, rolledBackBy string) (*domain.PromptDetails, error) {
+	if _, ok := s.prompts[name]; !ok {
+		return nil, domain.ErrUnknownPrompt
+	}
+	if err := s.repo.RollBack(ctx, name, rolledBackBy); err != nil {
+		return nil, err
+	}
+	s.invalidate()
+	return s.GetPrompt(ctx, name)
+}
+
+// GetFeedbackStats compares the feedback on answers generated with each version of a prompt.
+func (s *PromptService) GetFeedbackStats(ctx context.Context, name string, from, to time.Time) ([]domain.PromptVersionStats, error) {
+	if _, ok := s.prompts[name]; !ok {
+		return nil, domain.ErrUnknownPrompt
+	}
+	return s.chatRepo.GetPromptFeedbackStats(ctx, name, from, to)
+}
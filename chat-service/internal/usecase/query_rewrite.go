@@ -0,0 +1,180 @@
+package usecase
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+)
+
+// maxRewriteTurnChars bounds each earlier message shown to the refine prompt; the gist of a long answer is
+// enough to resolve a follow-up.
+const maxRewriteTurnChars = 600
+
+// Numbering and bullets the LLM may put in front of split queries, e.g. "1. " or "- "
+var queryListMarkerRe = regexp.MustCompile(`^\s*(?:[-*•]|\d+[.)])\s*`)
+
+// renderFunc renders a prompt for the current request, recording the version used.
+type renderFunc func(name string, data interface{}) (string, error)
+
+// rewriteQuery turns the latest question into standalone retrieval queries, using the last QueryRewriteTurns
+// turns to resolve follow-ups such as "what about for employers?". A question covering several issues may be
+// split into up to MaxSubQueries queries. If the LLM fails, the query is used as it is.
+func (s *ChatService) rewriteQuery(ctx context.Context, render renderFunc, query string, history []domain.ChatEntry, currentEntryID string) []string {
+	maxQueries := s.cfg.MaxSubQueries
+	if maxQueries < 1 {
+		maxQueries = 1
+	}
+	rewritePrompt, err := render(PromptRefine, RefinePromptData{
+		Query:      query,
+		History:    rewriteHistory(history, currentEntryID, s.cfg.QueryRewriteTurns),
+		MaxQueries: maxQueries,
+	})
+	var output string
+	if err == nil {
+		output, err = s.llmService.Generate(ctx, rewritePrompt, nil) // The history is in the prompt
+	}
+	log.Printf("\n### Refinement Service\nPrompt: %s\nResult: %s\nError: %v\n", rewritePrompt, output, err)
+	if err != nil {
+		log.Printf("Warning: Failed to refine query, falling back to original: %v", err)
+		return []string{query}
+	}
+	if queries := parseRewrittenQueries(output, maxQueries); len(queries) > 0 {
+		return queries
+	}
+	return []string{query}
+}
+
+// rewriteHistory formats the last turns before the current query, shortening long messages.
+func rewriteHistory(history []domain.ChatEntry, currentEntryID string, turns int) string {
+	if turns <= 0 {
+		return ""
+	}
+	var entries []domain.ChatEntry
+	for _, entry := range history {
+		if entry.ID != currentEntryID && (entry.Type == domain.MessageTypeUser || entry.Type == domain.MessageTypeLLM) {
+			entries = append(entries, entry)
+		}
+	}
+	if len(entries) > 2*turns {
+		entries = entries[len(entries)-2*turns:]
+	}
+	for i, entry := range entries {
+		if content := []rune(entry.Content); len(content) > maxRewriteTurnChars {
+			entries[i].Content = string(content[:maxRewriteTurnChars]) + "..."
+		}
+	}
+	return formatTurns(entries)
+}
+
+// parseRewrittenQueries reads one query per line, dropping list markers and repeats. With splitting disabled
+// the whole output is one query, as some models wrap long lines.
+func parseRewrittenQueries(output string, maxQueries int) []string {
+	if maxQueries == 1 {
+		if query := strings.Join(strings.Fields(output), " "); query != "" {
+			return []string{query}
+		}
+		return nil
+	}
+	seen := map[string]bool{}
+	var queries []string
+	for _, line := range strings.Split(output, "\n") {
+		query := strings.TrimSpace(queryListMarkerRe.ReplaceAllString(line, ""))
+		key := strings.ToLower(query)
+		if query == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		queries = append(queries, query)
+		if len(queries) == maxQueries {
+			break
+		}
+	}
+	return queries
+}
+
+// retrieveAll retrieves the sources for each query. A single query streams its sources as usual; several
+// are retrieved in parallel and merged, and the merged sources are sent once. Sub-queries that fail are
+// skipped unless all of them do.
+func (s *ChatService) retrieveAll(ctx context.Context, queries []string, maxRefs int, resChan chan<- ChatResponseChunk) (*domain.RAGResult, error) {
+	if len(queries) == 1 {
+		return s.retrieve(ctx, queries[0], maxRefs, resChan)
+	}
+	results := make([]*domain.RAGResult, len(queries))
+	errs := make([]error, len(queries))
+	var wg sync.WaitGroup
+	for i, query := range queries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = s.ragService.Retrieve(ctx, query, maxRefs)
+		}()
+	}
+	wg.Wait()
+
+	var succeeded []*domain.RAGResult
+	for i, err := range errs {
+		if err != nil {
+			log.Printf("Warning: Retrieval failed for sub-query %q: %v", queries[i], err)
+			continue
+		}
+		succeeded = append(succeeded, results[i])
+	}
+	if len(succeeded) == 0 {
+		return nil, errs[0]
+	}
+	merged := mergeRAGResults(succeeded)
+	if sources := s.filterSources(merged.Results, maxRefs); len(sources) > 0 {
+		if !send(ctx, resChan, ChatResponseChunk{RetrievedSources: sources}) {
+			return nil, ctx.Err()
+		}
+	}
+	return merged, nil
+}
+
+// mergeRAGResults interleaves the results of several queries by rank, so each query's best sources come
+// before any query's weaker ones, and drops repeated articles. Articles are told apart by ArticleNumber
+// within their source document, since every law has its own Article 1.
+func mergeRAGResults(results []*domain.RAGResult) *domain.RAGResult {
+	merged := &domain.RAGResult{}
+	seen := map[string]bool{}
+	for rank := 0; ; rank++ {
+		more := false
+		for _, result := range results {
+			if rank >= len(result.Results) {
+				continue
+			}
+			more = true
+			source := result.Results[rank]
+			key := source.Source + "\x00" + source.ArticleNumber
+			if source.ArticleNumber == "" {
+				key += "\x00" + source.Content
+			}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			merged.Results = append(merged.Results, source)
+		}
+		if !more {
+			break
+		}
+	}
+	references := map[string]bool{}
+	for _, result := range results {
+		if merged.Message == "" {
+			merged.Message = result.Message
+		}
+		merged.Degraded = merged.Degraded || result.Degraded
+		for _, reference := range result.References {
+			if !references[reference] {
+				references[reference] = true
+				merged.References = append(merged.References, reference)
+			}
+		}
+	}
+	return merged
+}
@@ -0,0 +1,226 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultGeneratedQuestions = 5
+	maxGeneratedQuestions     = 20
+)
+
+// generatedQuizOptions are the option keys every generated question must use, in order.
+var generatedQuizOptions = []string{"A", "B", "C", "D"}
+
+var articleNumberRe = regexp.MustCompile(`\d+`)
+
+// generatedQuestion is the strict JSON schema the LLM is asked to answer in.
+type generatedQuestion struct {
+	Text          string            `json:"text"`
+	Options       map[string]string `json:"options"`
+	CorrectOption string            `json:"correct_option"`
+	ArticleNumber string            `json:"article_number"`
+}
+
+func (u *quizUseCase) GenerateQuiz(ctx context.Context, req domain.GenerateQuizRequest) (*domain.Quiz, error) {
+	catObjID, err := primitive.ObjectIDFromHex(req.CategoryID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid category ID", domain.ErrInvalidQuizRequest)
+	}
+	if strings.TrimSpace(req.Source) == "" {
+		return nil, fmt.Errorf("%w: law source cannot be empty", domain.ErrInvalidQuizRequest)
+	}
+	if req.ArticleFrom < 0 || req.ArticleTo < 0 || (req.ArticleTo > 0 && req.ArticleFrom > req.ArticleTo) {
+		return nil, fmt.Errorf("%w: invalid article range", domain.ErrInvalidQuizRequest)
+	}
+	if req.NumQuestions <= 0 {
+		req.NumQuestions = defaultGeneratedQuestions
+	}
+	if req.NumQuestions > maxGeneratedQuestions {
+		return nil, fmt.Errorf("%w: at most %d questions can be generated at once", domain.ErrInvalidQuizRequest, maxGeneratedQuestions)
+	}
+	if _, err := u.quizRepo.GetCategoryByID(ctx, catObjID); err != nil {
+		return nil, err
+	}
+
+	passages, err := u.retrieveQuizPassages(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	prompt, _, err := u.prompts.Render(ctx, PromptQuizGeneration, "", QuizPromptData{
+		NumQuestions: req.NumQuestions,
+		Passages:     formatQuizPassages(passages),
+	})
+	if err != nil {
+		return nil, err
+	}
+	response, err := u.llmService.Generate(ctx, prompt, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate questions: %w", err)
+	}
+
+	questions, err := parseGeneratedQuestions(response, req.Source)
+	if err != nil {
+		return nil, err
+	}
+	if len(questions) > req.NumQuestions {
+		questions = questions[:req.NumQuestions]
+	}
+
+	quiz := &domain.Quiz{
+		CategoryID:  catObjID,
+		Name:        req.Name,
+		Description: "Generated from " + describeQuizSource(req),
+		Status:      domain.QuizStatusDraft,
+		Questions:   questions,
+	}
+	if quiz.Name == "" {
+		quiz.Name = describeQuizSource(req)
+	}
+	if err := u.quizRepo.CreateQuiz(ctx, quiz); err != nil {
+		return nil, err
+	}
+	return quiz, nil
+}
+
+// retrieveQuizPassages fetches passages for the source and keeps only those from that source and article range.
+func (u *quizUseCase) retrieveQuizPassages(ctx context.Context, req domain.GenerateQuizRequest) ([]domain.RAGSource, error) {
+	k := req.NumQuestions * 3
+	if k < 10 {
+		k = 10
+	}
+	result, err := u.ragService.Retrieve(ctx, describeQuizSource(req), k)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve passages: %w", err)
+	}
+
+	var passages []domain.RAGSource
+	for _, passage := range result.Results {
+		if !strings.Contains(strings.ToLower(passage.Source), strings.ToLower(strings.TrimSpace(req.Source))) {
+			continue
+		}
+		if req.ArticleFrom > 0 || req.ArticleTo > 0 {
+			article, err := strconv.Atoi(articleNumberRe.FindString(passage.ArticleNumber))
+			if err != nil || article < req.ArticleFrom || (req.ArticleTo > 0 && article > req.ArticleTo) {
+				continue
+			}
+		}
+		passages = append(passages, passage)
+	}
+	if len(passages) == 0 {
+		return nil, domain.ErrNoQuizPassages
+	}
+	return passages, nil
+}
+
+func describeQuizSource(req domain.GenerateQuizRequest) string {
+	source := strings.TrimSpace(req.Source)
+	switch {
+	case req.ArticleFrom > 0 && req.ArticleTo > 0:
+		return fmt.Sprintf("%s, Articles %d-%d", source, req.ArticleFrom, req.ArticleTo)
+	case req.ArticleFrom > 0:
+		return fmt.Sprintf("%s, Articles %d onwards", source, req.ArticleFrom)
+	case req.ArticleTo > 0:
+		return fmt.Sprintf("%s, Articles up to %d", source, req.ArticleTo)
+	}
+	return source
+}
+
+func formatQuizPassages(passages []domain.RAGSource) string {
+	var b strings.Builder
+	for _, p := range passages {
+		fmt.Fprintf(&b, "[%s, Article %s]\n%s\n\n", p.Source, p.ArticleNumber, p.Content)
+	}
+	return b.String()
+}
+
+// parseGeneratedQuestions decodes the LLM's JSON and keeps only valid, non-duplicate questions.
+func parseGeneratedQuestions(response, source string) ([]domain.Question, error) {
+	// Models sometimes wrap JSON in code fences or prose despite the instructions
+	start, end := strings.Index(response, "{"), strings.LastIndex(response, "}")
+	if start < 0 || end < start {
+		return nil, errors.New("generated questions are not valid JSON")
+	}
+	var parsed struct {
+		Questions []generatedQuestion `json:"questions"`
+	}
+	if err := json.Unmarshal([]byte(response[start:end+1]), &parsed); err != nil {
+		return nil, fmt.Errorf("generated questions are not valid JSON: %w", err)
+	}
+
+	now := time.Now()
+	seen := make(map[string]bool)
+	var questions []domain.Question
+	for i, gq := range parsed.Questions {
+		if err := validateGeneratedQuestion(gq); err != nil {
+			log.Printf("Warning: Dropping generated question %d: %v", i+1, err)
+			continue
+		}
+		key := normalizeCacheQuery(gq.Text)
+		if seen[key] {
+			log.Printf("Warning: Dropping generated question %d: duplicate question", i+1)
+			continue
+		}
+		seen[key] = true
+
+		options := make(map[string]string, len(generatedQuizOptions))
+		for _, key := range generatedQuizOptions {
+			options[key] = strings.TrimSpace(gq.Options[key])
+		}
+		question := domain.Question{
+			ID:            primitive.NewObjectID(),
+			Text:          strings.TrimSpace(gq.Text),
+			Options:       options,
+			CorrectOption: strings.ToUpper(strings.TrimSpace(gq.CorrectOption)),
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+		if gq.ArticleNumber != "" {
+			question.Reference = fmt.Sprintf("%s, Article %s", source, gq.ArticleNumber)
+		}
+		questions = append(questions, question)
+	}
+	if len(questions) == 0 {
+		return nil, errors.New("no valid questions were generated")
+	}
+	return questions, nil
+}
+
+// validateGeneratedQuestion requires options A-D only, all distinct, with exactly one of them marked correct.
+func validateGeneratedQuestion(gq generatedQuestion) error {
+	if strings.TrimSpace(gq.Text) == "" {
+		return errors.New("empty question text")
+	}
+	if len(gq.Options) != len(generatedQuizOptions) {
+		return fmt.Errorf("expected %d options, got %d", len(generatedQuizOptions), len(gq.Options))
+	}
+	seen := make(map[string]bool)
+	for _, key := range generatedQuizOptions {
+		text, ok := gq.Options[key]
+		if !ok || strings.TrimSpace(text) == "" {
+			return fmt.Errorf("missing option %s", key)
+		}
+		normalized := normalizeCacheQuery(text)
+		if seen[normalized] {
+			return errors.New("duplicate options")
+		}
+		seen[normalized] = true
+	}
+	if _, ok := gq.Options[strings.ToUpper(strings.TrimSpace(gq.CorrectOption))]; !ok {
+		return fmt.Errorf("correct option %q is not one of A-D", gq.CorrectOption)
+	}
+	return nil
+}
@@ -3,7 +3,9 @@ package usecase
 import (
 	"context"
 	"errors"
+	"fmt"
 	"math"
+	"time"
 
 	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
 
@@ -11,11 +13,14 @@ import (
 )
 
 type quizUseCase struct {
-	quizRepo domain.IQuizRepository
+	quizRepo   domain.IQuizRepository
+	ragService domain.RAGService // used only to generate quizzes
+	llmService domain.LLMService
+	prompts    *PromptService
 }
 
-func NewQuizUseCase(quizRepo domain.IQuizRepository) domain.IQuizUseCase {
-	return &quizUseCase{quizRepo: quizRepo}
+func NewQuizUseCase(quizRepo domain.IQuizRepository, ragService domain.RAGService, llmService domain.LLMService, prompts *PromptService) domain.IQuizUseCase {
+	return &quizUseCase{quizRepo: quizRepo, ragService: ragService, llmService: llmService, prompts: prompts}
 }
 
 // --- Category Methods ---
@@ -166,6 +171,65 @@ func (u *quizUseCase) UpdateQuiz(ctx context.Context, id, name, description stri
 	return quiz, nil
 }
 
+func (u *quizUseCase) UpdateQuizSettings(ctx context.Context, id string, passThreshold float64) (*domain.Quiz, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid quiz ID", domain.ErrInvalidQuizSettings)
+	}
+	if passThreshold < 0 || passThreshold > 100 {
+		return nil, fmt.Errorf("%w: pass threshold must be a percentage between 0 and 100", domain.ErrInvalidQuizSettings)
+	}
+	quiz, err := u.quizRepo.GetQuizByID(ctx, objID)
+	if err != nil {
+		return nil, err
+	}
+	quiz.PassThreshold = passThreshold
+	err = u.quizRepo.UpdateQuiz(ctx, quiz)
+	if err != nil {
+		return nil, err
+	}
+	return quiz, nil
+}
+
+func (u *quizUseCase) ListDraftQuizzes(ctx context.Context, page, limit int64) (*domain.PaginatedQuizzes, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+	quizzes, total, err := u.quizRepo.GetDraftQuizzes(ctx, page, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.PaginatedQuizzes{
+		Items:       quizzes,
+		TotalItems:  total,
+		TotalPages:  int64(math.Ceil(float64(total) / float64(limit))),
+		CurrentPage: page,
+		PageSize:    limit,
+	}, nil
+}
+
+func (u *quizUseCase) PublishQuiz(ctx context.Context, id string) (*domain.Quiz, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid quiz ID")
+	}
+	quiz, err := u.quizRepo.GetQuizByID(ctx, objID)
+	if err != nil {
+		return nil, err
+	}
+	if len(quiz.Questions) == 0 {
+		return nil, errors.New("cannot publish a quiz without questions")
+	}
+	if err := u.quizRepo.PublishQuiz(ctx, objID); err != nil {
+		return nil, err
+	}
+	return u.quizRepo.GetQuizByID(ctx, objID)
+}
+
 func (u *quizUseCase) DeleteQuiz(ctx context.Context, id string) error {
 	objID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
@@ -250,3 +314,154 @@ func (u *quizUseCase) DeleteQuestion(ctx context.Context, quizID, questionID str
 	}
 	return u.quizRepo.DeleteQuestionFromQuiz(ctx, quizObjID, questionObjID)
 }
+
+// --- Attempt Methods ---
+
+func (u *quizUseCase) SubmitQuiz(ctx context.Context, userID, quizID string, answers map[string]string, startedAt time.Time) (*domain.QuizAttempt, error) {
+	quizObjID, err := primitive.ObjectIDFromHex(quizID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid quiz ID", domain.ErrInvalidQuizRequest)
+	}
+	quiz, err := u.quizRepo.GetQuizByID(ctx, quizObjID)
+	if err != nil {
+		return nil, err
+	}
+	if quiz.IsDraft() {
+		return nil, domain.ErrQuizNotFound
+	}
+	if len(quiz.Questions) == 0 {
+		return nil, fmt.Errorf("%w: quiz has no questions", domain.ErrInvalidQuizRequest)
+	}
+
+	attempt := gradeQuiz(quiz, answers)
+	attempt.UserID = userID
+	attempt.SubmittedAt = time.Now()
+	if !startedAt.IsZero() && startedAt.Before(attempt.SubmittedAt) {
+		attempt.DurationSeconds = int64(attempt.SubmittedAt.Sub(startedAt).Seconds())
+	}
+
+	if userID == "" {
+		return attempt, nil
+	}
+	if err := u.quizRepo.CreateAttempt(ctx, attempt); err != nil {
+		return nil, err
+	}
+	return attempt, nil
+}
+
+// gradeQuiz scores answers against the quiz's current questions. Unanswered questions count as wrong
+// and don't get their correct option back.
+func gradeQuiz(quiz *domain.Quiz, answers map[string]string) *domain.QuizAttempt {
+	attempt := &domain.QuizAttempt{
+		QuizID:         quiz.ID,
+		QuizName:       quiz.Name,
+		CategoryID:     quiz.CategoryID,
+		TotalQuestions: len(quiz.Questions),
+		PassThreshold:  quiz.EffectivePassThreshold(),
+		Results:        make([]domain.QuestionResult, 0, len(quiz.Questions)),
+	}
+	for _, question := range quiz.Questions {
+		selected := answers[question.ID.Hex()]
+		result := domain.QuestionResult{
+			QuestionID:     question.ID,
+			SelectedOption: selected,
+			IsCorrect:      selected != "" && selected == question.CorrectOption,
+		}
+		if selected != "" {
+			result.CorrectOption = question.CorrectOption
+		}
+		if result.IsCorrect {
+			attempt.Score++
+		}
+		attempt.Results = append(attempt.Results, result)
+	}
+	attempt.Percentage = math.Round(float64(attempt.Score)/float64(attempt.TotalQuestions)*10000) / 100
+	attempt.Passed = attempt.Percentage >= attempt.PassThreshold
+	return attempt
+}
+
+func (u *quizUseCase) ListAttempts(ctx context.Context, userID, quizID string, page, limit int64) (*domain.PaginatedQuizAttempts, error) {
+	if userID == "" {
+		return nil, fmt.Errorf("%w: user ID is required", domain.ErrInvalidQuizRequest)
+	}
+	quizObjID := primitive.NilObjectID
+	if quizID != "" {
+		var err error
+		quizObjID, err = primitive.ObjectIDFromHex(quizID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid quiz ID", domain.ErrInvalidQuizRequest)
+		}
+	}
+	if page <= 0 {
+		page = 1
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+	attempts, total, err := u.quizRepo.GetAttemptsByUser(ctx, userID, quizObjID, page, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.PaginatedQuizAttempts{
+		Items:       attempts,
+		TotalItems:  total,
+		TotalPages:  int64(math.Ceil(float64(total) / float64(limit))),
+		CurrentPage: page,
+		PageSize:    limit,
+	}, nil
+}
+
+// GetProgress groups the user's per-quiz progress by category, for one category or all attempted ones.
+func (u *quizUseCase) GetProgress(ctx context.Context, userID, categoryID string) ([]*domain.CategoryProgress, error) {
+	if userID == "" {
+		return nil, fmt.Errorf("%w: user ID is required", domain.ErrInvalidQuizRequest)
+	}
+	catObjID := primitive.NilObjectID
+	if categoryID != "" {
+		var err error
+		catObjID, err = primitive.ObjectIDFromHex(categoryID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid category ID", domain.ErrInvalidQuizRequest)
+		}
+	}
+	quizProgress, err := u.quizRepo.GetQuizProgressByUser(ctx, userID, catObjID)
+	if err != nil {
+		return nil, err
+	}
+
+	var categories []*domain.CategoryProgress
+	byCategory := make(map[primitive.ObjectID]*domain.CategoryProgress)
+	if !catObjID.IsZero() {
+		// Report the requested category even if the user has not attempted any of its quizzes yet
+		byCategory[catObjID] = &domain.CategoryProgress{CategoryID: catObjID, Quizzes: []*domain.QuizProgress{}}
+		categories = append(categories, byCategory[catObjID])
+	}
+	for _, qp := range quizProgress {
+		cp, ok := byCategory[qp.CategoryID]
+		if !ok {
+			cp = &domain.CategoryProgress{CategoryID: qp.CategoryID}
+			byCategory[qp.CategoryID] = cp
+			categories = append(categories, cp)
+		}
+		cp.Quizzes = append(cp.Quizzes, qp)
+		cp.AttemptedQuizzes++
+		if qp.Passed {
+			cp.PassedQuizzes++
+		}
+		cp.AverageBestPercentage += qp.BestPercentage
+	}
+
+	for _, cp := range categories {
+		if cp.AttemptedQuizzes > 0 {
+			cp.AverageBestPercentage = math.Round(cp.AverageBestPercentage/float64(cp.AttemptedQuizzes)*100) / 100
+		}
+		category, err := u.quizRepo.GetCategoryByID(ctx, cp.CategoryID)
+		if err != nil {
+			continue // the category was deleted; keep the user's history anyway
+		}
+		cp.CategoryName = category.Name
+		cp.TotalQuizzes = category.TotalQuizzes
+	}
+	return categories, nil
+}
@@ -0,0 +1,69 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestGradeQuizHidesAnswersForUnansweredQuestions(t *testing.T) {
+	quiz := &domain.Quiz{
+		ID: primitive.NewObjectID(),
+		Questions: []domain.Question{
+			{ID: primitive.NewObjectID(), CorrectOption: "A"},
+			{ID: primitive.NewObjectID(), CorrectOption: "B"},
+		},
+	}
+
+	attempt := gradeQuiz(quiz, map[string]string{})
+	if attempt.Score != 0 || attempt.Passed {
+		t.Errorf("empty submission scored %d (passed=%t), want 0 and a fail", attempt.Score, attempt.Passed)
+	}
+	for _, result := range attempt.Results {
+		if result.CorrectOption != "" {
+			t.Errorf("unanswered question %s revealed correct option %q", result.QuestionID.Hex(), result.CorrectOption)
+		}
+	}
+
+	attempt = gradeQuiz(quiz, map[string]string{quiz.Questions[0].ID.Hex(): "C"})
+	if got := attempt.Results[0].CorrectOption; got != "A" {
+		t.Errorf("answered question correct option = %q, want %q", got, "A")
+	}
+	if got := attempt.Results[1].CorrectOption; got != "" {
+		t.Errorf("unanswered question revealed correct option %q", got)
+	}
+}
+
+func TestGenerateQuizErrorsSeparateCallerMistakesFromFailures(t *testing.T) {
+	u := &quizUseCase{ragService: &fakeRAG{result: domain.RAGResult{Results: testSources}}}
+	categoryID := primitive.NewObjectID().Hex()
+
+	for _, tc := range []struct {
+		name string
+		req  domain.GenerateQuizRequest
+	}{
+		{"bad category ID", domain.GenerateQuizRequest{CategoryID: "nope", Source: "Labour Proclamation"}},
+		{"empty source", domain.GenerateQuizRequest{CategoryID: categoryID, Source: " "}},
+		{"reversed article range", domain.GenerateQuizRequest{CategoryID: categoryID, Source: "Labour Proclamation", ArticleFrom: 9, ArticleTo: 3}},
+		{"too many questions", domain.GenerateQuizRequest{CategoryID: categoryID, Source: "Labour Proclamation", NumQuestions: maxGeneratedQuestions + 1}},
+	} {
+		if _, err := u.GenerateQuiz(context.Background(), tc.req); !errors.Is(err, domain.ErrInvalidQuizRequest) {
+			t.Errorf("%s: err = %v, want ErrInvalidQuizRequest", tc.name, err)
+		}
+	}
+
+	_, err := u.retrieveQuizPassages(context.Background(), domain.GenerateQuizRequest{Source: "Family Code", NumQuestions: 5})
+	if !errors.Is(err, domain.ErrNoQuizPassages) {
+		t.Errorf("unknown source: err = %v, want ErrNoQuizPassages", err)
+	}
+
+	u.ragService = &fakeRAG{err: domain.ErrRAGUnavailable}
+	_, err = u.retrieveQuizPassages(context.Background(), domain.GenerateQuizRequest{Source: "Labour Proclamation", NumQuestions: 5})
+	if errors.Is(err, domain.ErrInvalidQuizRequest) || errors.Is(err, domain.ErrNoQuizPassages) {
+		t.Errorf("retrieval failure: err = %v, want a server error", err)
+	}
+}
@@ -0,0 +1,122 @@
+package usecase
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+)
+
+// quotaSubject identifies whose quota a request is metered against: the account,
+// or the client IP for visitors who have none.
+func quotaSubject(userID, clientIP string) string {
+	if userID != "" {
+		return "user:" + userID
+	}
+	return "ip:" + clientIP
+}
+
+// endOfDay and endOfMonth return when the current quota periods reset (UTC).
+func endOfDay(now time.Time) time.Time {
+	y, m, d := now.UTC().Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, time.UTC)
+}
+
+func endOfMonth(now time.Time) time.Time {
+	y, m, _ := now.UTC().Date()
+	return time.Date(y, m+1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func queryQuotaLimits(params domain.UserParams, now time.Time) []domain.QuotaLimit {
+	return []domain.QuotaLimit{
+		{Kind: domain.QuotaQueries, Period: domain.QuotaDaily, Limit: params.DailyQueries, ResetAt: endOfDay(now)},
+		{Kind: domain.QuotaQueries, Period: domain.QuotaMonthly, Limit: params.MonthlyQueries, ResetAt: endOfMonth(now)},
+	}
+}
+
+func voiceQuotaLimits(params domain.UserParams, now time.Time) []domain.QuotaLimit {
+	return []domain.QuotaLimit{
+		{Kind: domain.QuotaVoiceSeconds, Period: domain.QuotaDaily, Limit: params.DailyVoiceMinutes * 60, ResetAt: endOfDay(now)},
+		{Kind: domain.QuotaVoiceSeconds, Period: domain.QuotaMonthly, Limit: params.MonthlyVoiceMinutes * 60, ResetAt: endOfMonth(now)},
+	}
+}
+
+// consume meters amount against limits. Quota storage failures are logged and the request is
+// allowed, so a Redis hiccup does not take the whole chat down.
+func (s *ChatService) consume(ctx context.Context, subject string, amount int64, limits []domain.QuotaLimit) error {
+	if s.quotaRepo == nil {
+		return nil
+	}
+	exceeded, err := s.quotaRepo.Consume(ctx, subject, amount, limits)
+	return quotaError(subject, exceeded, err)
+}
+
+// quotaError turns the outcome of a quota repository call into a *domain.QuotaExceededError, logging storage failures.
+func quotaError(subject string, exceeded *domain.QuotaLimit, err error) error {
+	if err != nil {
+		log.Printf("Warning: Failed to check quota for %s: %v", subject, err)
+		return nil
+	}
+	if exceeded != nil {
+		return &domain.QuotaExceededError{Kind: exceeded.Kind, Period: exceeded.Period, Limit: exceeded.Limit, ResetAt: exceeded.ResetAt}
+	}
+	return nil
+}
+
+// consumeQueryQuota counts one query against the caller's daily and monthly limits.
+func (s *ChatService) consumeQueryQuota(ctx context.Context, req QueryRequest) error {
+	params := domain.GetUserParamsFromPlanID(req.PlanID)
+	return s.consume(ctx, quotaSubject(req.UserID, req.ClientIP), 1, queryQuotaLimits(params, time.Now()))
+}
+
+// refundQueryQuota gives back a query that was counted but ended without an answer. It still runs
+// when the request was cancelled, and failures are only logged.
+func (s *ChatService) refundQueryQuota(ctx context.Context, req QueryRequest) {
+	if s.quotaRepo == nil {
+		return
+	}
+	params := domain.GetUserParamsFromPlanID(req.PlanID)
+	subject := quotaSubject(req.UserID, req.ClientIP)
+	if err := s.quotaRepo.Refund(context.WithoutCancel(ctx), subject, 1, queryQuotaLimits(params, time.Now())); err != nil {
+		log.Printf("Warning: Failed to refund quota for %s: %v", subject, err)
+	}
+}
+
+// CheckVoiceQuota returns a *domain.QuotaExceededError if the caller has no voice time left today or this month.
+func (s *ChatService) CheckVoiceQuota(ctx context.Context, userID, clientIP, planID string) error {
+	usage, err := s.GetUsage(ctx, userID, clientIP, planID)
+	if err != nil {
+		log.Printf("Warning: Failed to check voice quota: %v", err)
+		return nil
+	}
+	for _, u := range usage {
+		if u.Kind == domain.QuotaVoiceSeconds && !u.Unlimited && u.Remaining == 0 {
+			return &domain.QuotaExceededError{Kind: u.Kind, Period: u.Period, Limit: u.Limit, ResetAt: u.ResetAt}
+		}
+	}
+	return nil
+}
+
+// ConsumeVoiceSeconds counts transcribed audio against the caller's daily and monthly voice allowances. The
+// audio has already been transcribed, so an overage still uses up what is left of the allowance before the error.
+func (s *ChatService) ConsumeVoiceSeconds(ctx context.Context, userID, clientIP, planID string, seconds int64) error {
+	if s.quotaRepo == nil {
+		return nil
+	}
+	params := domain.GetUserParamsFromPlanID(planID)
+	subject := quotaSubject(userID, clientIP)
+	exceeded, err := s.quotaRepo.ConsumeUpTo(ctx, subject, seconds, voiceQuotaLimits(params, time.Now()))
+	return quotaError(subject, exceeded, err)
+}
+
+// GetUsage reports the caller's consumption and remaining allowance for every quota of their plan.
+func (s *ChatService) GetUsage(ctx context.Context, userID, clientIP, planID string) ([]domain.QuotaUsage, error) {
+	if s.quotaRepo == nil {
+		return nil, nil
+	}
+	params := domain.GetUserParamsFromPlanID(planID)
+	now := time.Now()
+	limits := append(queryQuotaLimits(params, now), voiceQuotaLimits(params, now)...)
+	return s.quotaRepo.Usage(ctx, quotaSubject(userID, clientIP), limits)
+}
@@ -0,0 +1,123 @@
+package usecase
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+)
+
+// ResponseCacheLookupsCounter counts response cache lookups by result (exact_hit, similar_hit, miss). Registered in main.
+var ResponseCacheLookupsCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "chat_response_cache_lookups_total",
+		Help: "Number of response cache lookups by result",
+	},
+	[]string{"result"},
+)
+
+// normalizeCacheQuery lowercases the query and drops punctuation and extra whitespace,
+// so trivially different phrasings of the same question share a cache entry.
+func normalizeCacheQuery(query string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(query) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r):
+			b.WriteRune(r)
+		default:
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// responseCacheKeyFor builds the exact-match cache key for a normalized query, language and plan tier.
+func responseCacheKeyFor(normalizedQuery, language, planTier string) string {
+	sum := sha256.Sum256([]byte(normalizedQuery))
+	return language + ":" + planTier + ":" + hex.EncodeToString(sum[:])
+}
+
+// hasPriorTurns reports whether the history holds anything besides the current user message.
+// Answers to follow-up questions depend on the conversation, so they are neither cached nor served from cache.
+func hasPriorTurns(history []domain.ChatEntry, currentEntryID string) bool {
+	for _, entry := range history {
+		if entry.ID != currentEntryID {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupCachedAnswer tries an exact match first and then, if an embedder is configured, a similar question.
+// It also returns the query embedding so a miss can be stored without embedding the query twice.
+func (s *ChatService) lookupCachedAnswer(ctx context.Context, refinedQuery, language, planTier string) (*domain.CachedAnswer, []float32) {
+	normalized := normalizeCacheQuery(refinedQuery)
+	cached, err := s.responseCache.Get(ctx, responseCacheKeyFor(normalized, language, planTier))
+	if err != nil {
+		log.Printf("Warning: Response cache lookup failed: %v", err)
+	}
+	if cached != nil {
+		ResponseCacheLookupsCounter.WithLabelValues("exact_hit").Inc()
+		return cached, nil
+	}
+
+	var embedding []float32
+	if s.embedder != nil {
+		embedding, err = s.embedder.Embed(ctx, normalized)
+		if err != nil {
+			log.Printf("Warning: Failed to embed query for response cache: %v", err)
+		} else {
+			cached, err = s.responseCache.FindSimilar(ctx, language, planTier, embedding, s.cfg.ResponseCacheSimilarity)
+			if err != nil {
+				log.Printf("Warning: Response cache similarity lookup failed: %v", err)
+			}
+			if cached != nil {
+				ResponseCacheLookupsCounter.WithLabelValues("similar_hit").Inc()
+				return cached, embedding
+			}
+		}
+	}
+	ResponseCacheLookupsCounter.WithLabelValues("miss").Inc()
+	return nil, embedding
+}
+
+// storeCachedAnswer saves a freshly generated answer for later identical or similar questions.
+func (s *ChatService) storeCachedAnswer(ctx context.Context, refinedQuery, language, planTier string, embedding []float32, answer string, sources []domain.RAGSource, citations []domain.Citation, answerPromptVersion int) {
+	normalized := normalizeCacheQuery(refinedQuery)
+	entry := &domain.CachedAnswer{
+		Key:       responseCacheKeyFor(normalized, language, planTier),
+		Query:     normalized,
+		Language:  language,
+		PlanTier:  planTier,
+		Answer:    answer,
+		Sources:   sources,
+		Citations: citations,
+		Embedding: embedding,
+		// Answers served from the cache are counted against the prompt version that wrote them
+		PromptVersions: map[string]int{PromptAnswer: answerPromptVersion},
+		CreatedAt:      time.Now(),
+	}
+	saveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 2*time.Second)
+	defer cancel()
+	if err := s.responseCache.Put(saveCtx, entry, s.cfg.ResponseCacheTTL); err != nil {
+		log.Printf("Warning: Failed to store answer in response cache: %v", err)
+	}
+}
+
+// InvalidateResponseCache drops cached answers citing the given law document, or every cached answer if source is empty.
+func (s *ChatService) InvalidateResponseCache(ctx context.Context, source string) (int, error) {
+	if s.responseCache == nil {
+		return 0, nil
+	}
+	if source == "" {
+		return s.responseCache.InvalidateAll(ctx)
+	}
+	return s.responseCache.InvalidateSource(ctx, source)
+}
@@ -0,0 +1,48 @@
+package usecase
+
+import (
+	"context"
+	"log"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+)
+
+// retrieve runs the RAG retrieval, streaming it when the service supports that. The sources the answer
+// may cite are forwarded to the client as they arrive, so they show before generation starts.
+func (s *ChatService) retrieve(ctx context.Context, query string, maxRefs int, resChan chan<- ChatResponseChunk) (*domain.RAGResult, error) {
+	streamer, ok := s.ragService.(domain.StreamingRAGService)
+	if !ok {
+		return s.ragService.Retrieve(ctx, query, maxRefs)
+	}
+	events, err := streamer.RetrieveStream(ctx, query, maxRefs)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &domain.RAGResult{}
+	for event := range events {
+		if event.Err != nil {
+			if len(result.Results) == 0 {
+				return nil, event.Err
+			}
+			// Keep what already arrived (and was shown) rather than failing the answer
+			log.Printf("Warning: RAG stream ended early after %d results: %v", len(result.Results), event.Err)
+			break
+		}
+		if event.Message != "" {
+			result.Message = event.Message
+		}
+		result.Degraded = result.Degraded || event.Degraded
+		if len(event.Sources) == 0 {
+			continue
+		}
+		shown := len(s.filterSources(result.Results, maxRefs))
+		result.Results = append(result.Results, event.Sources...)
+		if sources := s.filterSources(result.Results, maxRefs); len(sources) > shown {
+			if !send(ctx, resChan, ChatResponseChunk{RetrievedSources: sources}) {
+				return nil, ctx.Err()
+			}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,182 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+)
+
+// defaultSessionTitle is shown until a title has been generated from the first exchange.
+const defaultSessionTitle = "New Chat"
+
+const (
+	maxSessionTitleLength = 80
+	maxSearchResults      = 50
+)
+
+// titleSession generates a title for an account holder's session after its first answer, off the response path.
+func (s *ChatService) titleSession(ctx context.Context, session *domain.Session, isGuest bool, question, answer string) {
+	if isGuest || session.Title != defaultSessionTitle || answer == "" {
+		return
+	}
+	go func() {
+		titleCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+		defer cancel()
+
+		prompt, _, err := s.prompts.Render(titleCtx, PromptTitle, session.ID, TitlePromptData{Query: question, Answer: answer})
+		var title string
+		if err == nil {
+			title, err = s.llmService.Generate(titleCtx, prompt, nil)
+		}
+		if err != nil {
+			log.Printf("Warning: Failed to generate title for session %s: %v", session.ID, err)
+			return
+		}
+		title = cleanSessionTitle(title)
+		if title == "" {
+			return
+		}
+		// Only replace the default title, never one the user set in the meantime
+		update := func(repo domain.SessionRepository) error {
+			current, err := repo.GetSessionByID(titleCtx, session.ID)
+			if err != nil {
+				return err
+			}
+			if current.Title != defaultSessionTitle {
+				return nil
+			}
+			current.Title = title
+			return repo.UpdateSession(titleCtx, current)
+		}
+		if err := update(s.sessionRepo); err != nil {
+			log.Printf("Warning: Failed to save title for session %s to Redis: %v", session.ID, err)
+		}
+		if err := update(s.mongoSessionRepo); err != nil {
+			log.Printf("Warning: Failed to save title for session %s to MongoDB: %v", session.ID, err)
+		}
+	}()
+}
+
+// cleanSessionTitle strips the quotes and trailing punctuation models like to add, and caps the length.
+func cleanSessionTitle(title string) string {
+	title = strings.TrimSpace(strings.SplitN(strings.TrimSpace(title), "\n", 2)[0])
+	title = strings.Trim(title, "\"'`*#. ")
+	if runes := []rune(title); len(runes) > maxSessionTitleLength {
+		title = strings.TrimSpace(string(runes[:maxSessionTitleLength]))
+	}
+	return title
+}
+
+// ownedSession loads a session from MongoDB and checks that it belongs to userID.
+func (s *ChatService) ownedSession(ctx context.Context, userID, sessionID string) (*domain.Session, error) {
+	session, err := s.mongoSessionRepo.GetSessionByID(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrSessionNotFound, err)
+	}
+	if session.UserID != userID {
+		return nil, domain.ErrSessionAccessDenied
+	}
+	return session, nil
+}
+
+// UpdateSession renames, pins or archives one of the user's sessions.
+func (s *ChatService) UpdateSession(ctx context.Context, userID, sessionID string, update domain.SessionUpdate) (*domain.Session, error) {
+	if update.Title != nil {
+		title := strings.TrimSpace(*update.Title)
+		if title == "" || len([]rune(title)) > maxSessionTitleLength {
+			return nil, fmt.Errorf("%w: title must be between 1 and %d characters", domain.ErrInvalidSessionUpdate, maxSessionTitleLength)
+		}
+		update.Title = &title
+	}
+	session, err := s.ownedSession(ctx, userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	applySessionUpdate(session, update)
+	if err := s.mongoSessionRepo.UpdateSession(ctx, session); err != nil {
+		return nil, err
+	}
+
+	// Keep the cached copy of an active session in step, so the next query does not write the old values back
+	if cached, err := s.sessionRepo.GetSessionByID(ctx, sessionID); err == nil {
+		applySessionUpdate(cached, update)
+		if err := s.sessionRepo.UpdateSession(ctx, cached); err != nil {
+			log.Printf("Warning: Failed to update session %s in Redis: %v", sessionID, err)
+		}
+	}
+	return session, nil
+}
+
+func applySessionUpdate(session *domain.Session, update domain.SessionUpdate) {
+	if update.Title != nil {
+		session.Title = *update.Title
+	}
+	if update.Pinned != nil {
+		session.Pinned = *update.Pinned
+	}
+	if update.Archived != nil {
+		session.Archived = *update.Archived
+	}
+}
+
+// DeleteSession removes one of the user's sessions with its entries, summary and share links from Redis and MongoDB.
+// Redis goes first so the sync worker has nothing left to copy back into MongoDB. The tombstone is left before
+// anything is removed, so a sync or summary write that was already under way cleans up after itself.
+func (s *ChatService) DeleteSession(ctx context.Context, userID, sessionID string) error {
+	if _, err := s.ownedSession(ctx, userID, sessionID); err != nil {
+		return err
+	}
+	if err := s.sessionRepo.MarkSessionDeleted(ctx, sessionID); err != nil {
+		return err
+	}
+	if err := s.chatRepo.DeleteChatHistory(ctx, sessionID); err != nil {
+		return err
+	}
+	if err := s.sessionRepo.DeleteSession(ctx, sessionID); err != nil {
+		return err
+	}
+	if err := s.mongoChatRepo.DeleteChatHistory(ctx, sessionID); err != nil {
+		return err
+	}
+	if err := s.shareRepo.DeleteShareLinksBySessionID(ctx, sessionID); err != nil {
+		return err
+	}
+	return s.mongoSessionRepo.DeleteSession(ctx, sessionID)
+}
+
+// SearchHistory runs a full-text search over all of the user's synced chat entries.
+func (s *ChatService) SearchHistory(ctx context.Context, userID, query string, limit int) ([]domain.ChatSearchResult, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, errors.New("search query cannot be empty")
+	}
+	if limit <= 0 || limit > maxSearchResults {
+		limit = 20
+	}
+	sessionIDs, err := s.mongoSessionRepo.GetSessionIDsByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	results, err := s.mongoChatRepo.SearchChatEntries(ctx, sessionIDs, query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	titles := make(map[string]string)
+	for i := range results {
+		sessionID := results[i].Entry.SessionID
+		if _, ok := titles[sessionID]; !ok {
+			if session, err := s.mongoSessionRepo.GetSessionByID(ctx, sessionID); err == nil {
+				titles[sessionID] = session.Title
+			}
+		}
+		results[i].SessionTitle = titles[sessionID]
+		results[i].Entry = results[i].Entry.WithoutTrace()
+	}
+	return results, nil
+}
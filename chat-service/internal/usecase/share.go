@@ -0,0 +1,94 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+)
+
+const shareTokenBytes = 24
+
+// CreateShareLink creates a read-only link to one of the user's sessions. A nil expiresAt never expires.
+func (s *ChatService) CreateShareLink(ctx context.Context, userID, sessionID string, expiresAt *time.Time) (*domain.ShareLink, error) {
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, domain.ErrInvalidShareExpiry
+	}
+	if _, err := s.ownedSession(ctx, userID, sessionID); err != nil {
+		return nil, err
+	}
+	token, err := newShareToken()
+	if err != nil {
+		return nil, err
+	}
+	link := &domain.ShareLink{
+		Token:     token,
+		SessionID: sessionID,
+		UserID:    userID,
+		CreatedAt: time.Now(),
+		ExpiresAt: expiresAt,
+	}
+	if err := s.shareRepo.CreateShareLink(ctx, link); err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+func newShareToken() (string, error) {
+	b := make([]byte, shareTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate share token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// ListShareLinks returns the user's active share links.
+func (s *ChatService) ListShareLinks(ctx context.Context, userID string) ([]domain.ShareLink, error) {
+	return s.shareRepo.GetActiveShareLinksByUserID(ctx, userID)
+}
+
+// RevokeShareLink stops one of the user's share links from working.
+func (s *ChatService) RevokeShareLink(ctx context.Context, userID, linkID string) error {
+	return s.shareRepo.RevokeShareLink(ctx, userID, linkID)
+}
+
+// GetSharedSession returns the public view of the session a share token points to. Revoked and expired
+// links are reported as not found, so a token's history cannot be probed.
+func (s *ChatService) GetSharedSession(ctx context.Context, token string) (*domain.SharedSession, error) {
+	link, err := s.shareRepo.GetShareLinkByToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if !link.Active(time.Now()) {
+		return nil, domain.ErrShareLinkNotFound
+	}
+	session, err := s.mongoSessionRepo.GetSessionByID(ctx, link.SessionID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrShareLinkNotFound, err)
+	}
+	entries, err := s.ListMessages(ctx, link.SessionID, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	shared := &domain.SharedSession{
+		Title:     session.Title,
+		Language:  session.Language,
+		CreatedAt: session.CreatedAt,
+		Messages:  make([]domain.SharedMessage, 0, len(entries)),
+	}
+	for _, entry := range entries {
+		shared.Messages = append(shared.Messages, domain.SharedMessage{
+			Type:       entry.Type,
+			Content:    entry.Content,
+			Sources:    entry.Sources,
+			Citations:  entry.Citations,
+			Disclaimer: entry.Disclaimer,
+			CreatedAt:  entry.CreatedAt,
+		})
+	}
+	return shared, nil
+}
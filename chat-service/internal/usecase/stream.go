@@ -0,0 +1,48 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+)
+
+// streamListenerTTL is how long a resumed reader counts as connected after its last read.
+const streamListenerTTL = 30 * time.Second
+
+// RecordStreamEvents buffers events of a query stream so a client that loses its connection can resume it.
+func (s *ChatService) RecordStreamEvents(ctx context.Context, info *domain.StreamInfo, events []domain.StreamEvent) error {
+	return s.streamBuffer.AppendStreamEvents(ctx, info, events, s.cfg.StreamBufferTTL)
+}
+
+// GetResumableStream returns a buffered stream the principal may read. Account holders must own it;
+// visitors prove access with the stream's session ID.
+func (s *ChatService) GetResumableStream(ctx context.Context, principal domain.Principal, messageID, sessionID string) (*domain.StreamInfo, error) {
+	info, err := s.streamBuffer.GetStreamInfo(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if info.UserID != principal.UserID || (info.UserID == "" && info.SessionID != sessionID) {
+		return nil, domain.ErrSessionAccessDenied
+	}
+	return info, nil
+}
+
+// ReadStreamEvents returns the events after afterID, waiting up to block for new ones. It also marks the
+// stream as having a reader, which keeps the generation behind it alive.
+func (s *ChatService) ReadStreamEvents(ctx context.Context, info *domain.StreamInfo, afterID int64, block time.Duration) ([]domain.StreamEvent, error) {
+	if err := s.streamBuffer.SetStreamListener(ctx, info.MessageID, block+streamListenerTTL); err != nil {
+		return nil, err
+	}
+	return s.streamBuffer.ReadStreamEvents(ctx, info, afterID, block)
+}
+
+// HasStreamListener reports whether a resumed client is reading the stream.
+func (s *ChatService) HasStreamListener(ctx context.Context, messageID string) bool {
+	listening, err := s.streamBuffer.HasStreamListener(ctx, messageID)
+	if err != nil {
+		// Keep generating rather than drop an answer someone may be waiting for
+		return true
+	}
+	return listening
+}
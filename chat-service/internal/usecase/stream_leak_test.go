@@ -0,0 +1,69 @@
+package usecase
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+)
+
+// waitForGoroutines fails the test unless the goroutine count drops back to baseline within a few seconds.
+func waitForGoroutines(t *testing.T, baseline int) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for runtime.NumGoroutine() > baseline {
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<20)
+			t.Fatalf("%d goroutines left running, want %d:\n%s", runtime.NumGoroutine(), baseline, buf[:runtime.Stack(buf, true)])
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestProcessQueryStopsWhenClientDisconnects(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		language   string
+		translator *fakeTranslator
+	}{
+		{name: "plain stream", language: "en", translator: &fakeTranslator{}},
+		{name: "translated through answerWriter", language: "am", translator: &fakeTranslator{delay: 20 * time.Millisecond}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			chat := newTestChat(t, tc.translator)
+			baseline := runtime.NumGoroutine()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			chunks, err := chat.ProcessQuery(ctx, QueryRequest{
+				PlanID:   string(domain.TierGuest),
+				ClientIP: "192.0.2.1",
+				Message:  "Can my employer dismiss me without severance pay?",
+				Language: tc.language,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Wait for the answer to start, then go away mid-stream without reading the rest
+			for {
+				chunk, ok := receive(t, chunks)
+				if !ok || chunk.Error != nil || chunk.IsComplete {
+					t.Fatalf("stream ended before any answer text: %+v", chunk)
+				}
+				if chunk.Text != "" {
+					break
+				}
+			}
+			// Stop reading long enough for the service to block on the next chunk, as it would with a dead client
+			time.Sleep(100 * time.Millisecond)
+			cancel()
+
+			waitForGoroutines(t, baseline)
+			if prompts := chat.llm.Prompts(); len(prompts) == 0 {
+				t.Error("the fake LLM was never called")
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+)
+
+// getSummary returns the session's rolling summary. Account holders' summaries fall back to MongoDB,
+// so earlier facts survive the Redis session expiring.
+func (s *ChatService) getSummary(ctx context.Context, sessionID string, isGuest bool) *domain.ChatEntry {
+	summary, err := s.chatRepo.GetSummary(ctx, sessionID)
+	if err != nil {
+		log.Printf("Warning: Failed to get summary for session %s from Redis: %v", sessionID, err)
+	}
+	if summary != nil || isGuest {
+		return summary
+	}
+	summary, err = s.mongoChatRepo.GetSummary(ctx, sessionID)
+	if err != nil {
+		log.Printf("Warning: Failed to get summary for session %s from MongoDB: %v", sessionID, err)
+	}
+	return summary
+}
+
+// summarizeOlderTurns rolls turns that will no longer fit in the next request's context window into the
+// session summary. Only turns newer than the existing summary are sent to the LLM, together with that summary.
+func (s *ChatService) summarizeOlderTurns(ctx context.Context, sessionID string, isGuest bool, params domain.UserParams, previous *domain.ChatEntry) {
+	if params.MaxSummaryWords <= 0 {
+		return
+	}
+	history, err := s.chatRepo.GetChatHistory(ctx, sessionID, 0)
+	if err != nil {
+		log.Printf("Warning: Failed to get chat history to summarize session %s: %v", sessionID, err)
+		return
+	}
+
+	// The next request's window holds its own query plus the latest 2*ContextWindow-1 stored entries
+	keep := 2*params.ContextWindow - 1
+	if keep < 0 {
+		keep = 0
+	}
+	if len(history) <= keep {
+		return
+	}
+	var turns []domain.ChatEntry
+	for _, entry := range history[:len(history)-keep] {
+		if previous == nil || previous.SummarizedUntil == nil || entry.CreatedAt.After(*previous.SummarizedUntil) {
+			turns = append(turns, entry)
+		}
+	}
+	if len(turns) == 0 {
+		return
+	}
+
+	previousText := "(none)"
+	if previous != nil && previous.Content != "" {
+		previousText = previous.Content
+	}
+	prompt, promptVersion, err := s.prompts.Render(ctx, PromptSummarize, sessionID, SummarizePromptData{
+		Summary:  previousText,
+		Turns:    formatTurns(turns),
+		MaxWords: params.MaxSummaryWords,
+	})
+	var text string
+	if err == nil {
+		text, err = s.llmService.Generate(ctx, prompt, nil)
+	}
+	if err != nil {
+		log.Printf("Warning: Failed to summarize session %s: %v", sessionID, err)
+		return
+	}
+
+	summarizedUntil := turns[len(turns)-1].CreatedAt
+	summary := &domain.ChatEntry{
+		SessionID:       sessionID,
+		Type:            domain.MessageTypeSummary,
+		Content:         s.enforceLimits(strings.TrimSpace(text), params.MaxSummaryWords),
+		SummarizedUntil: &summarizedUntil,
+		PromptVersions:  map[string]int{PromptSummarize: promptVersion},
+		CreatedAt:       time.Now(),
+	}
+	if previous != nil {
+		summary.ID, summary.MongoID = previous.ID, previous.MongoID
+	}
+	if err := s.chatRepo.SaveSummary(ctx, summary); err != nil {
+		log.Printf("Warning: Failed to save summary for session %s to Redis: %v", sessionID, err)
+	}
+	if !isGuest {
+		if err := s.mongoChatRepo.SaveSummary(ctx, summary); err != nil {
+			log.Printf("Warning: Failed to save summary for session %s to MongoDB: %v", sessionID, err)
+		}
+	}
+	// The session may have been deleted while the LLM was summarizing it
+	if _, err := discardIfDeleted(ctx, s.sessionRepo, s.chatRepo, s.mongoChatRepo, sessionID); err != nil {
+		log.Printf("Warning: Failed to discard the summary of deleted session %s: %v", sessionID, err)
+	}
+}
+
+// formatTurns renders history entries as a User/Assistant transcript.
+func formatTurns(entries []domain.ChatEntry) string {
+	var b strings.Builder
+	for _, entry := range entries {
+		if entry.Type == domain.MessageTypeUser {
+			b.WriteString(fmt.Sprintf("User: %s\n", entry.Content))
+		} else if entry.Type == domain.MessageTypeLLM {
+			b.WriteString(fmt.Sprintf("Assistant: %s\n", entry.Content))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,271 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"strings"
+	"sync/atomic"
+
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+)
+
+const (
+	// speechChunkBytes bounds the audio carried by one event.
+	speechChunkBytes = 16 * 1024
+	// maxQueuedSentences is how far the text answer may run ahead of the speech before it waits.
+	maxQueuedSentences = 64
+)
+
+// VoiceService answers spoken questions. The audio is transcribed as it arrives, the transcript is answered
+// like a typed question and the answer is spoken a sentence at a time while it is generated.
+type VoiceService struct {
+	chatService *ChatService
+	stt         domain.SpeechToTextService
+	tts         domain.TextToSpeechService
+}
+
+func NewVoiceService(chatService *ChatService, stt domain.SpeechToTextService, tts domain.TextToSpeechService) *VoiceService {
+	return &VoiceService{
+		chatService: chatService,
+		stt:         stt,
+		tts:         tts,
+	}
+}
+
+type VoiceRequest struct {
+	SessionID   string
+	UserID      string
+	PlanID      string
+	ClientIP    string
+	Language    string // of the question and the answer, en or am
+	ContentType string // of the audio
+}
+
+// VoiceEvent is one step of a spoken exchange. Exactly one field is set.
+type VoiceEvent struct {
+	Transcript *domain.TranscriptEvent // a partial or the final transcript of the question
+	Answer     *ChatResponseChunk      // the text answer, as ProcessQuery streams it
+	Sentence   *SpokenSentence         // the answer sentence whose speech follows in Audio events
+	Audio      []byte                  // audio/mpeg speech of the last Sentence
+	Error      error                   // ends the exchange
+}
+
+// SpokenSentence introduces the speech for one sentence of the answer. Each is a separate audio clip.
+type SpokenSentence struct {
+	Index int
+	Text  string
+}
+
+// Converse answers the utterance read from audio, which the caller closes when the user stops speaking. It
+// returns a *domain.QuotaExceededError if the caller has no voice time left. The returned channel is
+// closed once the answer has been spoken or ctx is cancelled.
+func (v *VoiceService) Converse(ctx context.Context, req VoiceRequest, audio <-chan []byte) (<-chan VoiceEvent, error) {
+	if err := v.chatService.CheckVoiceQuota(ctx, req.UserID, req.ClientIP, req.PlanID); err != nil {
+		return nil, err
+	}
+	stream, err := v.stt.StartTranscription(ctx, req.ContentType, req.Language)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transcription: %w", err)
+	}
+	events := make(chan VoiceEvent)
+	go v.converse(ctx, req, audio, stream, events)
+	return events, nil
+}
+
+func (v *VoiceService) converse(ctx context.Context, req VoiceRequest, audio <-chan []byte, stream domain.TranscriptionStream, events chan<- VoiceEvent) {
+	defer close(events)
+	defer stream.Close()
+
+	transcript, received, err := transcribe(ctx, audio, stream, events)
+	if err != nil {
+		if ctx.Err() == nil {
+			sendVoice(ctx, events, VoiceEvent{Error: err})
+		}
+		return
+	}
+
+	// Meter the audio against the daily and monthly voice allowances
+	seconds := int64(math.Ceil(transcript.Duration))
+	if seconds <= 0 {
+		seconds = estimateAudioSeconds(received)
+	}
+	if err := v.chatService.ConsumeVoiceSeconds(ctx, req.UserID, req.ClientIP, req.PlanID, seconds); err != nil {
+		sendVoice(ctx, events, VoiceEvent{Error: err})
+		return
+	}
+
+	chunks, err := v.chatService.ProcessQuery(ctx, QueryRequest{
+		SessionID: req.SessionID,
+		UserID:    req.UserID,
+		PlanID:    req.PlanID,
+		ClientIP:  req.ClientIP,
+		Message:   strings.TrimSpace(transcript.Text),
+		Language:  req.Language,
+	})
+	if err != nil {
+		sendVoice(ctx, events, VoiceEvent{Error: err})
+		return
+	}
+
+	// Speak sentences as they complete, without holding back the text answer
+	sentences := make(chan string, maxQueuedSentences)
+	spoken := make(chan struct{})
+	go func() {
+		defer close(spoken)
+		v.speak(ctx, req.Language, sentences, events)
+	}()
+	queue := func(sentence string) {
+		// Citation markers are for reading, not for listening
+		text := strings.TrimSpace(citationMarkerRe.ReplaceAllString(sentence, ""))
+		if text == "" {
+			return
+		}
+		select {
+		case sentences <- text:
+		case <-ctx.Done():
+		}
+	}
+	var pending sentenceBuffer
+	for chunk := range chunks {
+		chunk := chunk
+		sendVoice(ctx, events, VoiceEvent{Answer: &chunk})
+		if chunk.Error != nil || chunk.Text == "" {
+			continue
+		}
+		if req.Language == "en" {
+			for _, sentence := range pending.Write(chunk.Text) {
+				queue(sentence)
+			}
+		} else {
+			// Translated answers already arrive a sentence at a time
+			queue(chunk.Text)
+		}
+	}
+	queue(pending.Flush())
+	close(sentences)
+	<-spoken
+}
+
+// transcribe feeds the audio to the STT stream, passing partial transcripts on, until the final transcript
+// arrives. It also returns how many bytes of audio were received.
+func transcribe(ctx context.Context, audio <-chan []byte, stream domain.TranscriptionStream, events chan<- VoiceEvent) (domain.TranscriptEvent, int64, error) {
+	var received atomic.Int64
+	pumped := make(chan error, 1)
+	go func() {
+		pumped <- pumpAudio(ctx, audio, stream, &received)
+	}()
+
+	results := stream.Events()
+	for {
+		select {
+		case <-ctx.Done():
+			return domain.TranscriptEvent{}, 0, ctx.Err()
+		case err := <-pumped:
+			if err != nil {
+				return domain.TranscriptEvent{}, 0, err
+			}
+			pumped = nil
+		case event, ok := <-results:
+			if !ok {
+				return domain.TranscriptEvent{}, 0, fmt.Errorf("%w: transcription ended without a transcript", domain.ErrSpeechUnavailable)
+			}
+			if event.Err != nil {
+				return domain.TranscriptEvent{}, 0, event.Err
+			}
+			if !sendVoice(ctx, events, VoiceEvent{Transcript: &event}) {
+				return domain.TranscriptEvent{}, 0, ctx.Err()
+			}
+			if event.Final {
+				if strings.TrimSpace(event.Text) == "" {
+					return domain.TranscriptEvent{}, 0, domain.ErrNoSpeech
+				}
+				return event, received.Load(), nil
+			}
+		}
+	}
+}
+
+// pumpAudio writes audio to the stream until the caller closes it, then ends the utterance.
+func pumpAudio(ctx context.Context, audio <-chan []byte, stream domain.TranscriptionStream, received *atomic.Int64) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case chunk, ok := <-audio:
+			if !ok {
+				return stream.CloseSend()
+			}
+			received.Add(int64(len(chunk)))
+			if err := stream.Write(chunk); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// speak synthesizes the queued sentences in order. A sentence that cannot be spoken is skipped; its text
+// has already been sent.
+func (v *VoiceService) speak(ctx context.Context, language string, sentences <-chan string, events chan<- VoiceEvent) {
+	index := 0
+	for text := range sentences {
+		speech, err := v.tts.Synthesize(ctx, text, language)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Warning: Failed to synthesize answer sentence: %v", err)
+			continue
+		}
+		ok := sendVoice(ctx, events, VoiceEvent{Sentence: &SpokenSentence{Index: index, Text: text}}) &&
+			sendSpeech(ctx, speech, events)
+		speech.Close()
+		if !ok {
+			return
+		}
+		index++
+	}
+}
+
+// sendSpeech passes the audio on as the TTS service produces it.
+func sendSpeech(ctx context.Context, speech io.Reader, events chan<- VoiceEvent) bool {
+	for {
+		buf := make([]byte, speechChunkBytes)
+		n, err := speech.Read(buf)
+		if n > 0 && !sendVoice(ctx, events, VoiceEvent{Audio: buf[:n]}) {
+			return false
+		}
+		if errors.Is(err, io.EOF) {
+			return true
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return false
+			}
+			log.Printf("Warning: Failed to read synthesized speech: %v", err)
+			return true
+		}
+	}
+}
+
+func sendVoice(ctx context.Context, events chan<- VoiceEvent, event VoiceEvent) bool {
+	select {
+	case events <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// estimateAudioSeconds approximates the length of a recording at a typical 128 kbps compressed bitrate.
+func estimateAudioSeconds(sizeBytes int64) int64 {
+	const bytesPerSecond = 16000
+	seconds := (sizeBytes + bytesPerSecond - 1) / bytesPerSecond
+	if seconds < 1 {
+		seconds = 1
+	}
+	return seconds
+}
@@ -42,4 +42,10 @@ func SendSSEError(w http.ResponseWriter, errMsg string) {
 	errorData := map[string]string{"message": errMsg}
 	// Call SendSSEEvent with the "error" event name.
 	SendSSEEvent(w, "error", errorData)
-}
\ No newline at end of file
+}
+// SendSSEEventWithID sends an event with an ID. Clients send the last ID they received back in the
+// Last-Event-ID header when they reconnect, so the stream can resume after it.
+func SendSSEEventWithID(w http.ResponseWriter, id int64, event string, data interface{}) {
+	fmt.Fprintf(w, "id: %d\n", id)
+	SendSSEEvent(w, event, data)
+}
@@ -28,7 +28,7 @@ func NewJWT(accessSecret string) *JWT {
 func (j *JWT) ValidateAccessToken(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {		
 		return []byte(j.AccessSecret), nil
-	})
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 
 	if err != nil {
 		return nil, errors.New("invalid token: " + err.Error())
@@ -4,6 +4,7 @@ import (
 	client "github.com/LAWGEN/lawgen-backend/chat-service/internal/client"
 	"github.com/LAWGEN/lawgen-backend/chat-service/internal/config"
 	"github.com/LAWGEN/lawgen-backend/chat-service/internal/domain"
+	"github.com/LAWGEN/lawgen-backend/chat-service/internal/export"
 	"github.com/LAWGEN/lawgen-backend/chat-service/internal/repository"
 	mongoRepo "github.com/LAWGEN/lawgen-backend/chat-service/internal/repository/mongo"
 	redisRepo "github.com/LAWGEN/lawgen-backend/chat-service/internal/repository/redis"
@@ -32,41 +33,6 @@ import (
 	ginprometheus "github.com/zsais/go-gin-prometheus"
 )
 
-// UserContextMiddleware sets user information from headers for simulation.
-func UserContextMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		userID := c.GetHeader("X-User-ID")
-		planID := c.GetHeader("X-Plan-ID")
-		role := c.GetHeader("X-User-Role")
-
-		if userID != "" {
-			c.Set("userID", userID)
-		}
-		// Default to 'free' for logged-in users if no plan is specified
-		if planID != "" {
-			c.Set("plan_id", planID)
-		} else if userID != "" {
-			c.Set("plan_id", "free")
-		}
-		if role != "" {
-			c.Set("userRole", role)
-		}
-		c.Next()
-	}
-}
-
-// AdminAuthMiddleware checks for admin role.
-func AdminAuthMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		role, _ := c.Get("userRole")
-		if role != "admin" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Admin access required"})
-			return
-		}
-		c.Next()
-	}
-}
-
 func main() {
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found")
@@ -111,51 +77,97 @@ func main() {
 		log.Fatalf("Failed to ping Redis: %v", err)
 	}
 	log.Println("Connected to Redis")
+	// Resumed streams block on reads, so they use a pool of their own and can't starve the rest of the service
+	streamReader := redis.NewClient(&redis.Options{
+		Addr:     u.Host,
+		Password: password,
+		DB:       0,
+		PoolSize: cfg.StreamReadPoolSize,
+	})
+	defer func() {
+		if err := streamReader.Close(); err != nil {
+			log.Printf("Error closing Redis stream reader: %v", err)
+		}
+	}()
 
 	// Initialize repositories
 	mongoSessionRepo := mongoRepo.NewSessionRepository(db)
 	mongoChatRepo := mongoRepo.NewChatRepository(db)
+	shareRepo := mongoRepo.NewShareLinkRepository(db)
+	analyticsRepo := mongoRepo.NewAnalyticsRepository(db)
+	promptRepo := mongoRepo.NewPromptRepository(db)
+	glossaryRepo := mongoRepo.NewGlossaryRepository(db)
 
 	redisSessionRepo := redisRepo.NewRedisSessionRepository(rdb, cfg)
 	redisChatRepo := redisRepo.NewRedisChatRepository(rdb, cfg)
 
 	quizRepo := repository.NewQuizRepository(db)
+	redisLock := redisRepo.NewRedisLock(rdb)
+	responseCache := redisRepo.NewRedisResponseCache(rdb)
+	quotaRepo := redisRepo.NewRedisQuotaRepository(rdb)
 
 	// Initialize clients
-	llmClient, err := client.NewLLMClient(cfg)
+	llmClient, err := client.NewLLMService(cfg)
 	if err != nil {
 		log.Fatalf("Failed to create LLM client: %v", err)
 	}
+	defer llmClient.Close()
+	log.Printf("Using LLM provider: %s", cfg.LLMProvider)
 
 	var ragClient domain.RAGService
 	ragClient, err = client.NewRAGClient(cfg)
 	if err != nil {
 		log.Fatalf("Failed to initialize RAG client: %v", err)
 	}
+	ragClient = client.NewResilientRAGService(cfg, ragClient, mongoRepo.NewLawArticleRepository(db))
 	defer ragClient.Close()
 
+	embedder := client.NewEmbedder(cfg)
+
 	// Initialize use cases
-	chatUseCase := usecase.NewChatService(cfg, redisSessionRepo, redisChatRepo, mongoSessionRepo, mongoChatRepo, llmClient, ragClient)
-	quizUseCase := usecase.NewQuizUseCase(quizRepo)
+	glossaryUseCase := usecase.NewGlossaryService(cfg, glossaryRepo)
+	if err := glossaryUseCase.SeedDefaults(ctx); err != nil {
+		log.Printf("Warning: Failed to seed the legal glossary: %v", err)
+	}
+	// Answers are translated with the glossary's approved equivalents of legal terms
+	translator := usecase.NewGlossaryTranslator(client.NewTranslationClient(cfg, redisRepo.NewRedisTranslationCache(rdb)), glossaryUseCase)
+	defer translator.Close()
+	promptUseCase, err := usecase.NewPromptService(cfg, promptRepo, mongoChatRepo)
+	if err != nil {
+		log.Fatalf("Invalid LLM prompt: %v", err)
+	}
+	guardrails, err := usecase.NewGuardrails(cfg)
+	if err != nil {
+		log.Fatalf("Invalid guardrail rules: %v", err)
+	}
+	chatUseCase := usecase.NewChatService(cfg, redisSessionRepo, redisChatRepo, mongoSessionRepo, mongoChatRepo, llmClient, ragClient, responseCache, embedder, quotaRepo, shareRepo, analyticsRepo, translator, redisRepo.NewRedisStreamBuffer(rdb, streamReader), promptUseCase, guardrails, glossaryUseCase)
+	voiceUseCase := usecase.NewVoiceService(chatUseCase, client.NewSTTClient(cfg), client.NewTTSClient(cfg))
+	quizUseCase := usecase.NewQuizUseCase(quizRepo, ragClient, llmClient, promptUseCase)
+	chatSyncWorker := usecase.NewChatSyncWorker(cfg, redisSessionRepo, redisChatRepo, mongoChatRepo, redisLock)
 
 	// Initialize controllers
 	quizController := app.NewQuizController(quizUseCase)
-	chatController := app.NewChatController(chatUseCase)
+	chatController := app.NewChatController(cfg, chatUseCase, export.NewExporter(cfg))
+	voiceController := app.NewVoiceChatController(cfg, voiceUseCase)
+	promptController := app.NewPromptController(promptUseCase)
+	glossaryController := app.NewGlossaryController(glossaryUseCase)
 
 	// setup middleware
-	// jwt := NewJWT(cfg.AccessSecret)
+	jwt := NewJWT(cfg.AccessSecret)
 
 	// Setup router
-	router := gin.Default()
+	router := gin.New()
+	router.Use(requestLogger(), gin.Recovery())
+	// Visitors' quotas are keyed on the client IP, so only known proxies may set it through X-Forwarded-For
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
 	router.Use(gzip.Gzip(gzip.DefaultCompression))
 
 	corsConfig := cors.Config{
-		AllowOrigins: []string{
-			"http://localhost:3000",
-			"https://lawgen-frontend-wine.vercel.app",
-		},
+		AllowOrigins:     cfg.AllowedOrigins,
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-Client-Type", "planID", "userID"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-Client-Type"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
@@ -163,8 +175,7 @@ func main() {
 	router.Use(cors.New(corsConfig))
 
 	router.StaticFile("/", "./index.html")
-	// router.Use(AuthMiddleware(*jwt))
-	router.Use(UserContextMiddleware())
+	router.Use(AuthMiddleware(*jwt, cfg.GatewaySecret, cfg.GatewayMaxClockSkew))
 
 	// Prometheus middleware for Gin
 	p := ginprometheus.NewPrometheus("chat_service")
@@ -175,10 +186,15 @@ func main() {
 
 	// Register custom Prometheus metrics
 	prometheus.MustRegister(app.ChatLatencyHistogram)
+	prometheus.MustRegister(usecase.ChatSyncedEntriesCounter, usecase.ChatSyncFailedEntriesCounter, usecase.ChatSyncLaggingEntriesGauge)
+	prometheus.MustRegister(usecase.ResponseCacheLookupsCounter, usecase.GuardrailVerdictsCounter)
+	prometheus.MustRegister(client.RAGBreakerStateGauge, client.RAGFallbackRetrievalsCounter)
 
 	// Register routes
 	app.RegisterQuizRoutes(router, quizController, RoleMiddleware())
-	app.RegisterChatRoutes(router, chatController, cfg)
+	app.RegisterChatRoutes(router, chatController, voiceController, RoleMiddleware())
+	app.RegisterPromptRoutes(router, promptController, RoleMiddleware())
+	app.RegisterGlossaryRoutes(router, glossaryController, RoleMiddleware())
 
 	// Start server
 	srv := &http.Server{
@@ -192,6 +208,14 @@ func main() {
 		}
 	}()
 
+	// Start the background Redis -> MongoDB chat history sync
+	syncCtx, stopSync := context.WithCancel(context.Background())
+	syncDone := make(chan struct{})
+	go func() {
+		defer close(syncDone)
+		chatSyncWorker.Run(syncCtx)
+	}()
+
 	// Wait for interrupt signal to gracefully shut down the server
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -201,8 +225,13 @@ func main() {
 	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatal("Server forced to shutdown:", err)
+		// Still flush the chat history below; exiting here would skip it exactly when requests were cut off
+		log.Println("Server forced to shutdown:", err)
 	}
 
+	// Let the sync worker flush entries written by the last requests before Redis is closed
+	stopSync()
+	<-syncDone
+
 	log.Println("Server exiting")
 }